@@ -4291,6 +4291,14 @@ var doc = `{
             "properties": {
                 "load_limit": {
                     "type": "integer"
+                },
+                "registry_mirrors": {
+                    "description": "RegistryMirrors replaces proxy.registryMirrors of dfdaemon, the items are same as dfdaemon config",
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
                 }
             }
         },
@@ -23,6 +23,7 @@ import (
 	"hash"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
@@ -33,6 +34,7 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+	"d7y.io/dragonfly/v2/pkg/util/stringutils"
 )
 
 // cacheDetector detect task cache
@@ -110,6 +112,11 @@ func (cd *cacheDetector) doDetect(ctx context.Context, seedTask *task.SeedTask,
 	}
 	// not expired
 	if fileMetadata.Finish {
+		// the real digest is calculated with the algorithm of request digest, see manager.doTrigger
+		if !stringutils.IsBlank(seedTask.Digest) && !strings.EqualFold(seedTask.Digest, fileMetadata.SourceRealDigest) {
+			return nil, errors.Wrapf(digestutils.ErrDigestNotMatch, "source real digest is inconsistent, expected is %s, but got %s",
+				seedTask.Digest, fileMetadata.SourceRealDigest)
+		}
 		// quickly detect the cache situation through the metadata
 		return cd.detectByReadMetaFile(seedTask.ID, fileMetadata)
 	}
@@ -23,6 +23,7 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -124,7 +125,9 @@ func (cm *manager) doTrigger(ctx context.Context, seedTask *task.SeedTask) (*tas
 	if !stringutils.IsBlank(seedTask.Digest) {
 		requestDigest := digestutils.Parse(seedTask.Digest)
 		digestType = requestDigest[0]
-		fileDigest = digestutils.CreateHash(digestType)
+		if fileDigest = digestutils.CreateHash(digestType); fileDigest == nil {
+			return nil, errors.Errorf("digest algorithm %s of task is not supported", digestType)
+		}
 	}
 	// first: detect Cache
 	detectResult, err := cm.detector.detectCache(ctx, seedTask, fileDigest)
@@ -203,9 +206,11 @@ func (cm *manager) handleCDNResult(seedTask *task.SeedTask, downloadMetadata *do
 	seedTask.Log().Debugf("handle cdn result, downloadMetadata: %#v", downloadMetadata)
 	var success = true
 	var errMsg string
-	// check md5
-	if !stringutils.IsBlank(seedTask.Digest) && seedTask.Digest != downloadMetadata.sourceRealDigest {
+	var digestMismatch bool
+	// check digest
+	if !stringutils.IsBlank(seedTask.Digest) && !strings.EqualFold(seedTask.Digest, downloadMetadata.sourceRealDigest) {
 		errMsg = fmt.Sprintf("file digest not match expected: %s real: %s", seedTask.Digest, downloadMetadata.sourceRealDigest)
+		digestMismatch = true
 		success = false
 	}
 	// check source length
@@ -232,6 +237,9 @@ func (cm *manager) handleCDNResult(seedTask *task.SeedTask, downloadMetadata *do
 	}); err != nil {
 		return errors.Wrapf(err, "update metadata")
 	}
+	if digestMismatch {
+		return errors.Wrap(digestutils.ErrDigestNotMatch, errMsg)
+	}
 	if !success {
 		return errors.New(errMsg)
 	}
@@ -0,0 +1,80 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientutil
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+// XattrMimeType is the extended attribute of the content type, see https://www.freedesktop.org/wiki/CommonExtendedAttributes/
+const XattrMimeType = "user.mime_type"
+
+// ApplyFileAttributes applies the attributes of the resource to the downloaded file,
+// the mode is not applied when keepMode is true, eg: the permission of the file is specified by user
+func ApplyFileAttributes(path string, attr *source.FileAttributes, keepMode bool) error {
+	if attr == nil {
+		return nil
+	}
+	if attr.Mode != 0 && !keepMode {
+		if err := os.Chmod(path, attr.Mode.Perm()); err != nil {
+			return errors.Wrapf(err, "change mode of %s", path)
+		}
+	}
+	if attr.LastModified > 0 {
+		mtime := time.Unix(0, attr.LastModified*int64(time.Millisecond))
+		if err := os.Chtimes(path, time.Now(), mtime); err != nil {
+			return errors.Wrapf(err, "change modification time of %s", path)
+		}
+	}
+	// extended attribute may be not supported by the file system, set it at last
+	if attr.ContentType != "" {
+		if err := setXattr(path, XattrMimeType, []byte(attr.ContentType)); err != nil {
+			return errors.Wrapf(err, "set content type of %s", path)
+		}
+	}
+	return nil
+}
+
+// FileAttributesToProto converts the attributes of the resource to send them to other peers
+func FileAttributesToProto(attr *source.FileAttributes) *base.FileAttributes {
+	if attr == nil {
+		return nil
+	}
+	return &base.FileAttributes{
+		Mode:         uint32(attr.Mode.Perm()),
+		LastModified: attr.LastModified,
+		ContentType:  attr.ContentType,
+	}
+}
+
+// FileAttributesFromProto converts the attributes of the resource received from other peers
+func FileAttributesFromProto(attr *base.FileAttributes) *source.FileAttributes {
+	if attr == nil {
+		return nil
+	}
+	return &source.FileAttributes{
+		Mode:         os.FileMode(attr.Mode).Perm(),
+		LastModified: attr.LastModified,
+		ContentType:  attr.ContentType,
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+func TestApplyFileAttributes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("dragonfly"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyFileAttributes(path, nil, false); err != nil {
+		t.Errorf("apply nil attributes error: %s", err)
+	}
+
+	mtime := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	attr := &source.FileAttributes{
+		Mode:         0755,
+		LastModified: mtime.UnixNano() / int64(time.Millisecond),
+	}
+	if err := ApplyFileAttributes(path, attr, true); err != nil {
+		t.Fatalf("apply attributes error: %s", err)
+	}
+	stat, _ := os.Stat(path)
+	if stat.Mode().Perm() != 0644 {
+		t.Errorf("mode is changed when keepMode is true: %s", stat.Mode())
+	}
+	if !stat.ModTime().Equal(mtime) {
+		t.Errorf("expected modification time %s, got %s", mtime, stat.ModTime())
+	}
+
+	if err := ApplyFileAttributes(path, attr, false); err != nil {
+		t.Fatalf("apply attributes error: %s", err)
+	}
+	stat, _ = os.Stat(path)
+	if stat.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %s", stat.Mode())
+	}
+}
@@ -49,6 +49,8 @@ const (
 	DefaultPerPeerDownloadLimit = 20 * unit.MB
 	DefaultTotalDownloadLimit   = 100 * unit.MB
 	DefaultUploadLimit          = 100 * unit.MB
+	DefaultScrubLimit           = 10 * unit.MB
+	DefaultSignatureMaxSize     = 1 * unit.MB
 	DefaultMinRate              = 64 * unit.KB
 )
 
@@ -62,6 +64,7 @@ const (
 
 	DefaultTaskExpireTime  = 3 * time.Minute
 	DefaultGCInterval      = 1 * time.Minute
+	DefaultScrubInterval   = 24 * time.Hour
 	DefaultDaemonAliveTime = 5 * time.Minute
 	DefaultScheduleTimeout = 5 * time.Minute
 	DefaultDownloadTimeout = 5 * time.Minute
@@ -71,9 +74,30 @@ const (
 	DefaultSchedulerPort   = 8002
 
 	DefaultPieceChanSize = 16
+
+	DefaultDfgetParallelism = 4
+)
+
+const (
+	SimpleLocalTaskStoreStrategy             = StoreStrategy("io.d7y.storage.v2.simple")
+	AdvanceLocalTaskStoreStrategy            = StoreStrategy("io.d7y.storage.v2.advance")
+	ContentAddressableLocalTaskStoreStrategy = StoreStrategy("io.d7y.storage.v2.content_addressable")
+)
+
+const (
+	// LRUGCPolicy reclaims the least recently used tasks first
+	LRUGCPolicy = GCPolicy("lru")
+	// LFUGCPolicy reclaims the least frequently used tasks first
+	LFUGCPolicy = GCPolicy("lfu")
+	// SizeGCPolicy reclaims the tasks with the largest size weighted by idle time first
+	SizeGCPolicy = GCPolicy("size")
 )
 
 const (
-	SimpleLocalTaskStoreStrategy  = StoreStrategy("io.d7y.storage.v2.simple")
-	AdvanceLocalTaskStoreStrategy = StoreStrategy("io.d7y.storage.v2.advance")
+	// SignatureTypeMinisign verifies the prehashed minisign signatures with ed25519 public keys
+	SignatureTypeMinisign = "minisign"
+	// SignatureTypeGPG verifies the detached gpg signatures with gpg public keys
+	SignatureTypeGPG = "gpg"
+	// SignatureTypeCosign verifies the blob signatures of cosign with ecdsa or rsa public keys
+	SignatureTypeCosign = "cosign"
 )
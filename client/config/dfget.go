@@ -36,19 +36,30 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/basic"
 	"d7y.io/dragonfly/v2/pkg/unit"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 	"d7y.io/dragonfly/v2/pkg/util/net/urlutils"
 	"d7y.io/dragonfly/v2/pkg/util/stringutils"
 )
 
 type DfgetConfig = ClientOption
 
+// StdoutOutput is the output to stream the content to stdout instead of a file
+const StdoutOutput = "-"
+
+const (
+	// OutputFormatText prints the messages and progress bar for human
+	OutputFormatText = "text"
+	// OutputFormatJSON prints the events in json lines for machine
+	OutputFormatJSON = "json"
+)
+
 // ClientOption holds all the runtime config information.
 type ClientOption struct {
 	base.Options `yaml:",inline" mapstructure:",squash"`
 	// URL download URL.
 	URL string `yaml:"url,omitempty" mapstructure:"url,omitempty"`
 
-	// Output full output path.
+	// Output full output path, "-" streams the content to stdout.
 	Output string `yaml:"output,omitempty" mapstructure:"output,omitempty"`
 
 	// Timeout download timeout(second).
@@ -91,6 +102,22 @@ type ClientOption struct {
 	// DisableBackSource indicates whether to not back source to download when p2p fails.
 	DisableBackSource bool `yaml:"disableBackSource,omitempty" mapstructure:"disableBackSource,omitempty"`
 
+	// Pin pins the task in the daemon storage, pinned task is never reclaimed by gc.
+	Pin bool `yaml:"pin,omitempty" mapstructure:"pin,omitempty"`
+
+	// Priority of the task in the daemon storage, task with lower priority is reclaimed first.
+	Priority int32 `yaml:"priority,omitempty" mapstructure:"priority,omitempty"`
+
+	// SignatureURL is the url of the detached signature, the content is verified by daemon with the trusted public keys.
+	SignatureURL string `yaml:"signatureURL,omitempty" mapstructure:"signature-url,omitempty"`
+
+	// SignatureType is the type of the detached signature, minisign, gpg or cosign.
+	SignatureType string `yaml:"signatureType,omitempty" mapstructure:"signature-type,omitempty"`
+
+	// DisableFileAttributes indicates whether to not apply the mode, modification time and content type of the resource to the output.
+	// The output is copied instead of hard linked to the daemon storage when the file attributes are applied.
+	DisableFileAttributes bool `yaml:"disableFileAttributes,omitempty" mapstructure:"disable-file-attributes,omitempty"`
+
 	// Insecure indicates whether skip secure verify when supernode interact with the source.
 	Insecure bool `yaml:"insecure,omitempty" mapstructure:"insecure,omitempty"`
 
@@ -127,6 +154,30 @@ type ClientOption struct {
 	RecursiveAcceptRegex string `yaml:"acceptRegex,omitempty" mapstructure:"accept-regex,omitempty"`
 
 	RecursiveRejectRegex string `yaml:"rejectRegex,omitempty" mapstructure:"reject-regex,omitempty"`
+
+	// Sync mirrors the resources in target url to output directory like recursive download,
+	// but only the new or changed resources are downloaded
+	Sync bool `yaml:"sync,omitempty" mapstructure:"sync,omitempty"`
+
+	// SyncDelete indicates to delete the local files which are not in target url when syncing
+	SyncDelete bool `yaml:"syncDelete,omitempty" mapstructure:"delete,omitempty"`
+
+	// SyncDryRun indicates to print the sync plan without downloading or deleting any files
+	SyncDryRun bool `yaml:"syncDryRun,omitempty" mapstructure:"dry-run,omitempty"`
+
+	// Manifest is a txt, json or yaml file which contains many items to download,
+	// when it is set, URL is ignored and Output is the base directory of the relative outputs in manifest
+	Manifest string `yaml:"manifest,omitempty" mapstructure:"manifest,omitempty"`
+
+	// Parallelism indicates the max number of files downloaded concurrently in manifest and recursive download
+	Parallelism int `yaml:"parallelism,omitempty" mapstructure:"parallelism,omitempty"`
+
+	// OutputFormat is the format of the messages printed by dfget, text for human or json for machine
+	OutputFormat string `yaml:"outputFormat,omitempty" mapstructure:"output-format,omitempty"`
+
+	// Extract is the directory which the downloaded tar, gzip or zstd archive is extracted to,
+	// the archive is cached compressed in daemon, Output is ignored when it is set
+	Extract string `yaml:"extract,omitempty" mapstructure:"extract,omitempty"`
 }
 
 func NewDfgetConfig() *ClientOption {
@@ -138,10 +189,51 @@ func (cfg *ClientOption) Validate() error {
 		return errors.Wrap(dferrors.ErrInvalidArgument, "runtime config")
 	}
 
+	if cfg.OutputFormat != "" && cfg.OutputFormat != OutputFormatText && cfg.OutputFormat != OutputFormatJSON {
+		return errors.Wrapf(dferrors.ErrInvalidArgument, "output format: %s", cfg.OutputFormat)
+	}
+
+	if cfg.Parallelism < 0 {
+		return errors.Wrapf(dferrors.ErrInvalidArgument, "parallelism: %d", cfg.Parallelism)
+	}
+
+	if cfg.IsStdout() && (cfg.Manifest != "" || cfg.Recursive || cfg.Sync) {
+		return errors.Wrap(dferrors.ErrInvalidArgument, "output: stdout only supports single url")
+	}
+
+	if cfg.Extract != "" && (cfg.IsStdout() || cfg.Manifest != "" || cfg.Recursive || cfg.Sync) {
+		return errors.Wrap(dferrors.ErrInvalidArgument, "extract: only supports single url")
+	}
+
+	// the items in manifest are validated one by one before downloading
+	if cfg.Manifest != "" {
+		if _, err := os.Stat(cfg.Manifest); err != nil {
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "manifest: %v", err)
+		}
+		return nil
+	}
+
 	if !urlutils.IsValidURL(cfg.URL) {
 		return errors.Wrapf(dferrors.ErrInvalidArgument, "url: %v", cfg.URL)
 	}
 
+	if cfg.Digest != "" {
+		if err := digestutils.Validate(cfg.Digest); err != nil {
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "digest: %v", err)
+		}
+	}
+
+	if cfg.SignatureURL != "" {
+		if !urlutils.IsValidURL(cfg.SignatureURL) {
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "signature url: %v", cfg.SignatureURL)
+		}
+		switch cfg.SignatureType {
+		case SignatureTypeMinisign, SignatureTypeGPG, SignatureTypeCosign:
+		default:
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "signature type: %v", cfg.SignatureType)
+		}
+	}
+
 	if _, err := regexp.Compile(cfg.RecursiveAcceptRegex); err != nil {
 		return err
 	}
@@ -150,6 +242,23 @@ func (cfg *ClientOption) Validate() error {
 		return err
 	}
 
+	if cfg.IsStdout() {
+		return nil
+	}
+
+	if cfg.Sync && cfg.SyncDelete {
+		if err := cfg.checkSyncDelete(); err != nil {
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "delete: %v", err)
+		}
+	}
+
+	if cfg.Extract != "" {
+		if err := cfg.checkExtract(); err != nil {
+			return errors.Wrapf(dferrors.ErrInvalidArgument, "extract: %v", err)
+		}
+		return nil
+	}
+
 	if err := cfg.checkOutput(); err != nil {
 		return errors.Wrapf(dferrors.ErrInvalidArgument, "output: %v", err)
 	}
@@ -160,8 +269,14 @@ func (cfg *ClientOption) Validate() error {
 func (cfg *ClientOption) Convert(args []string) error {
 	var err error
 
-	if cfg.Output, err = filepath.Abs(cfg.Output); err != nil {
-		return err
+	if cfg.IsStdout() || cfg.OutputFormat == OutputFormatJSON {
+		cfg.ShowProgress = false
+	}
+	// blank output is generated from url in Validate, keep it blank here
+	if !cfg.IsStdout() && !stringutils.IsBlank(cfg.Output) {
+		if cfg.Output, err = filepath.Abs(cfg.Output); err != nil {
+			return err
+		}
 	}
 
 	if cfg.URL == "" && len(args) > 0 {
@@ -179,11 +294,47 @@ func (cfg *ClientOption) Convert(args []string) error {
 	return nil
 }
 
+// IsStdout indicates whether the content is streamed to stdout
+func (cfg *ClientOption) IsStdout() bool {
+	return cfg.Output == StdoutOutput
+}
+
 func (cfg *ClientOption) String() string {
 	js, _ := json.Marshal(cfg)
 	return string(js)
 }
 
+// checkExtract checks the extract directory, it is replaced by the extracted archive
+func (cfg *ClientOption) checkExtract() error {
+	var err error
+	if cfg.Extract, err = filepath.Abs(cfg.Extract); err != nil {
+		return err
+	}
+	if f, err := os.Stat(cfg.Extract); err == nil && !f.IsDir() {
+		return fmt.Errorf("path[%s] is file but requires directory path", cfg.Extract)
+	}
+	return MkdirAll(filepath.Dir(cfg.Extract), 0777, basic.UserID, basic.UserGroup)
+}
+
+// checkSyncDelete checks the output directory of sync with --delete, the files in it may be deleted,
+// so it must be given explicitly, and the root and home directories are refused
+func (cfg *ClientOption) checkSyncDelete() error {
+	if stringutils.IsBlank(cfg.Output) {
+		return errors.New("output directory is required")
+	}
+	output, err := filepath.Abs(cfg.Output)
+	if err != nil {
+		return err
+	}
+	if output == "/" {
+		return fmt.Errorf("path[%s] is root directory", cfg.Output)
+	}
+	if home, err := os.UserHomeDir(); err == nil && output == filepath.Clean(home) {
+		return fmt.Errorf("path[%s] is home directory", cfg.Output)
+	}
+	return nil
+}
+
 // This function must be called after checkURL
 func (cfg *ClientOption) checkOutput() error {
 	if stringutils.IsBlank(cfg.Output) {
@@ -209,8 +360,8 @@ func (cfg *ClientOption) checkOutput() error {
 	}
 
 	f, err := os.Stat(cfg.Output)
-	// when recursive download, need a directory
-	if cfg.Recursive {
+	// when recursive download or sync, need a directory
+	if cfg.Recursive || cfg.Sync {
 		if err == nil && !f.IsDir() {
 			return fmt.Errorf("path[%s] is file but requires directory path", cfg.Output)
 		}
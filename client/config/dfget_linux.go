@@ -37,4 +37,6 @@ var dfgetConfig = ClientOption{
 	ShowProgress:      false,
 	Recursive:         false,
 	RecursiveLevel:    5,
+	Parallelism:       DefaultDfgetParallelism,
+	OutputFormat:      OutputFormatText,
 }
@@ -18,11 +18,14 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
 
 	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
 )
 
 func TestMkdirAllRoot(t *testing.T) {
@@ -138,3 +141,49 @@ func TestMkdirAll(t *testing.T) {
 		})
 	}
 }
+
+func TestClientOption_SyncDelete(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %s", err)
+	}
+	tests := []struct {
+		name   string
+		output string
+		valid  bool
+	}{
+		{
+			name:   "no output",
+			output: "",
+		},
+		{
+			name:   "root directory",
+			output: "/",
+		},
+		{
+			name:   "home directory",
+			output: home + "/",
+		},
+		{
+			name:   "output directory",
+			output: filepath.Join(t.TempDir(), "sync"),
+			valid:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			cfg := NewDfgetConfig()
+			cfg.Output = tc.output
+			cfg.Sync, cfg.Recursive, cfg.SyncDelete = true, true, true
+			assert.Nil(cfg.Convert([]string{"http://localhost/dir/"}))
+			err := cfg.Validate()
+			if tc.valid {
+				assert.Nil(err)
+				return
+			}
+			assert.ErrorIs(err, dferrors.ErrInvalidArgument)
+		})
+	}
+}
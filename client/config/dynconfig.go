@@ -17,6 +17,7 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"time"
@@ -40,6 +41,32 @@ type DynconfigData struct {
 	Schedulers []*manager.Scheduler
 }
 
+// schedulerClusterClientConfig is the client config of scheduler cluster in manager
+type schedulerClusterClientConfig struct {
+	RegistryMirrors []*RegistryMirror `json:"registry_mirrors"`
+}
+
+// GetRegistryMirrors returns the registry mirrors in the client config of scheduler cluster,
+// it is empty when they are not configured in manager
+func (d *DynconfigData) GetRegistryMirrors() ([]*RegistryMirror, error) {
+	for _, scheduler := range d.Schedulers {
+		if scheduler.SchedulerCluster == nil || len(scheduler.SchedulerCluster.ClientConfig) == 0 {
+			continue
+		}
+
+		var config schedulerClusterClientConfig
+		if err := json.Unmarshal(scheduler.SchedulerCluster.ClientConfig, &config); err != nil {
+			return nil, err
+		}
+		if err := ValidateRegistryMirrors(config.RegistryMirrors); err != nil {
+			return nil, err
+		}
+		return config.RegistryMirrors, nil
+	}
+
+	return nil, nil
+}
+
 type Dynconfig interface {
 	// Get the dynamic config from manager.
 	GetSchedulers() ([]*manager.Scheduler, error)
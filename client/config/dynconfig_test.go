@@ -483,3 +483,65 @@ func TestDynconfigGetSchedulers(t *testing.T) {
 		})
 	}
 }
+
+func TestDynconfigDataGetRegistryMirrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientConfig string
+		expect       func(t *testing.T, mirrors []*RegistryMirror, err error)
+	}{
+		{
+			name:         "registry mirrors are not configured",
+			clientConfig: `{"load_limit":100}`,
+			expect: func(t *testing.T, mirrors []*RegistryMirror, err error) {
+				assert := assert.New(t)
+				assert.Nil(err)
+				assert.Empty(mirrors)
+			},
+		},
+		{
+			name: "registry mirrors are configured",
+			clientConfig: `{"registry_mirrors":[{"host":"ghcr.io","url":"https://ghcr.io",` +
+				`"endpoints":["https://mirror.example.com"],"insecure":true,"oci":{"enable":true,"manifestTTL":"30s"}}]}`,
+			expect: func(t *testing.T, mirrors []*RegistryMirror, err error) {
+				assert := assert.New(t)
+				assert.Nil(err)
+				assert.Len(mirrors, 1)
+				assert.Equal("ghcr.io", mirrors[0].Host)
+				assert.Equal([]string{"https://ghcr.io", "https://mirror.example.com"},
+					[]string{mirrors[0].Upstreams()[0].String(), mirrors[0].Upstreams()[1].String()})
+				assert.True(mirrors[0].Insecure)
+				assert.Equal(30*time.Second, mirrors[0].OCI.ManifestTTL.Duration)
+			},
+		},
+		{
+			name:         "registry mirror without host",
+			clientConfig: `{"registry_mirrors":[{"url":"https://ghcr.io"}]}`,
+			expect: func(t *testing.T, mirrors []*RegistryMirror, err error) {
+				assert := assert.New(t)
+				assert.NotNil(err)
+			},
+		},
+		{
+			name:         "registry mirrors with duplicated host",
+			clientConfig: `{"registry_mirrors":[{"host":"ghcr.io","url":"https://ghcr.io"},{"host":"ghcr.io","url":"https://ghcr.io"}]}`,
+			expect: func(t *testing.T, mirrors []*RegistryMirror, err error) {
+				assert := assert.New(t)
+				assert.NotNil(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := &DynconfigData{
+				Schedulers: []*manager.Scheduler{
+					{HostName: "foo"},
+					{HostName: "bar", SchedulerCluster: &manager.SchedulerCluster{ClientConfig: []byte(tc.clientConfig)}},
+				},
+			}
+			mirrors, err := data.GetRegistryMirrors()
+			tc.expect(t, mirrors, err)
+		})
+	}
+}
@@ -23,4 +23,7 @@ const (
 	HeaderDragonflyBiz    = "X-Dragonfly-Biz"
 	// HeaderDragonflyRegistry is used for dynamic registry mirrors
 	HeaderDragonflyRegistry = "X-Dragonfly-Registry"
+	// HeaderDragonflySignatureType and HeaderDragonflySignatureURL request to verify the signature of the content
+	HeaderDragonflySignatureType = "X-Dragonfly-Signature-Type"
+	HeaderDragonflySignatureURL  = "X-Dragonfly-Signature-URL"
 )
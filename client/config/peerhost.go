@@ -28,9 +28,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
@@ -63,6 +65,7 @@ type DaemonOption struct {
 	Proxy        *ProxyOption    `mapstructure:"proxy" yaml:"proxy"`
 	Upload       UploadOption    `mapstructure:"upload" yaml:"upload"`
 	Storage      StorageOption   `mapstructure:"storage" yaml:"storage"`
+	Signature    SignatureOption `mapstructure:"signature" yaml:"signature"`
 	ConfigServer string          `mapstructure:"configServer" yaml:"configServer"`
 }
 
@@ -126,6 +129,43 @@ func (p *DaemonOption) Validate() error {
 		}
 	}
 
+	switch p.Storage.GCPolicy {
+	case "", LRUGCPolicy, LFUGCPolicy, SizeGCPolicy:
+	default:
+		return errors.Errorf("not support gc policy: %s", p.Storage.GCPolicy)
+	}
+
+	if p.Storage.Scrub.Enable && p.Storage.Scrub.Interval.Duration <= 0 {
+		return errors.New("storage scrub interval is not specified")
+	}
+
+	if p.Proxy != nil {
+		if p.Proxy.RegistryMirror != nil {
+			if err := p.Proxy.RegistryMirror.OCI.Validate(); err != nil {
+				return errors.Wrap(err, "registry mirror")
+			}
+		}
+		if err := ValidateRegistryMirrors(p.Proxy.RegistryMirrors); err != nil {
+			return err
+		}
+		for _, rule := range p.Proxy.Proxies {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range p.Signature.PublicKeys {
+		switch key.Type {
+		case SignatureTypeMinisign, SignatureTypeGPG, SignatureTypeCosign:
+		default:
+			return errors.Errorf("not support signature type: %s", key.Type)
+		}
+		if key.Path == "" {
+			return errors.Errorf("%s public key path is not specified", key.Type)
+		}
+	}
+
 	return nil
 }
 
@@ -190,14 +230,16 @@ type TransportOption struct {
 type ProxyOption struct {
 	// WARNING: when add more option, please update ProxyOption.unmarshal function
 	ListenOption    `mapstructure:",squash" yaml:",inline"`
-	BasicAuth       *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
-	DefaultFilter   string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
-	MaxConcurrency  int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
-	RegistryMirror  *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
-	WhiteList       []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
-	Proxies         []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
-	HijackHTTPS     *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
-	DumpHTTPContent bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+	BasicAuth       *BasicAuth        `mapstructure:"basicAuth" yaml:"basicAuth"`
+	DefaultFilter   string            `mapstructure:"defaultFilter" yaml:"defaultFilter"`
+	MaxConcurrency  int64             `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
+	RegistryMirror  *RegistryMirror   `mapstructure:"registryMirror" yaml:"registryMirror"`
+	RegistryMirrors []*RegistryMirror `mapstructure:"registryMirrors" yaml:"registryMirrors"`
+	WhiteList       []*WhiteList      `mapstructure:"whiteList" yaml:"whiteList"`
+	Proxies         []*Proxy          `mapstructure:"proxies" yaml:"proxies"`
+	HijackHTTPS     *HijackConfig     `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
+	DumpHTTPContent bool              `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+	HTTP2           *HTTP2Option      `mapstructure:"http2" yaml:"http2"`
 }
 
 func (p *ProxyOption) UnmarshalJSON(b []byte) error {
@@ -275,14 +317,16 @@ func (p *ProxyOption) UnmarshalYAML(node *yaml.Node) error {
 func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err error), b []byte) error {
 	pt := struct {
 		ListenOption    `mapstructure:",squash" yaml:",inline"`
-		BasicAuth       *BasicAuth      `mapstructure:"basicAuth" yaml:"basicAuth"`
-		DefaultFilter   string          `mapstructure:"defaultFilter" yaml:"defaultFilter"`
-		MaxConcurrency  int64           `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
-		RegistryMirror  *RegistryMirror `mapstructure:"registryMirror" yaml:"registryMirror"`
-		WhiteList       []*WhiteList    `mapstructure:"whiteList" yaml:"whiteList"`
-		Proxies         []*Proxy        `mapstructure:"proxies" yaml:"proxies"`
-		HijackHTTPS     *HijackConfig   `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
-		DumpHTTPContent bool            `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+		BasicAuth       *BasicAuth        `mapstructure:"basicAuth" yaml:"basicAuth"`
+		DefaultFilter   string            `mapstructure:"defaultFilter" yaml:"defaultFilter"`
+		MaxConcurrency  int64             `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
+		RegistryMirror  *RegistryMirror   `mapstructure:"registryMirror" yaml:"registryMirror"`
+		RegistryMirrors []*RegistryMirror `mapstructure:"registryMirrors" yaml:"registryMirrors"`
+		WhiteList       []*WhiteList      `mapstructure:"whiteList" yaml:"whiteList"`
+		Proxies         []*Proxy          `mapstructure:"proxies" yaml:"proxies"`
+		HijackHTTPS     *HijackConfig     `mapstructure:"hijackHTTPS" yaml:"hijackHTTPS"`
+		DumpHTTPContent bool              `mapstructure:"dumpHTTPContent" yaml:"dumpHTTPContent"`
+		HTTP2           *HTTP2Option      `mapstructure:"http2" yaml:"http2"`
 	}{}
 
 	if err := unmarshal(b, &pt); err != nil {
@@ -291,6 +335,7 @@ func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err
 
 	p.ListenOption = pt.ListenOption
 	p.RegistryMirror = pt.RegistryMirror
+	p.RegistryMirrors = pt.RegistryMirrors
 	p.Proxies = pt.Proxies
 	p.HijackHTTPS = pt.HijackHTTPS
 	p.WhiteList = pt.WhiteList
@@ -298,6 +343,7 @@ func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err
 	p.DefaultFilter = pt.DefaultFilter
 	p.BasicAuth = pt.BasicAuth
 	p.DumpHTTPContent = pt.DumpHTTPContent
+	p.HTTP2 = pt.HTTP2
 
 	return nil
 }
@@ -305,6 +351,16 @@ func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out interface{}) (err
 type UploadOption struct {
 	ListenOption `yaml:",inline" mapstructure:",squash"`
 	RateLimit    clientutil.RateLimit `mapstructure:"rateLimit" yaml:"rateLimit"`
+	// PieceCache caches the hot pieces in memory for uploading to other peers
+	PieceCache PieceCacheOption `mapstructure:"pieceCache" yaml:"pieceCache"`
+}
+
+type PieceCacheOption struct {
+	// Capacity indicates the max bytes of the cached pieces, 0 disables the piece cache
+	Capacity unit.Bytes `mapstructure:"capacity" yaml:"capacity"`
+	// MinAccessCount indicates a piece is cached after it is uploaded at least MinAccessCount times,
+	// default is 2, avoid the pieces only uploaded once evicting the hot pieces
+	MinAccessCount int `mapstructure:"minAccessCount" yaml:"minAccessCount"`
 }
 
 type ListenOption struct {
@@ -434,13 +490,48 @@ type StorageOption struct {
 	// DiskGCThresholdPercent indicates the threshold to gc the oldest tasks according the disk usage
 	// Eg, DiskGCThresholdPercent=80, when the disk usage is above 80%, start to gc the oldest tasks
 	DiskGCThresholdPercent float64 `mapstructure:"diskGCThresholdPercent" yaml:"diskGCThresholdPercent"`
+	// DiskQuota indicates the limit of the content length of all tasks, when exceeding it, tasks are marked by GCPolicy
+	// and reclaimed in next gc loop, and the task is refused if no enough tasks can be marked, then none is marked.
+	// The quota is soft until next gc loop, the marked tasks are kept on disk until then
+	DiskQuota unit.Bytes `mapstructure:"diskQuota" yaml:"diskQuota"`
+	// GCPolicy indicates the order to reclaim tasks when reaching gc threshold or disk quota,
+	// support: lru, lfu and size, default is lru. Pinned tasks are never reclaimed by gc
+	GCPolicy GCPolicy `mapstructure:"gcPolicy" yaml:"gcPolicy"`
 	// Multiplex indicates reusing underlying storage for same task id
 	Multiplex     bool          `mapstructure:"multiplex" yaml:"multiplex"`
 	StoreStrategy StoreStrategy `mapstructure:"strategy" yaml:"strategy"`
+	// Scrub re-verifies the data of the cached tasks periodically
+	Scrub ScrubOption `mapstructure:"scrub" yaml:"scrub"`
+}
+
+type SignatureOption struct {
+	// PublicKeys are the trusted keys to verify the signatures of the content,
+	// the downloads requesting verification fail when no key of the signature type is configured
+	PublicKeys []SignaturePublicKey `mapstructure:"publicKeys" yaml:"publicKeys"`
+	// MaxSize limits the size of the fetched signature, default is 1Mi
+	MaxSize unit.Bytes `mapstructure:"maxSize" yaml:"maxSize"`
+}
+
+type SignaturePublicKey struct {
+	// Type is the signature type of the key, support: minisign, gpg and cosign
+	Type string `mapstructure:"type" yaml:"type"`
+	// Path is the file of the public key, gpg key file may contain many keys
+	Path string `mapstructure:"path" yaml:"path"`
+}
+
+type ScrubOption struct {
+	// Enable indicates whether to scrub the cached tasks in background
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// Interval indicates the duration between two scrubs
+	Interval clientutil.Duration `mapstructure:"interval" yaml:"interval"`
+	// RateLimit limits the read bytes per second of scrubbing, avoid affecting downloading and uploading
+	RateLimit clientutil.RateLimit `mapstructure:"rateLimit" yaml:"rateLimit"`
 }
 
 type StoreStrategy string
 
+type GCPolicy string
+
 type FileString string
 
 func (f *FileString) UnmarshalJSON(b []byte) error {
@@ -512,24 +603,95 @@ func (t *TLSConfig) UnmarshalJSON(b []byte) error {
 
 // RegistryMirror configures the mirror of the official docker registry
 type RegistryMirror struct {
+	// Host is the registry served by the mirror in registryMirrors, for example docker.io or ghcr.io,
+	// it is matched with the "ns" query of containerd or the host of header "X-Dragonfly-Registry"
+	Host string `yaml:"host" mapstructure:"host" json:"host"`
+
 	// Remote url for the registry mirror, default is https://index.docker.io
-	Remote *URL `yaml:"url" mapstructure:"url"`
+	Remote *URL `yaml:"url" mapstructure:"url" json:"url"`
+
+	// Endpoints are the fallback upstreams tried in order after Remote fails with 5xx or timeout
+	Endpoints []*URL `yaml:"endpoints" mapstructure:"endpoints" json:"endpoints"`
 
 	// DynamicRemote indicates using header "X-Dragonfly-Registry" for remote instead of Remote
 	// if header "X-Dragonfly-Registry" does not exist, use Remote by default
-	DynamicRemote bool `yaml:"dynamic" mapstructure:"dynamic"`
+	DynamicRemote bool `yaml:"dynamic" mapstructure:"dynamic" json:"dynamic"`
 
 	// Optional certificates if the mirror uses self-signed certificates
-	Certs *CertPool `yaml:"certs" mapstructure:"certs"`
+	Certs *CertPool `yaml:"certs" mapstructure:"certs" json:"certs"`
 
 	// Whether to ignore certificates errors for the registry
-	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+	Insecure bool `yaml:"insecure" mapstructure:"insecure" json:"insecure"`
 
 	// Request the remote registry directly.
-	Direct bool `yaml:"direct" mapstructure:"direct"`
+	Direct bool `yaml:"direct" mapstructure:"direct" json:"direct"`
 
 	// Whether to use proxies to decide when to use dragonfly
-	UseProxies bool `yaml:"useProxies" mapstructure:"useProxies"`
+	UseProxies bool `yaml:"useProxies" mapstructure:"useProxies" json:"useProxies"`
+
+	// OCI enables the registry aware mode of the mirror
+	OCI *OCIOption `yaml:"oci" mapstructure:"oci" json:"oci"`
+}
+
+// OCIOption configures the registry aware mode of the mirror, it understands the OCI distribution api:
+// manifests are cached, bearer tokens are requested and cached per repository scope when the client
+// does not carry one, and blobs are downloaded as tasks keyed by digest, so the same layer from
+// different repositories or mirrors is shared
+type OCIOption struct {
+	Enable bool `yaml:"enable" mapstructure:"enable" json:"enable"`
+
+	// ManifestTTL is how long a manifest referenced by tag is cached, manifests referenced by digest
+	// are immutable and only evicted when the cache is full
+	ManifestTTL clientutil.Duration `yaml:"manifestTTL" mapstructure:"manifestTTL" json:"manifestTTL"`
+
+	// ManifestCacheSize is the max count of cached manifests
+	ManifestCacheSize int `yaml:"manifestCacheSize" mapstructure:"manifestCacheSize" json:"manifestCacheSize"`
+
+	// Prefetch downloads all layers of an image manifest in parallel when the manifest is pulled,
+	// so the whole image is in p2p network before the client requests the layers
+	Prefetch bool `yaml:"prefetch" mapstructure:"prefetch" json:"prefetch"`
+
+	// PrefetchConcurrency is the max count of layers prefetched at the same time for one image
+	PrefetchConcurrency int `yaml:"prefetchConcurrency" mapstructure:"prefetchConcurrency" json:"prefetchConcurrency"`
+}
+
+// Validate checks the oci option, nil option is valid
+func (o *OCIOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.ManifestTTL.Duration < 0 || o.ManifestCacheSize < 0 || o.PrefetchConcurrency < 0 {
+		return errors.New("oci manifestTTL, manifestCacheSize and prefetchConcurrency should not be negative")
+	}
+	return nil
+}
+
+// Upstreams returns the upstream urls of the mirror in order
+func (r *RegistryMirror) Upstreams() []*url.URL {
+	var upstreams []*url.URL
+	if r.Remote != nil && r.Remote.URL != nil {
+		upstreams = append(upstreams, r.Remote.URL)
+	}
+	for _, u := range r.Endpoints {
+		if u != nil && u.URL != nil {
+			upstreams = append(upstreams, u.URL)
+		}
+	}
+	return upstreams
+}
+
+// Validate checks the mirror in registryMirrors
+func (r *RegistryMirror) Validate() error {
+	if r.Host == "" {
+		return errors.New("registry mirror host is not specified")
+	}
+	if len(r.Upstreams()) == 0 && !r.DynamicRemote {
+		return errors.Errorf("registry mirror %s has no upstream", r.Host)
+	}
+	if err := r.OCI.Validate(); err != nil {
+		return errors.Wrapf(err, "registry mirror %s", r.Host)
+	}
+	return nil
 }
 
 // TLSConfig returns the tls.Config used to communicate with the mirror.
@@ -546,6 +708,21 @@ func (r *RegistryMirror) TLSConfig() *tls.Config {
 	return cfg
 }
 
+// ValidateRegistryMirrors checks the mirrors and the hosts of them are unique
+func ValidateRegistryMirrors(mirrors []*RegistryMirror) error {
+	hosts := map[string]bool{}
+	for _, m := range mirrors {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if hosts[m.Host] {
+			return errors.Errorf("duplicated registry mirror host: %s", m.Host)
+		}
+		hosts[m.Host] = true
+	}
+	return nil
+}
+
 // URL is simple wrapper around url.URL to make it unmarshallable from a string.
 type URL struct {
 	*url.URL
@@ -655,6 +832,33 @@ type Proxy struct {
 
 	// Redirect is the host to redirect to, if not empty
 	Redirect string `yaml:"redirect" mapstructure:"redirect"`
+
+	// Filter is the query params of url filtered when computing the task id, separated by '&',
+	// it is used when the request has no X-Dragonfly-Filter header
+	Filter string `yaml:"filter" mapstructure:"filter"`
+
+	// Tag is the biz tag of the task, it is used when the request has no X-Dragonfly-Biz header
+	Tag string `yaml:"tag" mapstructure:"tag"`
+
+	// Pattern is the download pattern, p2p, cdn or source, the requests of source pattern are
+	// proxied directly without dragonfly, the requests of cdn pattern are scheduled to cdn peers only
+	Pattern string `yaml:"pattern" mapstructure:"pattern"`
+
+	// RateLimit limits the bandwidth of the responses of all requests matching the rule, 0 is no limit
+	RateLimit clientutil.RateLimit `yaml:"rateLimit" mapstructure:"rateLimit"`
+
+	// MaxP2PSize is the max content length in bytes of the objects downloaded with dragonfly,
+	// the larger objects are proxied directly, 0 is no limit. The content length is got from the completed
+	// task or the last download with dragonfly, a HEAD request with the headers of task is sent to the source
+	// only when neither is available
+	MaxP2PSize int64 `yaml:"maxP2PSize" mapstructure:"maxP2PSize"`
+
+	// StripHeaders are the request headers not passed to the task, peers and cdn download
+	// from the source without them
+	StripHeaders []string `yaml:"stripHeaders" mapstructure:"stripHeaders"`
+
+	limiterOnce sync.Once
+	limiter     *rate.Limiter
 }
 
 func NewProxy(regx string, useHTTPS bool, direct bool, redirect string) (*Proxy, error) {
@@ -676,6 +880,33 @@ func (r *Proxy) Match(url string) bool {
 	return r.Regx != nil && r.Regx.MatchString(url)
 }
 
+// Validate checks the policies of the rule
+func (r *Proxy) Validate() error {
+	switch r.Pattern {
+	case "", PatternP2P, PatternCDN, PatternSource:
+	default:
+		return errors.Errorf("proxy rule %s: pattern %s should be p2p, cdn or source", r.Regx, r.Pattern)
+	}
+	if r.RateLimit.Limit < 0 || r.MaxP2PSize < 0 {
+		return errors.Errorf("proxy rule %s: rateLimit and maxP2PSize should not be negative", r.Regx)
+	}
+	return nil
+}
+
+// Limiter returns the rate limiter shared by the requests matching the rule, it is nil when there is no limit
+func (r *Proxy) Limiter() *rate.Limiter {
+	r.limiterOnce.Do(func() {
+		if r.RateLimit.Limit > 0 && r.RateLimit.Limit != rate.Inf {
+			burst := int(r.RateLimit.Limit)
+			if burst < 1 {
+				burst = 1
+			}
+			r.limiter = rate.NewLimiter(r.RateLimit.Limit, burst)
+		}
+	})
+	return r.limiter
+}
+
 // Regexp is a simple wrapper around regexp. Regexp to make it unmarshallable from a string.
 type Regexp struct {
 	*regexp.Regexp
@@ -722,6 +953,16 @@ func (r *Regexp) MarshalYAML() (interface{}, error) {
 	return r.String(), nil
 }
 
+// HTTP2Option configures HTTP/2 of the proxy.
+type HTTP2Option struct {
+	// Enable serves HTTP/2 without TLS (h2c) on the proxy listener besides HTTP/1.1, including HTTP/2 CONNECT
+	// requests, and negotiates HTTP/2 in the TLS connections of hijacked https requests
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// MaxConcurrentStreams is the max count of concurrent streams per HTTP/2 connection, 0 means 250
+	MaxConcurrentStreams uint32 `yaml:"maxConcurrentStreams" mapstructure:"maxConcurrentStreams"`
+}
+
 // HijackConfig represents how dfdaemon hijacks http requests.
 type HijackConfig struct {
 	Cert  string             `yaml:"cert" mapstructure:"cert"`
@@ -123,5 +123,16 @@ var peerHostConfig = DaemonOption{
 		StoreStrategy:          AdvanceLocalTaskStoreStrategy,
 		Multiplex:              false,
 		DiskGCThresholdPercent: 95,
+		Scrub: ScrubOption{
+			Interval: clientutil.Duration{
+				Duration: DefaultScrubInterval,
+			},
+			RateLimit: clientutil.RateLimit{
+				Limit: rate.Limit(DefaultScrubLimit),
+			},
+		},
+	},
+	Signature: SignatureOption{
+		MaxSize: DefaultSignatureMaxSize,
 	},
 }
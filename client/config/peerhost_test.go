@@ -346,6 +346,32 @@ func TestPeerHostOption_Load(t *testing.T) {
 				},
 				Insecure: true,
 				Direct:   false,
+				OCI: &OCIOption{
+					Enable:              true,
+					ManifestTTL:         clientutil.Duration{Duration: time.Minute},
+					ManifestCacheSize:   1000,
+					Prefetch:            true,
+					PrefetchConcurrency: 4,
+				},
+			},
+			RegistryMirrors: []*RegistryMirror{
+				{
+					Host: "ghcr.io",
+					Remote: &URL{
+						&url.URL{
+							Host:   "ghcr.io",
+							Scheme: "https",
+						},
+					},
+					Endpoints: []*URL{
+						{
+							&url.URL{
+								Host:   "ghcr-mirror.d7y.io",
+								Scheme: "https",
+							},
+						},
+					},
+				},
 			},
 			Proxies: []*Proxy{
 				{
@@ -353,6 +379,14 @@ func TestPeerHostOption_Load(t *testing.T) {
 					UseHTTPS: false,
 					Direct:   false,
 					Redirect: "d7y.io",
+					Filter:   "Expires&Signature",
+					Tag:      "d7y/blobs",
+					Pattern:  PatternCDN,
+					RateLimit: clientutil.RateLimit{
+						Limit: 10 * 1024 * 1024,
+					},
+					MaxP2PSize:   1024 * 1024 * 1024,
+					StripHeaders: []string{"Authorization"},
 				},
 			},
 			HijackHTTPS: &HijackConfig{
@@ -365,6 +399,10 @@ func TestPeerHostOption_Load(t *testing.T) {
 					},
 				},
 			},
+			HTTP2: &HTTP2Option{
+				Enable:               true,
+				MaxConcurrentStreams: 100,
+			},
 		},
 	}
 
@@ -375,3 +413,22 @@ func TestPeerHostOption_Load(t *testing.T) {
 
 	assert.EqualValues(peerHostOption, peerHostOptionYAML)
 }
+
+func TestProxy_Validate(t *testing.T) {
+	assert := testifyassert.New(t)
+	for _, tc := range []struct {
+		pattern string
+		valid   bool
+	}{
+		{pattern: "", valid: true},
+		{pattern: PatternP2P, valid: true},
+		{pattern: PatternSource, valid: true},
+		{pattern: PatternCDN, valid: true},
+		{pattern: "unknown", valid: false},
+	} {
+		rule, err := NewProxy("blobs/sha256.*", false, false, "")
+		assert.Nil(err)
+		rule.Pattern = tc.pattern
+		assert.Equal(tc.valid, rule.Validate() == nil, tc.pattern)
+	}
+}
@@ -43,6 +43,7 @@ import (
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/client/daemon/proxy"
 	"d7y.io/dragonfly/v2/client/daemon/rpcserver"
+	"d7y.io/dragonfly/v2/client/daemon/signature"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/upload"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
@@ -155,7 +156,7 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		}
 	}
 	storageManager, err := storage.NewStorageManager(opt.Storage.StoreStrategy, &opt.Storage,
-		gcCallback, storage.WithGCInterval(opt.GCInterval.Duration))
+		gcCallback, storage.WithGCInterval(opt.GCInterval.Duration), storage.WithPluginDir(d.PluginDir()))
 	if err != nil {
 		return nil, err
 	}
@@ -168,8 +169,13 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 	if err != nil {
 		return nil, err
 	}
+	signatureManager, err := signature.NewManager(opt.Signature)
+	if err != nil {
+		return nil, err
+	}
 	peerTaskManager, err := peer.NewPeerTaskManager(host, pieceManager, storageManager, sched, opt.Scheduler,
-		opt.Download.PerPeerRateLimit.Limit, opt.Storage.Multiplex, opt.Download.CalculateDigest, opt.Download.GetPiecesMaxRetry)
+		opt.Download.PerPeerRateLimit.Limit, opt.Storage.Multiplex, opt.Download.CalculateDigest, signatureManager,
+		opt.Download.GetPiecesMaxRetry)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +209,8 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 	}
 
 	uploadManager, err := upload.NewUploadManager(storageManager,
-		upload.WithLimiter(rate.NewLimiter(opt.Upload.RateLimit.Limit, int(opt.Upload.RateLimit.Limit))))
+		upload.WithLimiter(rate.NewLimiter(opt.Upload.RateLimit.Limit, int(opt.Upload.RateLimit.Limit))),
+		upload.WithPieceCache(int64(opt.Upload.PieceCache.Capacity), opt.Upload.PieceCache.MinAccessCount))
 	if err != nil {
 		return nil, err
 	}
@@ -445,6 +452,14 @@ func (cd *clientDaemon) Serve() error {
 		return nil
 	})
 
+	// serve storage scrubber
+	if cd.Option.Storage.Scrub.Enable {
+		g.Go(func() error {
+			cd.serveScrubber()
+			return nil
+		})
+	}
+
 	if cd.Option.AliveTime.Duration > 0 {
 		g.Go(func() error {
 			select {
@@ -474,6 +489,10 @@ func (cd *clientDaemon) Serve() error {
 	if cd.dynconfig != nil {
 		// dynconfig register client daemon
 		cd.dynconfig.Register(cd)
+		// dynconfig register proxy for registry mirrors
+		if cd.ProxyManager.IsEnabled() {
+			cd.dynconfig.Register(cd.ProxyManager)
+		}
 
 		// servce dynconfig
 		g.Go(func() error {
@@ -504,6 +523,41 @@ func (cd *clientDaemon) Serve() error {
 	return werr
 }
 
+// serveScrubber re-verifies the data of the cached tasks periodically until the daemon is stopped
+func (cd *clientDaemon) serveScrubber() {
+	opt := cd.Option.Storage.Scrub
+	limiter := rate.NewLimiter(opt.RateLimit.Limit, int(opt.RateLimit.Limit))
+	if opt.RateLimit.Limit <= 0 {
+		limiter = rate.NewLimiter(rate.Inf, 0)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-cd.done
+		cancel()
+	}()
+
+	logger.Infof("serve storage scrubber, interval: %s, rate limit: %.0f bytes/s", opt.Interval.Duration, float64(opt.RateLimit.Limit))
+	ticker := time.NewTicker(opt.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			corrupted, err := cd.StorageManager.ScrubTasks(ctx, limiter)
+			if err != nil {
+				logger.Warnf("scrub tasks error: %s", err)
+				continue
+			}
+			if corrupted > 0 {
+				logger.Warnf("scrub tasks, found %d corrupted task(s)", corrupted)
+			}
+		case <-cd.done:
+			logger.Infof("peer host done, stop storage scrubber")
+			return
+		}
+	}
+}
+
 func (cd *clientDaemon) Stop() {
 	cd.once.Do(func() {
 		close(cd.done)
@@ -55,6 +55,55 @@ var (
 		Help:      "Counter of the total byte of all proxy request.",
 	}, []string{"method"})
 
+	ProxyActiveStreamCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_active_stream_total",
+		Help:      "Current count of active proxy streams, the requests in hijacked https connections are included.",
+	}, []string{"proto"})
+
+	ProxyManifestCacheHitCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_manifest_cache_hit_total",
+		Help:      "Counter of the total hits of registry mirror manifest cache.",
+	})
+
+	ProxyManifestCacheMissCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_manifest_cache_miss_total",
+		Help:      "Counter of the total misses of registry mirror manifest cache.",
+	})
+
+	ProxyRegistryTokenCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_registry_token_total",
+		Help:      "Counter of the total bearer tokens requested by registry mirror.",
+	})
+
+	ProxyImagePrefetchCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_image_prefetch_total",
+		Help:      "Counter of the total images prefetched by registry mirror.",
+	})
+
+	ProxyImagePrefetchFailedCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_image_prefetch_failed_total",
+		Help:      "Counter of the total failed images prefetched by registry mirror.",
+	})
+
+	ProxyImagePrefetchBytesCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "proxy_image_prefetch_bytes_total",
+		Help:      "Counter of the total bytes of image blobs prefetched by registry mirror.",
+	})
+
 	PeerTaskCount = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: constants.MetricsNamespace,
 		Subsystem: constants.DfdaemonMetricsName,
@@ -75,6 +124,62 @@ var (
 		Name:      "peer_task_reuse_total",
 		Help:      "Counter of the total reused peer tasks.",
 	})
+
+	PeerTaskResumeCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "peer_task_resume_total",
+		Help:      "Counter of the total resumed peer tasks.",
+	})
+
+	UploadPieceCacheHitCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "upload_piece_cache_hit_total",
+		Help:      "Counter of the total hits of upload piece cache.",
+	})
+
+	UploadPieceCacheMissCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "upload_piece_cache_miss_total",
+		Help:      "Counter of the total misses of upload piece cache.",
+	})
+
+	UploadPieceCacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "upload_piece_cache_bytes",
+		Help:      "Current bytes of the pieces in upload piece cache.",
+	})
+
+	StorageScrubPieceCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "storage_scrub_piece_total",
+		Help:      "Counter of the total pieces verified by storage scrubber.",
+	})
+
+	StorageScrubBytesCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "storage_scrub_bytes_total",
+		Help:      "Counter of the total bytes read by storage scrubber.",
+	})
+
+	StorageScrubCorruptedPieceCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "storage_scrub_corrupted_piece_total",
+		Help:      "Counter of the total corrupted pieces found by storage scrubber.",
+	})
+
+	StorageScrubCorruptedTaskCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: constants.MetricsNamespace,
+		Subsystem: constants.DfdaemonMetricsName,
+		Name:      "storage_scrub_corrupted_task_total",
+		Help:      "Counter of the total corrupted tasks found by storage scrubber.",
+	})
 )
 
 func New(addr string) *http.Server {
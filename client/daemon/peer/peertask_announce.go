@@ -0,0 +1,136 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+func (ptm *peerTaskManager) AnnouncePeerTask(ctx context.Context, meta storage.PeerTaskMetadata, url string, urlMeta *base.UrlMeta) error {
+	log := logger.With("peer", meta.PeerID, "task", meta.TaskID, "component", "announcePeerTask")
+
+	// 1. load all pieces from local storage, first request is used for getting total piece count
+	packet, err := ptm.storageManager.GetPieces(ctx, &base.PieceTaskRequest{
+		TaskId: meta.TaskID,
+		DstPid: meta.PeerID,
+	})
+	if err != nil {
+		log.Errorf("get total piece count error: %s", err)
+		return err
+	}
+	if packet.TotalPiece <= 0 {
+		return errors.Errorf("task %s/%s is not completed", meta.TaskID, meta.PeerID)
+	}
+	packet, err = ptm.storageManager.GetPieces(ctx, &base.PieceTaskRequest{
+		TaskId: meta.TaskID,
+		DstPid: meta.PeerID,
+		Limit:  uint32(packet.TotalPiece),
+	})
+	if err != nil {
+		log.Errorf("get pieces error: %s", err)
+		return err
+	}
+	if int32(len(packet.PieceInfos)) != packet.TotalPiece {
+		return errors.Errorf("task %s/%s is not completed, desired pieces: %d, actual: %d",
+			meta.TaskID, meta.PeerID, packet.TotalPiece, len(packet.PieceInfos))
+	}
+
+	// 2. register peer task to scheduler
+	request := &scheduler.PeerTaskRequest{
+		Url:      url,
+		UrlMeta:  urlMeta,
+		PeerId:   meta.PeerID,
+		PeerHost: ptm.host,
+	}
+	log.Infof("step 1: start to register")
+	result, err := ptm.schedulerClient.RegisterPeerTask(ctx, request)
+	if err != nil {
+		log.Errorf("step 1: register failed: %s", err)
+		return err
+	}
+	if result.TaskId != meta.TaskID {
+		return errors.Errorf("task id mismatch, local: %s, scheduler: %s", meta.TaskID, result.TaskId)
+	}
+
+	// 3. report all pieces to scheduler, the begin piece result is sent by ReportPieceResult when
+	// the stream is opened, it must not be sent twice, otherwise the peer is scheduled twice
+	log.Infof("step 2: start to report piece result")
+	stream, err := ptm.schedulerClient.ReportPieceResult(ctx, meta.TaskID, request)
+	if err != nil {
+		log.Errorf("step 2: report piece result failed: %s", err)
+		return err
+	}
+	if err = reportLocalPieces(stream.Send, meta.TaskID, meta.PeerID, packet.PieceInfos); err != nil {
+		log.Errorf("step 2: %s", err)
+		return err
+	}
+	if err = stream.Send(scheduler.NewEndPieceResult(meta.TaskID, meta.PeerID, packet.TotalPiece)); err != nil {
+		log.Errorf("step 2: report end piece result failed: %s", err)
+		return err
+	}
+
+	// 4. report peer result, then scheduler treats this peer as a completed peer
+	err = ptm.schedulerClient.ReportPeerResult(ctx, &scheduler.PeerResult{
+		TaskId:          meta.TaskID,
+		PeerId:          meta.PeerID,
+		SrcIp:           ptm.host.Ip,
+		SecurityDomain:  ptm.host.SecurityDomain,
+		Idc:             ptm.host.Idc,
+		Url:             url,
+		ContentLength:   packet.ContentLength,
+		TotalPieceCount: packet.TotalPiece,
+		Success:         true,
+		Code:            base.Code_Success,
+	})
+	if err != nil {
+		log.Errorf("step 3: report successful peer result, error: %s", err)
+		return err
+	}
+	log.Infof("step 3: report successful peer result ok")
+	return nil
+}
+
+// reportLocalPieces reports the pieces already in local storage as successful piece results with send,
+// the stream must be opened by schedulerclient.ReportPieceResult which sends the begin piece result
+func reportLocalPieces(send func(*scheduler.PieceResult) error, taskID, peerID string, pieces []*base.PieceInfo) error {
+	now := uint64(time.Now().UnixNano())
+	for i, piece := range pieces {
+		err := send(&scheduler.PieceResult{
+			TaskId:        taskID,
+			SrcPid:        peerID,
+			DstPid:        peerID,
+			PieceInfo:     piece,
+			BeginTime:     now,
+			EndTime:       now,
+			Success:       true,
+			Code:          base.Code_Success,
+			FinishedCount: int32(i + 1),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "report piece %d result", piece.PieceNum)
+		}
+	}
+	return nil
+}
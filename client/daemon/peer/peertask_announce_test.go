@@ -0,0 +1,150 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	testifyassert "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/client/daemon/test"
+	mock_scheduler "d7y.io/dragonfly/v2/client/daemon/test/mock/scheduler"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/base/common"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+)
+
+// pieceResultRecorder records the piece results sent to scheduler in order
+type pieceResultRecorder struct {
+	sync.Mutex
+	results []*scheduler.PieceResult
+}
+
+func (r *pieceResultRecorder) Send(pr *scheduler.PieceResult) error {
+	r.Lock()
+	defer r.Unlock()
+	r.results = append(r.results, pr)
+	return nil
+}
+
+func (r *pieceResultRecorder) Results() []*scheduler.PieceResult {
+	r.Lock()
+	defer r.Unlock()
+	return append([]*scheduler.PieceResult{}, r.results...)
+}
+
+// assertBeginPieceResult checks the begin piece result is sent exactly once and before others
+func assertBeginPieceResult(assert *testifyassert.Assertions, results []*scheduler.PieceResult) {
+	if !assert.NotEmpty(results) {
+		return
+	}
+	assert.Equal(common.ZeroOfPiece, results[0].PieceInfo.PieceNum)
+	for _, pr := range results[1:] {
+		assert.NotEqual(common.ZeroOfPiece, pr.PieceInfo.PieceNum)
+	}
+}
+
+func TestPeerTaskManager_AnnouncePeerTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testBytes, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	var (
+		url     = "http://localhost/test/announce"
+		urlMeta = &base.UrlMeta{Tag: "d7y-test"}
+		meta    = storage.PeerTaskMetadata{
+			PeerID: "peer-announce",
+			TaskID: idgen.TaskID(url, urlMeta),
+		}
+		recorder = &pieceResultRecorder{}
+	)
+
+	dataDir, err := os.MkdirTemp("", "d7y-test-*")
+	assert.Nil(err)
+	defer os.RemoveAll(dataDir)
+	storageManager, err := storage.NewStorageManager(
+		config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Hour,
+			},
+		}, func(request storage.CommonTaskRequest) {})
+	assert.Nil(err)
+	err = storageManager.RegisterTask(context.Background(), storage.RegisterTaskRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		},
+		ContentLength: -1,
+		TotalPieces:   -1,
+	})
+	assert.Nil(err)
+	pm, err := NewPieceManager(storageManager, time.Minute, WithCalculateDigest(false))
+	assert.Nil(err)
+	assert.Nil(pm.ImportFile(context.Background(), meta, test.File, ""))
+
+	pps := mock_scheduler.NewMockPeerPacketStream(ctrl)
+	pps.EXPECT().Send(gomock.Any()).AnyTimes().DoAndReturn(recorder.Send)
+	sched := mock_scheduler.NewMockSchedulerClient(ctrl)
+	sched.EXPECT().RegisterPeerTask(gomock.Any(), gomock.Any()).Times(1).Return(
+		&scheduler.RegisterResult{TaskId: meta.TaskID}, nil)
+	sched.EXPECT().ReportPieceResult(gomock.Any(), meta.TaskID, gomock.Any()).Times(1).DoAndReturn(
+		func(ctx context.Context, taskID string, ptr *scheduler.PeerTaskRequest, opts ...grpc.CallOption) (schedulerclient.PeerPacketStream, error) {
+			// like the scheduler client, the begin piece result is sent when the stream is opened
+			return pps, pps.Send(scheduler.NewZeroPieceResult(taskID, ptr.PeerId))
+		})
+	sched.EXPECT().ReportPeerResult(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(ctx context.Context, pr *scheduler.PeerResult, opts ...grpc.CallOption) error {
+			assert.True(pr.Success)
+			assert.Equal(int64(len(testBytes)), pr.ContentLength)
+			return nil
+		})
+
+	ptm := &peerTaskManager{
+		host:            &scheduler.PeerHost{Ip: "127.0.0.1"},
+		storageManager:  storageManager,
+		schedulerClient: sched,
+	}
+	assert.Nil(ptm.AnnouncePeerTask(context.Background(), meta, url, urlMeta))
+
+	results := recorder.Results()
+	assertBeginPieceResult(assert, results)
+	if !assert.True(len(results) > 2) {
+		return
+	}
+	for i, pr := range results[1 : len(results)-1] {
+		assert.True(pr.Success)
+		assert.Equal(int32(i), pr.PieceInfo.PieceNum)
+		assert.Equal(int32(i+1), pr.FinishedCount)
+	}
+	assert.Equal(common.EndOfPiece, results[len(results)-1].PieceInfo.PieceNum)
+}
@@ -31,6 +31,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
@@ -39,6 +40,7 @@ import (
 	dfclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
@@ -88,6 +90,7 @@ type peerTask struct {
 	taskID          string
 	totalPiece      int32
 	md5             string
+	fileAttributes  *source.FileAttributes
 	contentLength   *atomic.Int64
 	completedLength *atomic.Int64
 	usedTraffic     *atomic.Uint64
@@ -97,6 +100,15 @@ type peerTask struct {
 
 	// TODO peerPacketStream
 	peerPacketStream schedulerclient.PeerPacketStream
+	// resumedPieceInfos are the pieces downloaded before the peer task is resumed,
+	// they are reported to scheduler when the peer task starts
+	resumedPieceInfos []*base.PieceInfo
+	// heldPieceResults are the successful piece results of the task with signature, they are sent after verified
+	heldPieceResults     []*scheduler.PieceResult
+	heldPieceResultsLock sync.Mutex
+	// verifyOnce and verifyErr hold the signature verification result of the task
+	verifyOnce sync.Once
+	verifyErr  error
 	// peerPacket is the latest available peers from peerPacketCh
 	peerPacket atomic.Value // *scheduler.PeerPacket
 	// peerPacketReady will receive a ready signal for peerPacket ready
@@ -189,6 +201,10 @@ func (pt *peerTask) GetPieceMd5Sign() string {
 	return pt.md5
 }
 
+func (pt *peerTask) GetFileAttributes() *source.FileAttributes {
+	return pt.fileAttributes
+}
+
 func (pt *peerTask) Context() context.Context {
 	return pt.ctx
 }
@@ -202,6 +218,7 @@ func (pt *peerTask) backSource() {
 }
 
 func (pt *peerTask) pullPieces(cleanUnfinishedFunc func()) {
+	pt.reportResumedPieces()
 	// when there is a single piece, try to download first
 	if pt.singlePiece != nil {
 		go pt.pullSinglePiece(cleanUnfinishedFunc)
@@ -474,6 +491,13 @@ loop:
 			pt.Debugf("update digest: %s", pt.md5)
 		}
 
+		// update file attributes, they are only known by the peer downloaded from source
+		if piecePacket.FileAttributes != nil && pt.fileAttributes == nil {
+			pt.fileAttributes = clientutil.FileAttributesFromProto(piecePacket.FileAttributes)
+			_ = pt.callback.Update(pt)
+			pt.Debugf("update file attributes: %#v", pt.fileAttributes)
+		}
+
 		// update content length
 		if piecePacket.ContentLength > 0 {
 			_ = pt.SetContentLength(piecePacket.ContentLength)
@@ -28,20 +28,29 @@ import (
 
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
 type FilePeerTaskRequest struct {
 	scheduler.PeerTaskRequest
-	Output            string
-	Limit             float64
-	DisableBackSource bool
-	Pattern           string
-	Callsystem        string
+	Output string
+	// Outputs replace Output when not empty, the content is materialized to all of them atomically
+	Outputs []storage.StoreOutput
+	// DisableFileAttributes does not apply the file attributes of the resource to the outputs
+	DisableFileAttributes bool
+	Limit                 float64
+	DisableBackSource     bool
+	Pattern               string
+	Callsystem            string
+	// Pin and Priority are applied to the task in local storage, they are not sent to scheduler
+	Pin      bool
+	Priority int32
 }
 
 // FilePeerTask represents a peer task to download a file
@@ -61,6 +70,11 @@ type filePeerTask struct {
 	disableBackSource bool
 	pattern           string
 	callsystem        string
+
+	// peerLength, cdnLength and backSourceLength count the bytes downloaded from other peers, cdn and source
+	peerLength       *atomic.Int64
+	cdnLength        *atomic.Int64
+	backSourceLength *atomic.Int64
 }
 
 var _ FilePeerTask = (*filePeerTask)(nil)
@@ -79,6 +93,13 @@ type FilePeerTaskProgress struct {
 	CompletedLength int64
 	PeerTaskDone    bool
 	DoneCallback    func()
+	// Reuse indicates the task is reused from the completed task in local storage
+	Reuse bool
+	// PeerLength, CDNLength and BackSourceLength are the bytes downloaded from other peers, cdn and source,
+	// the pieces resumed from the partial task are not counted
+	PeerLength       int64
+	CDNLength        int64
+	BackSourceLength int64
 }
 
 func newFilePeerTask(ctx context.Context,
@@ -178,6 +199,9 @@ func newFilePeerTask(ctx context.Context,
 		disableBackSource: request.DisableBackSource,
 		pattern:           request.Pattern,
 		callsystem:        request.Callsystem,
+		peerLength:        atomic.NewInt64(0),
+		cdnLength:         atomic.NewInt64(0),
+		backSourceLength:  atomic.NewInt64(0),
 		peerTask: peerTask{
 			host:                host,
 			needBackSource:      needBackSource,
@@ -267,9 +291,10 @@ func (pt *filePeerTask) ReportPieceResult(result *pieceTaskResult) error {
 	pt.readyPieces.Set(result.pieceResult.PieceInfo.PieceNum)
 	pt.completedLength.Add(int64(result.piece.RangeSize))
 	pt.lock.Unlock()
+	pt.countSourceLength(result.pieceResult.DstPid, int64(result.piece.RangeSize))
 
 	result.pieceResult.FinishedCount = pt.readyPieces.Settled()
-	_ = pt.peerPacketStream.Send(result.pieceResult)
+	_ = pt.sendPieceResult(result.pieceResult)
 	// send progress first to avoid close channel panic
 	p := &FilePeerTaskProgress{
 		State: &ProgressState{
@@ -277,11 +302,14 @@ func (pt *filePeerTask) ReportPieceResult(result *pieceTaskResult) error {
 			Code:    result.pieceResult.Code,
 			Msg:     "downloading",
 		},
-		TaskID:          pt.taskID,
-		PeerID:          pt.peerID,
-		ContentLength:   pt.contentLength.Load(),
-		CompletedLength: pt.completedLength.Load(),
-		PeerTaskDone:    false,
+		TaskID:           pt.taskID,
+		PeerID:           pt.peerID,
+		ContentLength:    pt.contentLength.Load(),
+		CompletedLength:  pt.completedLength.Load(),
+		PeerTaskDone:     false,
+		PeerLength:       pt.peerLength.Load(),
+		CDNLength:        pt.cdnLength.Load(),
+		BackSourceLength: pt.backSourceLength.Load(),
 	}
 
 	select {
@@ -300,17 +328,43 @@ func (pt *filePeerTask) ReportPieceResult(result *pieceTaskResult) error {
 	return pt.finish()
 }
 
+// countSourceLength counts the piece length by where the piece is downloaded from,
+// the pieces downloaded from source are reported with the peer id of current peer task
+func (pt *filePeerTask) countSourceLength(dstPid string, length int64) {
+	switch {
+	case dstPid == pt.peerID:
+		pt.backSourceLength.Add(length)
+	case idgen.IsCDNPeerID(dstPid):
+		pt.cdnLength.Add(length)
+	default:
+		pt.peerLength.Add(length)
+	}
+}
+
 func (pt *filePeerTask) finish() error {
 	var err error
 	if err = pt.callback.ValidateDigest(pt); err != nil {
 		pt.Errorf("validate digest error: %s", err)
 		pt.span.RecordError(err)
+		if errors.Is(err, storage.ErrInvalidDigest) {
+			pt.failedCode = base.Code_ClientDigestMismatch
+		}
+		pt.failedReason = err.Error()
+		pt.cleanUnfinished()
+		return err
+	}
+	if err = pt.verifyTaskSignature(pt); err != nil {
+		pt.Errorf("verify signature error: %s", err)
+		pt.span.RecordError(err)
+		pt.failedCode = base.Code_ClientSignatureVerifyFail
+		pt.failedReason = err.Error()
 		pt.cleanUnfinished()
 		return err
 	}
 	// send last progress
 	pt.once.Do(func() {
 		defer pt.recoverFromPanic()
+		pt.sendHeldPieceResults()
 		// send EOF piece result to scheduler
 		_ = pt.peerPacketStream.Send(
 			scheduler.NewEndPieceResult(pt.taskID, pt.peerID, pt.readyPieces.Settled()))
@@ -338,11 +392,14 @@ func (pt *filePeerTask) finish() error {
 				Code:    code,
 				Msg:     message,
 			},
-			TaskID:          pt.taskID,
-			PeerID:          pt.peerID,
-			ContentLength:   pt.contentLength.Load(),
-			CompletedLength: pt.completedLength.Load(),
-			PeerTaskDone:    true,
+			TaskID:           pt.taskID,
+			PeerID:           pt.peerID,
+			ContentLength:    pt.contentLength.Load(),
+			CompletedLength:  pt.completedLength.Load(),
+			PeerTaskDone:     true,
+			PeerLength:       pt.peerLength.Load(),
+			CDNLength:        pt.cdnLength.Load(),
+			BackSourceLength: pt.backSourceLength.Load(),
 			DoneCallback: func() {
 				progressDone = true
 				close(pt.progressStopCh)
@@ -401,11 +458,14 @@ func (pt *filePeerTask) cleanUnfinished() {
 				Code:    pt.failedCode,
 				Msg:     pt.failedReason,
 			},
-			TaskID:          pt.taskID,
-			PeerID:          pt.peerID,
-			ContentLength:   pt.contentLength.Load(),
-			CompletedLength: pt.completedLength.Load(),
-			PeerTaskDone:    true,
+			TaskID:           pt.taskID,
+			PeerID:           pt.peerID,
+			ContentLength:    pt.contentLength.Load(),
+			CompletedLength:  pt.completedLength.Load(),
+			PeerTaskDone:     true,
+			PeerLength:       pt.peerLength.Load(),
+			CDNLength:        pt.cdnLength.Load(),
+			BackSourceLength: pt.backSourceLength.Load(),
 			DoneCallback: func() {
 				progressDone = true
 				close(pt.progressStopCh)
@@ -477,6 +537,9 @@ func (pt *filePeerTask) backSource() {
 	err := pt.pieceManager.DownloadSource(pt.ctx, pt, pt.request)
 	if err != nil {
 		pt.Errorf("download from source error: %s", err)
+		if errors.Is(err, digestutils.ErrDigestNotMatch) {
+			pt.failedCode = base.Code_ClientDigestMismatch
+		}
 		pt.failedReason = err.Error()
 		backSourceSpan.SetAttributes(config.AttributePeerTaskSuccess.Bool(false))
 		backSourceSpan.RecordError(err)
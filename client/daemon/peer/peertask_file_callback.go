@@ -50,9 +50,13 @@ func (p *filePeerTaskCallback) Init(pt Task) error {
 				TaskID:      pt.GetTaskID(),
 				Destination: p.req.Output,
 			},
+			URL:           p.req.Url,
+			URLMeta:       p.req.UrlMeta,
 			ContentLength: pt.GetContentLength(),
 			TotalPieces:   int32(pt.GetTotalPieces()),
 			PieceMd5Sign:  pt.GetPieceMd5Sign(),
+			Pinned:        p.req.Pin,
+			Priority:      p.req.Priority,
 		})
 	if err != nil {
 		pt.Log().Errorf("register task to storage manager failed: %s", err)
@@ -68,9 +72,10 @@ func (p *filePeerTaskCallback) Update(pt Task) error {
 				PeerID: pt.GetPeerID(),
 				TaskID: pt.GetTaskID(),
 			},
-			ContentLength: pt.GetContentLength(),
-			TotalPieces:   int32(pt.GetTotalPieces()),
-			PieceMd5Sign:  pt.GetPieceMd5Sign(),
+			ContentLength:  pt.GetContentLength(),
+			TotalPieces:    int32(pt.GetTotalPieces()),
+			PieceMd5Sign:   pt.GetPieceMd5Sign(),
+			FileAttributes: pt.GetFileAttributes(),
 		})
 	if err != nil {
 		pt.Log().Errorf("update task to storage manager failed: %s", err)
@@ -89,8 +94,10 @@ func (p *filePeerTaskCallback) Done(pt Task) error {
 				TaskID:      pt.GetTaskID(),
 				Destination: p.req.Output,
 			},
-			MetadataOnly: false,
-			TotalPieces:  int32(pt.GetTotalPieces()),
+			MetadataOnly:          false,
+			TotalPieces:           int32(pt.GetTotalPieces()),
+			Outputs:               p.req.Outputs,
+			DisableFileAttributes: p.req.DisableFileAttributes,
 		})
 	if e != nil {
 		return e
@@ -168,3 +175,17 @@ func (p *filePeerTaskCallback) ValidateDigest(pt Task) error {
 	}
 	return err
 }
+
+func (p *filePeerTaskCallback) VerifySignature(pt Task) error {
+	err := p.ptm.verifySignature(p.pt.ctx,
+		storage.PeerTaskMetadata{
+			PeerID: pt.GetPeerID(),
+			TaskID: pt.GetTaskID(),
+		}, &p.req.PeerTaskRequest, true)
+	if err != nil {
+		pt.Log().Errorf("%s", err)
+	} else if p.req.UrlMeta.GetSignature() != nil {
+		pt.Log().Debugf("verified signature")
+	}
+	return err
+}
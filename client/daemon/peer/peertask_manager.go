@@ -33,11 +33,13 @@ import (
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	"d7y.io/dragonfly/v2/client/daemon/signature"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+	"d7y.io/dragonfly/v2/pkg/source"
 )
 
 // TaskManager processes all peer tasks request
@@ -49,11 +51,25 @@ type TaskManager interface {
 		progress chan *FilePeerTaskProgress, tiny *TinyData, err error)
 	// StartStreamPeerTask starts a peer task with stream io
 	// tiny stands task file is tiny and task is done
-	StartStreamPeerTask(ctx context.Context, req *scheduler.PeerTaskRequest) (
+	StartStreamPeerTask(ctx context.Context, req *StreamPeerTaskRequest) (
 		readCloser io.ReadCloser, attribute map[string]string, err error)
 
 	IsPeerTaskRunning(pid string) bool
 
+	// FindCompletedTask finds a completed task in local storage for reusing,
+	// it returns nil when multiplex is disabled or no completed task found
+	FindCompletedTask(taskID string) *storage.ReusePeerTask
+
+	// ReadCompletedTaskRange reads a range of the completed task found by FindCompletedTask
+	ReadCompletedTaskRange(ctx context.Context, meta storage.PeerTaskMetadata, rg clientutil.Range) (io.ReadCloser, error)
+
+	// AnnouncePeerTask announces a peer task which is already completed in local storage to scheduler,
+	// then other peers can download it from this peer
+	AnnouncePeerTask(ctx context.Context, meta storage.PeerTaskMetadata, url string, urlMeta *base.UrlMeta) error
+
+	// GetPieceManager returns the piece manager used by peer tasks
+	GetPieceManager() PieceManager
+
 	// Stop stops the PeerTaskManager
 	Stop(ctx context.Context) error
 }
@@ -75,6 +91,8 @@ type Task interface {
 	GetTraffic() uint64
 	SetPieceMd5Sign(string)
 	GetPieceMd5Sign() string
+	// GetFileAttributes returns the attributes of the resource, nil when they are unknown
+	GetFileAttributes() *source.FileAttributes
 }
 
 // TaskCallback inserts some operations for peer task download lifecycle
@@ -85,6 +103,8 @@ type TaskCallback interface {
 	Fail(pt Task, code base.Code, reason string) error
 	GetStartTime() time.Time
 	ValidateDigest(pt Task) error
+	// VerifySignature verifies the content with the signature in url meta after the digest is validated
+	VerifySignature(pt Task) error
 }
 
 type TinyData struct {
@@ -109,6 +129,9 @@ type peerTaskManager struct {
 	storageManager  storage.Manager
 
 	runningPeerTasks sync.Map
+	// resumingPeerTasks holds the peer ids of the unfinished peer tasks which are being resumed,
+	// avoid resuming one peer task by multiple requests
+	resumingPeerTasks sync.Map
 
 	perPeerRateLimit rate.Limit
 
@@ -119,6 +142,9 @@ type peerTaskManager struct {
 
 	calculateDigest bool
 
+	// signatureManager verifies the content of the peer tasks which request signature verification
+	signatureManager signature.Manager
+
 	getPiecesMaxRetry int
 }
 
@@ -131,6 +157,7 @@ func NewPeerTaskManager(
 	perPeerRateLimit rate.Limit,
 	multiplex bool,
 	calculateDigest bool,
+	signatureManager signature.Manager,
 	getPiecesMaxRetry int) (TaskManager, error) {
 
 	ptm := &peerTaskManager{
@@ -143,6 +170,7 @@ func NewPeerTaskManager(
 		perPeerRateLimit:  perPeerRateLimit,
 		enableMultiplex:   multiplex,
 		calculateDigest:   calculateDigest,
+		signatureManager:  signatureManager,
 		getPiecesMaxRetry: getPiecesMaxRetry,
 	}
 	return ptm, nil
@@ -158,6 +186,11 @@ func (ptm *peerTaskManager) StartFilePeerTask(ctx context.Context, req *FilePeer
 			return progress, nil, nil
 		}
 	}
+	partial := ptm.tryResumePeerTask(&req.PeerTaskRequest)
+	if partial != nil {
+		defer ptm.resumingPeerTasks.Delete(partial.PeerID)
+		ptm.pinReusedTask(partial.TaskID, req.Pin, req.Priority)
+	}
 	// TODO ensure scheduler is ok first
 	start := time.Now()
 	limit := ptm.perPeerRateLimit
@@ -171,9 +204,27 @@ func (ptm *peerTaskManager) StartFilePeerTask(ctx context.Context, req *FilePeer
 	}
 	// tiny file content is returned by scheduler, just write to output
 	if tiny != nil {
-		ptm.storeTinyPeerTask(ctx, tiny)
 		defer tiny.span.End()
 		log := logger.With("peer", tiny.PeerID, "task", tiny.TaskID, "component", "peerTaskManager")
+		if err = ptm.verifyContentSignature(ctx, &req.PeerTaskRequest, bytes.NewReader(tiny.Content)); err != nil {
+			tiny.span.RecordError(err)
+			tiny.span.SetAttributes(config.AttributePeerTaskSuccess.Bool(false))
+			log.Errorf("%s", err)
+			return nil, nil, err
+		}
+		ptm.storeTinyPeerTask(ctx, tiny)
+		if len(req.Outputs) > 0 {
+			if err = storage.StoreContentToOutputs(tiny.Content, req.Outputs); err != nil {
+				tiny.span.RecordError(err)
+				tiny.span.SetAttributes(config.AttributePeerTaskSuccess.Bool(false))
+				log.Errorf("store tiny data to outputs error: %s", err)
+				return nil, nil, err
+			}
+			log.Debugf("copied tasks data %d bytes to %d outputs", len(tiny.Content), len(req.Outputs))
+			tiny.span.SetAttributes(config.AttributePeerTaskSuccess.Bool(true))
+			tiny.span.SetAttributes(config.AttributePeerTaskSize.Int(len(tiny.Content)))
+			return nil, tiny, nil
+		}
 		_, err = os.Stat(req.Output)
 		if err == nil {
 			// remove exist file
@@ -206,6 +257,9 @@ func (ptm *peerTaskManager) StartFilePeerTask(ctx context.Context, req *FilePeer
 		req:   req,
 		start: start,
 	})
+	if partial != nil {
+		pt.resume(partial)
+	}
 
 	ptm.runningPeerTasks.Store(req.PeerId, pt)
 
@@ -214,22 +268,36 @@ func (ptm *peerTaskManager) StartFilePeerTask(ctx context.Context, req *FilePeer
 	return progress, nil, err
 }
 
-func (ptm *peerTaskManager) StartStreamPeerTask(ctx context.Context, req *scheduler.PeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+func (ptm *peerTaskManager) StartStreamPeerTask(ctx context.Context, req *StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
 	if ptm.enableMultiplex {
-		r, attr, ok := ptm.tryReuseStreamPeerTask(ctx, req)
+		r, attr, ok := ptm.tryReuseStreamPeerTask(ctx, &req.PeerTaskRequest)
 		if ok {
 			metrics.PeerTaskReuseCount.Add(1)
 			return r, attr, nil
 		}
 	}
+	partial := ptm.tryResumePeerTask(&req.PeerTaskRequest)
+	if partial != nil {
+		defer ptm.resumingPeerTasks.Delete(partial.PeerID)
+	}
 
 	start := time.Now()
-	ctx, pt, tiny, err := newStreamPeerTask(ctx, ptm, req)
+	limit := ptm.perPeerRateLimit
+	if req.Limit > 0 {
+		limit = rate.Limit(req.Limit)
+	}
+	ctx, pt, tiny, err := newStreamPeerTask(ctx, ptm, req, limit)
 	if err != nil {
 		return nil, nil, err
 	}
 	// tiny file content is returned by scheduler, just write to output
 	if tiny != nil {
+		if err = ptm.verifyContentSignature(ctx, &req.PeerTaskRequest, bytes.NewReader(tiny.Content)); err != nil {
+			tiny.span.RecordError(err)
+			tiny.span.SetAttributes(config.AttributePeerTaskSuccess.Bool(false))
+			logger.Errorf("%s", err)
+			return nil, nil, err
+		}
 		ptm.storeTinyPeerTask(ctx, tiny)
 		logger.Infof("copied tasks data %d bytes to buffer", len(tiny.Content))
 		tiny.span.SetAttributes(config.AttributePeerTaskSuccess.Bool(true))
@@ -242,15 +310,40 @@ func (ptm *peerTaskManager) StartStreamPeerTask(ctx context.Context, req *schedu
 		&streamPeerTaskCallback{
 			ptm:   ptm,
 			pt:    pt,
-			req:   req,
+			req:   &req.PeerTaskRequest,
 			start: start,
 		})
+	if partial != nil {
+		pt.resume(partial)
+	}
 
 	ptm.runningPeerTasks.Store(req.PeerId, pt)
 
 	// FIXME when failed due to schedulerClient error, relocate schedulerClient and retry
 	readCloser, attribute, err := pt.Start(ctx)
-	return readCloser, attribute, err
+	if err != nil || req.UrlMeta.GetSignature() == nil {
+		return readCloser, attribute, err
+	}
+	return ptm.waitVerifiedStream(ctx, pt, readCloser, attribute)
+}
+
+// waitVerifiedStream drains the stream until the content is stored and verified, then reads the verified content
+// from local storage, the untrusted content is never returned to the caller
+func (ptm *peerTaskManager) waitVerifiedStream(ctx context.Context, pt *streamPeerTask,
+	readCloser io.ReadCloser, attribute map[string]string) (io.ReadCloser, map[string]string, error) {
+	_, err := io.Copy(io.Discard, readCloser)
+	readCloser.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := ptm.storageManager.ReadAllPieces(ctx, &storage.PeerTaskMetadata{
+		PeerID: pt.GetPeerID(),
+		TaskID: pt.GetTaskID(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, attribute, nil
 }
 
 func (ptm *peerTaskManager) Stop(ctx context.Context) error {
@@ -258,6 +351,10 @@ func (ptm *peerTaskManager) Stop(ctx context.Context) error {
 	return nil
 }
 
+func (ptm *peerTaskManager) GetPieceManager() PieceManager {
+	return ptm.pieceManager
+}
+
 func (ptm *peerTaskManager) PeerTaskDone(peerID string) {
 	ptm.runningPeerTasks.Delete(peerID)
 }
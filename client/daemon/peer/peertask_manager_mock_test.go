@@ -10,9 +10,11 @@ import (
 	reflect "reflect"
 	time "time"
 
+	clientutil "d7y.io/dragonfly/v2/client/clientutil"
+	storage "d7y.io/dragonfly/v2/client/daemon/storage"
 	dflog "d7y.io/dragonfly/v2/internal/dflog"
 	base "d7y.io/dragonfly/v2/pkg/rpc/base"
-	scheduler "d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+	source "d7y.io/dragonfly/v2/pkg/source"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -39,6 +41,48 @@ func (m *MockTaskManager) EXPECT() *MockTaskManagerMockRecorder {
 	return m.recorder
 }
 
+// AnnouncePeerTask mocks base method.
+func (m *MockTaskManager) AnnouncePeerTask(ctx context.Context, meta storage.PeerTaskMetadata, url string, urlMeta *base.UrlMeta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnnouncePeerTask", ctx, meta, url, urlMeta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnnouncePeerTask indicates an expected call of AnnouncePeerTask.
+func (mr *MockTaskManagerMockRecorder) AnnouncePeerTask(ctx, meta, url, urlMeta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnnouncePeerTask", reflect.TypeOf((*MockTaskManager)(nil).AnnouncePeerTask), ctx, meta, url, urlMeta)
+}
+
+// FindCompletedTask mocks base method.
+func (m *MockTaskManager) FindCompletedTask(taskID string) *storage.ReusePeerTask {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCompletedTask", taskID)
+	ret0, _ := ret[0].(*storage.ReusePeerTask)
+	return ret0
+}
+
+// FindCompletedTask indicates an expected call of FindCompletedTask.
+func (mr *MockTaskManagerMockRecorder) FindCompletedTask(taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCompletedTask", reflect.TypeOf((*MockTaskManager)(nil).FindCompletedTask), taskID)
+}
+
+// GetPieceManager mocks base method.
+func (m *MockTaskManager) GetPieceManager() PieceManager {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPieceManager")
+	ret0, _ := ret[0].(PieceManager)
+	return ret0
+}
+
+// GetPieceManager indicates an expected call of GetPieceManager.
+func (mr *MockTaskManagerMockRecorder) GetPieceManager() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPieceManager", reflect.TypeOf((*MockTaskManager)(nil).GetPieceManager))
+}
+
 // IsPeerTaskRunning mocks base method.
 func (m *MockTaskManager) IsPeerTaskRunning(pid string) bool {
 	m.ctrl.T.Helper()
@@ -53,6 +97,21 @@ func (mr *MockTaskManagerMockRecorder) IsPeerTaskRunning(pid interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPeerTaskRunning", reflect.TypeOf((*MockTaskManager)(nil).IsPeerTaskRunning), pid)
 }
 
+// ReadCompletedTaskRange mocks base method.
+func (m *MockTaskManager) ReadCompletedTaskRange(ctx context.Context, meta storage.PeerTaskMetadata, rg clientutil.Range) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadCompletedTaskRange", ctx, meta, rg)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadCompletedTaskRange indicates an expected call of ReadCompletedTaskRange.
+func (mr *MockTaskManagerMockRecorder) ReadCompletedTaskRange(ctx, meta, rg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadCompletedTaskRange", reflect.TypeOf((*MockTaskManager)(nil).ReadCompletedTaskRange), ctx, meta, rg)
+}
+
 // StartFilePeerTask mocks base method.
 func (m *MockTaskManager) StartFilePeerTask(ctx context.Context, req *FilePeerTaskRequest) (chan *FilePeerTaskProgress, *TinyData, error) {
 	m.ctrl.T.Helper()
@@ -70,7 +129,7 @@ func (mr *MockTaskManagerMockRecorder) StartFilePeerTask(ctx, req interface{}) *
 }
 
 // StartStreamPeerTask mocks base method.
-func (m *MockTaskManager) StartStreamPeerTask(ctx context.Context, req *scheduler.PeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+func (m *MockTaskManager) StartStreamPeerTask(ctx context.Context, req *StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "StartStreamPeerTask", ctx, req)
 	ret0, _ := ret[0].(io.ReadCloser)
@@ -176,6 +235,20 @@ func (mr *MockTaskMockRecorder) GetPeerID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPeerID", reflect.TypeOf((*MockTask)(nil).GetPeerID))
 }
 
+// GetFileAttributes mocks base method.
+func (m *MockTask) GetFileAttributes() *source.FileAttributes {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFileAttributes")
+	ret0, _ := ret[0].(*source.FileAttributes)
+	return ret0
+}
+
+// GetFileAttributes indicates an expected call of GetFileAttributes.
+func (mr *MockTaskMockRecorder) GetFileAttributes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileAttributes", reflect.TypeOf((*MockTask)(nil).GetFileAttributes))
+}
+
 // GetPieceMd5Sign mocks base method.
 func (m *MockTask) GetPieceMd5Sign() string {
 	m.ctrl.T.Helper()
@@ -402,3 +475,31 @@ func (mr *MockTaskCallbackMockRecorder) Update(pt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTaskCallback)(nil).Update), pt)
 }
+
+// ValidateDigest mocks base method.
+func (m *MockTaskCallback) ValidateDigest(pt Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateDigest", pt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateDigest indicates an expected call of ValidateDigest.
+func (mr *MockTaskCallbackMockRecorder) ValidateDigest(pt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateDigest", reflect.TypeOf((*MockTaskCallback)(nil).ValidateDigest), pt)
+}
+
+// VerifySignature mocks base method.
+func (m *MockTaskCallback) VerifySignature(pt Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifySignature", pt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifySignature indicates an expected call of VerifySignature.
+func (mr *MockTaskCallbackMockRecorder) VerifySignature(pt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifySignature", reflect.TypeOf((*MockTaskCallback)(nil).VerifySignature), pt)
+}
@@ -57,6 +57,10 @@ type componentsOption struct {
 	pieceSize          uint32
 	pieceParallelCount int32
 	peerPacketDelay    []time.Duration
+	// pieceResults records the piece results sent to scheduler when it is not nil
+	pieceResults *pieceResultRecorder
+	// fileAttributes are sent by the mock peer like it downloaded from source
+	fileAttributes *base.FileAttributes
 }
 
 func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOption) (
@@ -86,11 +90,12 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 				})
 		}
 		return &base.PiecePacket{
-			TaskId:        request.TaskId,
-			DstPid:        "peer-x",
-			PieceInfos:    tasks,
-			ContentLength: opt.contentLength,
-			TotalPiece:    int32(math.Ceil(float64(opt.contentLength) / float64(opt.pieceSize))),
+			TaskId:         request.TaskId,
+			DstPid:         "peer-x",
+			PieceInfos:     tasks,
+			ContentLength:  opt.contentLength,
+			TotalPiece:     int32(math.Ceil(float64(opt.contentLength) / float64(opt.pieceSize))),
+			FileAttributes: opt.fileAttributes,
 		}, nil
 	})
 	ln, _ := rpc.Listen(dfnet.NetAddr{
@@ -110,6 +115,9 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 	pps := mock_scheduler.NewMockPeerPacketStream(ctrl)
 	pps.EXPECT().Send(gomock.Any()).AnyTimes().DoAndReturn(
 		func(pr *scheduler.PieceResult) error {
+			if opt.pieceResults != nil {
+				return opt.pieceResults.Send(pr)
+			}
 			return nil
 		})
 	var delayCount int
@@ -145,7 +153,8 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 		})
 	sched.EXPECT().ReportPieceResult(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, taskId string, ptr *scheduler.PeerTaskRequest, opts ...grpc.CallOption) (schedulerclient.PeerPacketStream, error) {
-			return pps, nil
+			// like the scheduler client, the begin piece result is sent when the stream is opened
+			return pps, pps.Send(scheduler.NewZeroPieceResult(taskId, ptr.PeerId))
 		})
 	sched.EXPECT().ReportPeerResult(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, pr *scheduler.PeerResult, opts ...grpc.CallOption) error {
@@ -182,6 +191,8 @@ func TestPeerTaskManager_StartFilePeerTask(t *testing.T) {
 		taskID = "task-0"
 
 		output = "../test/testdata/test.output"
+
+		lastModified = time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
 	)
 	defer os.Remove(output)
 
@@ -192,6 +203,7 @@ func TestPeerTaskManager_StartFilePeerTask(t *testing.T) {
 			contentLength:      int64(mockContentLength),
 			pieceSize:          uint32(pieceSize),
 			pieceParallelCount: pieceParallelCount,
+			fileAttributes:     &base.FileAttributes{Mode: 0600, LastModified: lastModified},
 		})
 	defer storageManager.CleanUp()
 
@@ -248,6 +260,12 @@ func TestPeerTaskManager_StartFilePeerTask(t *testing.T) {
 	outputBytes, err := os.ReadFile(output)
 	assert.Nil(err, "load output file")
 	assert.Equal(testBytes, outputBytes, "output and desired output must match")
+
+	// the file attributes from the peer downloaded from source are applied to output
+	stat, err := os.Stat(output)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), stat.Mode().Perm())
+	assert.Equal(lastModified, stat.ModTime().UnixNano()/int64(time.Millisecond))
 }
 
 func TestPeerTaskManager_StartStreamPeerTask(t *testing.T) {
@@ -304,13 +322,15 @@ func TestPeerTaskManager_StartStreamPeerTask(t *testing.T) {
 		},
 	}
 
-	r, _, err := ptm.StartStreamPeerTask(context.Background(), &scheduler.PeerTaskRequest{
-		Url: "http://localhost/test/data",
-		UrlMeta: &base.UrlMeta{
-			Tag: "d7y-test",
+	r, _, err := ptm.StartStreamPeerTask(context.Background(), &StreamPeerTaskRequest{
+		PeerTaskRequest: scheduler.PeerTaskRequest{
+			Url: "http://localhost/test/data",
+			UrlMeta: &base.UrlMeta{
+				Tag: "d7y-test",
+			},
+			PeerId:   peerID,
+			PeerHost: &scheduler.PeerHost{},
 		},
-		PeerId:   peerID,
-		PeerHost: &scheduler.PeerHost{},
 	})
 	assert.Nil(err, "start stream peer task")
 
@@ -377,13 +397,15 @@ func TestPeerTaskManager_StartStreamPeerTask_BackSource(t *testing.T) {
 		},
 	}
 
-	r, _, err := ptm.StartStreamPeerTask(context.Background(), &scheduler.PeerTaskRequest{
-		Url: ts.URL,
-		UrlMeta: &base.UrlMeta{
-			Tag: "d7y-test",
+	r, _, err := ptm.StartStreamPeerTask(context.Background(), &StreamPeerTaskRequest{
+		PeerTaskRequest: scheduler.PeerTaskRequest{
+			Url: ts.URL,
+			UrlMeta: &base.UrlMeta{
+				Tag: "d7y-test",
+			},
+			PeerId:   peerID,
+			PeerHost: &scheduler.PeerHost{},
 		},
-		PeerId:   peerID,
-		PeerHost: &scheduler.PeerHost{},
 	})
 	assert.Nil(err, "start stream peer task")
 
@@ -0,0 +1,93 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// tryResumePeerTask finds an unfinished peer task of the same task in local storage, which is usually
+// interrupted by daemon restart, when found, the request takes over the peer id of it,
+// then the downloaded pieces are reused and only the missing pieces will be downloaded
+func (ptm *peerTaskManager) tryResumePeerTask(request *scheduler.PeerTaskRequest) *storage.PartialPeerTask {
+	taskID := idgen.TaskID(request.Url, request.UrlMeta)
+	partial := ptm.storageManager.FindPartialTask(taskID)
+	if partial == nil {
+		return nil
+	}
+	if _, loaded := ptm.resumingPeerTasks.LoadOrStore(partial.PeerID, struct{}{}); loaded {
+		return nil
+	}
+	// the peer task is stored in runningPeerTasks before it is deleted from resumingPeerTasks,
+	// so check it after LoadOrStore
+	if ptm.IsPeerTaskRunning(partial.PeerID) {
+		ptm.resumingPeerTasks.Delete(partial.PeerID)
+		return nil
+	}
+
+	logger.With("peer", request.PeerId, "task", taskID, "component", "resumePeerTask").
+		Infof("resume unfinished peer task: %s, downloaded pieces: %d", partial.PeerID, len(partial.Pieces))
+	request.PeerId = partial.PeerID
+	metrics.PeerTaskResumeCount.Add(1)
+	return partial
+}
+
+// resume marks the downloaded pieces of the unfinished peer task as ready, they are reported to scheduler
+// when the peer task starts, it must be called before the peer task starts
+func (pt *peerTask) resume(partial *storage.PartialPeerTask) {
+	pt.lock.Lock()
+	for _, piece := range partial.Pieces {
+		pt.readyPieces.Set(piece.PieceNum)
+		pt.requestedPieces.Set(piece.PieceNum)
+		pt.completedLength.Add(int64(piece.RangeSize))
+	}
+	pt.lock.Unlock()
+	if partial.ContentLength >= 0 {
+		pt.contentLength.Store(partial.ContentLength)
+	}
+	if partial.TotalPieces > 0 {
+		pt.totalPiece = partial.TotalPieces
+	}
+	if len(partial.PieceMd5Sign) > 0 {
+		pt.md5 = partial.PieceMd5Sign
+	}
+	pt.resumedPieceInfos = partial.Pieces
+	pt.Infof("resumed %d pieces, completed length: %d", len(partial.Pieces), pt.completedLength.Load())
+}
+
+// reportResumedPieces reports the resumed pieces to scheduler after the begin piece result,
+// which is sent when the peer packet stream is opened
+func (pt *peerTask) reportResumedPieces() {
+	if pt.peerPacketStream == nil || len(pt.resumedPieceInfos) == 0 {
+		return
+	}
+	if err := reportLocalPieces(pt.sendPieceResult, pt.taskID, pt.peerID, pt.resumedPieceInfos); err != nil {
+		pt.Errorf("report resumed pieces error: %s", err)
+	}
+}
+
+// resume records the downloaded pieces additionally, they will be written to the stream when it starts
+func (s *streamPeerTask) resume(partial *storage.PartialPeerTask) {
+	s.peerTask.resume(partial)
+	for _, piece := range partial.Pieces {
+		s.resumedPieces = append(s.resumedPieces, piece.PieceNum)
+	}
+}
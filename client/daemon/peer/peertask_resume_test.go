@@ -0,0 +1,166 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/client/daemon/test"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+func TestPeerTaskManager_ResumeFilePeerTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testBytes, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	var (
+		pieceSize     = 1024
+		pieceCount    = int(math.Ceil(float64(len(testBytes)) / float64(pieceSize)))
+		resumedPieces = 2
+
+		url          = "http://localhost/test/resume"
+		urlMeta      = &base.UrlMeta{Tag: "d7y-test"}
+		taskID       = idgen.TaskID(url, urlMeta)
+		resumedPeers = "peer-resumed"
+		recorder     = &pieceResultRecorder{}
+
+		output = "../test/testdata/test.resume.output"
+	)
+	defer os.Remove(output)
+
+	schedulerClient, storageManager := setupPeerTaskManagerComponents(
+		ctrl,
+		componentsOption{
+			taskID:             taskID,
+			contentLength:      int64(len(testBytes)),
+			pieceSize:          uint32(pieceSize),
+			pieceParallelCount: 4,
+			pieceResults:       recorder,
+		})
+	defer storageManager.CleanUp()
+
+	// the first pieces are downloaded before the daemon restarts
+	err = storageManager.RegisterTask(context.Background(), storage.RegisterTaskRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{
+			PeerID: resumedPeers,
+			TaskID: taskID,
+		},
+		ContentLength: int64(len(testBytes)),
+		TotalPieces:   int32(pieceCount),
+	})
+	assert.Nil(err, "register partial task")
+	for i := 0; i < resumedPieces; i++ {
+		_, err = storageManager.WritePiece(context.Background(), &storage.WritePieceRequest{
+			PeerTaskMetadata: storage.PeerTaskMetadata{
+				PeerID: resumedPeers,
+				TaskID: taskID,
+			},
+			PieceMetadata: storage.PieceMetadata{
+				Num: int32(i),
+				Range: clientutil.Range{
+					Start:  int64(i * pieceSize),
+					Length: int64(pieceSize),
+				},
+			},
+			Reader: bytes.NewBuffer(testBytes[i*pieceSize : (i+1)*pieceSize]),
+		})
+		assert.Nil(err, "write resumed piece")
+	}
+
+	// only the missing pieces are downloaded
+	downloader := NewMockPieceDownloader(ctrl)
+	downloader.EXPECT().DownloadPiece(gomock.Any(), gomock.Any()).Times(pieceCount - resumedPieces).DoAndReturn(
+		func(ctx context.Context, task *DownloadPieceRequest) (io.Reader, io.Closer, error) {
+			assert.GreaterOrEqual(task.piece.PieceNum, int32(resumedPieces))
+			rc := io.NopCloser(
+				bytes.NewBuffer(
+					testBytes[task.piece.RangeStart : task.piece.RangeStart+uint64(task.piece.RangeSize)],
+				))
+			return rc, rc, nil
+		})
+
+	ptm := &peerTaskManager{
+		host: &scheduler.PeerHost{
+			Ip: "127.0.0.1",
+		},
+		pieceManager: &pieceManager{
+			storageManager:  storageManager,
+			pieceDownloader: downloader,
+		},
+		storageManager:  storageManager,
+		schedulerClient: schedulerClient,
+		schedulerOption: config.SchedulerOption{
+			ScheduleTimeout: clientutil.Duration{Duration: 10 * time.Minute},
+		},
+	}
+	progress, _, err := ptm.StartFilePeerTask(context.Background(), &FilePeerTaskRequest{
+		PeerTaskRequest: scheduler.PeerTaskRequest{
+			Url:      url,
+			UrlMeta:  urlMeta,
+			PeerId:   "peer-0",
+			PeerHost: &scheduler.PeerHost{},
+		},
+		Output: output,
+	})
+	assert.Nil(err, "start file peer task")
+
+	var p *FilePeerTaskProgress
+	for p = range progress {
+		assert.True(p.State.Success)
+		if p.PeerTaskDone {
+			p.DoneCallback()
+			break
+		}
+	}
+	assert.NotNil(p)
+	assert.True(p.PeerTaskDone)
+
+	outputBytes, err := os.ReadFile(output)
+	assert.Nil(err, "load output file")
+	assert.Equal(testBytes, outputBytes, "output and desired output must match")
+
+	// the resumed pieces are reported right after the begin piece result
+	results := recorder.Results()
+	assertBeginPieceResult(assert, results)
+	if !assert.True(len(results) > resumedPieces) {
+		return
+	}
+	for i, pr := range results[1 : resumedPieces+1] {
+		assert.True(pr.Success)
+		assert.Equal(resumedPeers, pr.SrcPid)
+		assert.Equal(resumedPeers, pr.DstPid)
+		assert.Equal(int32(i), pr.PieceInfo.PieceNum)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"go.opentelemetry.io/otel/semconv"
 	"go.opentelemetry.io/otel/trace"
 
+	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
@@ -56,6 +57,14 @@ func (ptm *peerTaskManager) tryReuseFilePeerTask(ctx context.Context,
 	defer span.End()
 
 	log.Infof("reuse from peer task: %s, size: %d", reuse.PeerID, reuse.ContentLength)
+	// the reused task may be downloaded without signature, verify it before linking to output,
+	// when it is not verified, download it again and the new peer task reports the verification error
+	if err := ptm.verifySignature(ctx, reuse.PeerTaskMetadata, &request.PeerTaskRequest, false); err != nil {
+		log.Warnf("reuse peer task is not verified: %s", err)
+		span.RecordError(err)
+		return nil, false
+	}
+	ptm.pinReusedTask(taskID, request.Pin, request.Priority)
 	span.AddEvent("reuse peer task", trace.WithAttributes(config.AttributePeerID.String(reuse.PeerID)))
 
 	start := time.Now()
@@ -70,6 +79,9 @@ func (ptm *peerTaskManager) tryReuseFilePeerTask(ctx context.Context,
 			MetadataOnly: false,
 			StoreOnly:    true,
 			TotalPieces:  reuse.TotalPieces,
+			Outputs:      request.Outputs,
+			// the attributes are stored in metadata, reused task applies them too
+			DisableFileAttributes: request.DisableFileAttributes,
 		})
 	if err != nil {
 		log.Errorf("store error when reuse peer task: %s", err)
@@ -92,6 +104,7 @@ func (ptm *peerTaskManager) tryReuseFilePeerTask(ctx context.Context,
 		CompletedLength: reuse.ContentLength,
 		PeerTaskDone:    true,
 		DoneCallback:    func() {},
+		Reuse:           true,
 	}
 
 	// make a new buffered channel, because we did not need to call newFilePeerTask
@@ -123,6 +136,12 @@ func (ptm *peerTaskManager) tryReuseStreamPeerTask(ctx context.Context,
 	span.SetAttributes(semconv.HTTPURLKey.String(request.Url))
 	defer span.End()
 
+	if err := ptm.verifySignature(ctx, reuse.PeerTaskMetadata, request, false); err != nil {
+		log.Warnf("reuse peer task is not verified: %s", err)
+		span.RecordError(err)
+		return nil, nil, false
+	}
+
 	rc, err := ptm.storageManager.ReadAllPieces(ctx, &reuse.PeerTaskMetadata)
 	if err != nil {
 		log.Errorf("read all pieces error when reuse peer task: %s", err)
@@ -140,3 +159,45 @@ func (ptm *peerTaskManager) tryReuseStreamPeerTask(ctx context.Context,
 	span.SetAttributes(config.AttributePeerTaskSuccess.Bool(true))
 	return rc, attr, true
 }
+
+func (ptm *peerTaskManager) FindCompletedTask(taskID string) *storage.ReusePeerTask {
+	if !ptm.enableMultiplex {
+		return nil
+	}
+	return ptm.storageManager.FindCompletedTask(taskID)
+}
+
+func (ptm *peerTaskManager) ReadCompletedTaskRange(ctx context.Context,
+	meta storage.PeerTaskMetadata, rg clientutil.Range) (io.ReadCloser, error) {
+	r, c, err := ptm.storageManager.ReadPiece(ctx, &storage.ReadPieceRequest{
+		PeerTaskMetadata: meta,
+		PieceMetadata: storage.PieceMetadata{
+			Num:   -1,
+			Range: rg,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, c}, nil
+}
+
+// pinReusedTask applies the pin and priority of the download to the reused task,
+// the task is never unpinned by downloads
+func (ptm *peerTaskManager) pinReusedTask(taskID string, pinned bool, priority int32) {
+	if !pinned && priority == 0 {
+		return
+	}
+	err := ptm.storageManager.PinTask(storage.PinTaskRequest{
+		TaskID:   taskID,
+		Pinned:   pinned,
+		Priority: priority,
+		Merge:    true,
+	})
+	if err != nil {
+		logger.Warnf("pin reused task %s error: %s", taskID, err)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+	"io"
+
+	"d7y.io/dragonfly/v2/client/daemon/signature"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// verifySignature verifies the content of the peer task in local storage with the signature in url meta,
+// the unverified content is unregistered, so it is never linked to output or shared with other peers.
+// When it is verified, the task store is marked verified and starts to serve other peers
+func (ptm *peerTaskManager) verifySignature(ctx context.Context, meta storage.PeerTaskMetadata, request *scheduler.PeerTaskRequest, unregister bool) error {
+	if request.UrlMeta.GetSignature() == nil {
+		return nil
+	}
+	rc, err := ptm.storageManager.ReadAllPieces(ctx, &meta)
+	if err != nil {
+		return err
+	}
+	err = ptm.verifyContentSignature(ctx, request, rc)
+	rc.Close()
+	if err == nil {
+		return ptm.storageManager.MarkVerified(&meta)
+	}
+	if unregister {
+		if e := ptm.storageManager.UnregisterTask(ctx, storage.CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		}); e != nil {
+			logger.With("peer", meta.PeerID, "task", meta.TaskID).Errorf("unregister untrusted task error: %s", e)
+		}
+	}
+	return err
+}
+
+// verifyContentSignature verifies the content with the signature in url meta
+func (ptm *peerTaskManager) verifyContentSignature(ctx context.Context, request *scheduler.PeerTaskRequest, content io.Reader) error {
+	urlMeta := request.UrlMeta
+	sig := urlMeta.GetSignature()
+	if sig == nil {
+		return nil
+	}
+	if ptm.signatureManager == nil {
+		return dferrors.Newf(base.Code_ClientSignatureVerifyFail, "%s: signature verification is not enabled", signature.ErrUntrusted)
+	}
+	if err := ptm.signatureManager.Verify(ctx, request.Url, sig, urlMeta.Header, content); err != nil {
+		return dferrors.Newf(base.Code_ClientSignatureVerifyFail, "verify %s signature %s error: %s", sig.Type, sig.Url, err)
+	}
+	return nil
+}
+
+// verifyTaskSignature verifies the signature of the peer task only once, both the stream writer
+// and the scheduler reporting wait for it
+func (pt *peerTask) verifyTaskSignature(task Task) error {
+	pt.verifyOnce.Do(func() {
+		pt.verifyErr = pt.callback.VerifySignature(task)
+	})
+	return pt.verifyErr
+}
+
+// sendPieceResult sends the piece result to scheduler, the successful piece results of the task with signature
+// are held until the content is verified, so other peers are never scheduled to the unverified content
+func (pt *peerTask) sendPieceResult(pr *scheduler.PieceResult) error {
+	if pr.Success && pt.request.UrlMeta.GetSignature() != nil {
+		pt.heldPieceResultsLock.Lock()
+		pt.heldPieceResults = append(pt.heldPieceResults, pr)
+		pt.heldPieceResultsLock.Unlock()
+		return nil
+	}
+	return pt.peerPacketStream.Send(pr)
+}
+
+// sendHeldPieceResults sends the successful piece results held by sendPieceResult after the content is verified
+func (pt *peerTask) sendHeldPieceResults() {
+	pt.heldPieceResultsLock.Lock()
+	results := pt.heldPieceResults
+	pt.heldPieceResults = nil
+	pt.heldPieceResultsLock.Unlock()
+	for _, pr := range results {
+		if err := pt.peerPacketStream.Send(pr); err != nil {
+			pt.Errorf("send held piece %d result error: %s", pr.PieceInfo.PieceNum, err)
+			return
+		}
+	}
+	if len(results) > 0 {
+		pt.Infof("verified, sent %d held piece results", len(results))
+	}
+}
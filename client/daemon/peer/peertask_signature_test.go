@@ -0,0 +1,200 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/signature"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/client/daemon/test"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+// recordSignatureManager records the successful piece results sent to scheduler before verifying
+type recordSignatureManager struct {
+	err                 error
+	recorder            *pieceResultRecorder
+	contentURL          string
+	successBeforeVerify int
+}
+
+func (m *recordSignatureManager) Verify(ctx context.Context, contentURL string, sig *base.Signature, header map[string]string, content io.Reader) error {
+	m.contentURL = contentURL
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return err
+	}
+	for _, pr := range m.recorder.Results() {
+		if pr.Success {
+			m.successBeforeVerify++
+		}
+	}
+	return m.err
+}
+
+func TestPeerTaskManager_SignedFilePeerTask(t *testing.T) {
+	testBytes, err := os.ReadFile(test.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		pieceSize  = 1024
+		pieceCount = int(math.Ceil(float64(len(testBytes)) / float64(pieceSize)))
+		url        = "http://localhost/test/signed"
+		urlMeta    = &base.UrlMeta{
+			Tag: "d7y-test",
+			Signature: &base.Signature{
+				Type: config.SignatureTypeMinisign,
+				Url:  "http://localhost/test/signed.minisig",
+			},
+		}
+		taskID = idgen.TaskID(url, urlMeta)
+		peerID = "peer-signed"
+	)
+
+	tests := []struct {
+		name      string
+		verifyErr error
+	}{
+		{
+			name: "verified",
+		},
+		{
+			name:      "untrusted",
+			verifyErr: signature.ErrUntrusted,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			output := "../test/testdata/test.signed.output"
+			defer os.Remove(output)
+
+			recorder := &pieceResultRecorder{}
+			schedulerClient, storageManager := setupPeerTaskManagerComponents(
+				ctrl,
+				componentsOption{
+					taskID:             taskID,
+					contentLength:      int64(len(testBytes)),
+					pieceSize:          uint32(pieceSize),
+					pieceParallelCount: 4,
+					pieceResults:       recorder,
+				})
+			defer storageManager.CleanUp()
+
+			downloader := NewMockPieceDownloader(ctrl)
+			downloader.EXPECT().DownloadPiece(gomock.Any(), gomock.Any()).Times(pieceCount).DoAndReturn(
+				func(ctx context.Context, task *DownloadPieceRequest) (io.Reader, io.Closer, error) {
+					rc := io.NopCloser(
+						bytes.NewBuffer(
+							testBytes[task.piece.RangeStart : task.piece.RangeStart+uint64(task.piece.RangeSize)],
+						))
+					return rc, rc, nil
+				})
+
+			signatureManager := &recordSignatureManager{err: tc.verifyErr, recorder: recorder}
+			ptm := &peerTaskManager{
+				host: &scheduler.PeerHost{
+					Ip: "127.0.0.1",
+				},
+				pieceManager: &pieceManager{
+					storageManager:  storageManager,
+					pieceDownloader: downloader,
+				},
+				storageManager:   storageManager,
+				schedulerClient:  schedulerClient,
+				signatureManager: signatureManager,
+				schedulerOption: config.SchedulerOption{
+					ScheduleTimeout: clientutil.Duration{Duration: 10 * time.Minute},
+				},
+			}
+			progress, _, err := ptm.StartFilePeerTask(context.Background(), &FilePeerTaskRequest{
+				PeerTaskRequest: scheduler.PeerTaskRequest{
+					Url:      url,
+					UrlMeta:  urlMeta,
+					PeerId:   peerID,
+					PeerHost: &scheduler.PeerHost{},
+				},
+				Output: output,
+			})
+			assert.Nil(err, "start file peer task")
+
+			var p *FilePeerTaskProgress
+			for p = range progress {
+				if p.PeerTaskDone || !p.State.Success {
+					if p.DoneCallback != nil {
+						p.DoneCallback()
+					}
+					break
+				}
+			}
+			if !assert.NotNil(p) {
+				return
+			}
+
+			// the successful piece results are held until the content is verified
+			assert.Equal(url, signatureManager.contentURL)
+			assert.Equal(0, signatureManager.successBeforeVerify)
+			var success int
+			for _, pr := range recorder.Results() {
+				if pr.Success {
+					success++
+				}
+			}
+
+			meta := &storage.PeerTaskMetadata{PeerID: peerID, TaskID: taskID}
+			if tc.verifyErr != nil {
+				assert.False(p.State.Success)
+				assert.Equal(base.Code_ClientSignatureVerifyFail, p.State.Code)
+				assert.Equal(0, success, "unverified pieces should never be reported")
+				_, err = storageManager.IsVerified(meta)
+				assert.ErrorIs(err, storage.ErrTaskNotFound, "untrusted task should be unregistered")
+				return
+			}
+
+			assert.True(p.State.Success)
+			assert.Equal(pieceCount, success)
+			verified, err := storageManager.IsVerified(meta)
+			assert.Nil(err)
+			assert.True(verified, "task should be shared after verified")
+			_, err = storageManager.GetPieces(context.Background(), &base.PieceTaskRequest{
+				TaskId: taskID,
+				DstPid: peerID,
+				Limit:  1,
+			})
+			assert.Nil(err)
+		})
+	}
+}
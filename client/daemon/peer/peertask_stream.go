@@ -46,17 +46,32 @@ type StreamPeerTask interface {
 	Start(ctx context.Context) (rc io.ReadCloser, attribute map[string]string, err error)
 }
 
+// StreamPeerTaskRequest is the request of a peer task with stream io
+type StreamPeerTaskRequest struct {
+	scheduler.PeerTaskRequest
+	// Limit overrides the per peer rate limit when it is positive
+	Limit float64
+	// DisableBackSource fails the task instead of downloading from source
+	DisableBackSource bool
+}
+
 type streamPeerTask struct {
 	peerTask
-	streamDone     chan struct{}
-	successPieceCh chan int32
+	// disableBackSource indicates not back source when failed
+	disableBackSource bool
+	streamDone        chan struct{}
+	successPieceCh    chan int32
+	// resumedPieces are the pieces downloaded before the peer task is resumed
+	resumedPieces []int32
 }
 
 var _ StreamPeerTask = (*streamPeerTask)(nil)
 
 func newStreamPeerTask(ctx context.Context,
 	ptm *peerTaskManager,
-	request *scheduler.PeerTaskRequest) (context.Context, *streamPeerTask, *TinyData, error) {
+	req *StreamPeerTaskRequest,
+	perPeerRateLimit rate.Limit) (context.Context, *streamPeerTask, *TinyData, error) {
+	request := &req.PeerTaskRequest
 	metrics.PeerTaskCount.Add(1)
 	ctx, span := tracer.Start(ctx, config.SpanStreamPeerTask, trace.WithSpanKind(trace.SpanKindClient))
 	span.SetAttributes(config.AttributePeerHost.String(ptm.host.Uuid))
@@ -142,12 +157,13 @@ func newStreamPeerTask(ctx context.Context,
 		return ctx, nil, nil, err
 	}
 	var limiter *rate.Limiter
-	if ptm.perPeerRateLimit > 0 {
-		limiter = rate.NewLimiter(ptm.perPeerRateLimit, int(ptm.perPeerRateLimit))
+	if perPeerRateLimit > 0 {
+		limiter = rate.NewLimiter(perPeerRateLimit, int(perPeerRateLimit))
 	}
 	pt := &streamPeerTask{
-		successPieceCh: make(chan int32),
-		streamDone:     make(chan struct{}),
+		disableBackSource: req.DisableBackSource,
+		successPieceCh:    make(chan int32),
+		streamDone:        make(chan struct{}),
 		peerTask: peerTask{
 			ctx:                 ctx,
 			host:                ptm.host,
@@ -219,7 +235,7 @@ func (s *streamPeerTask) ReportPieceResult(result *pieceTaskResult) error {
 	s.lock.Unlock()
 
 	result.pieceResult.FinishedCount = s.readyPieces.Settled()
-	_ = s.peerPacketStream.Send(result.pieceResult)
+	_ = s.sendPieceResult(result.pieceResult)
 	s.successPieceCh <- result.piece.PieceNum
 	s.Debugf("success piece %d sent", result.piece.PieceNum)
 	select {
@@ -243,6 +259,9 @@ func (s *streamPeerTask) Start(ctx context.Context) (io.ReadCloser, map[string]s
 	} else {
 		s.pullPieces(s.cleanUnfinished)
 	}
+	if len(s.resumedPieces) > 0 {
+		go s.sendResumedPieces()
+	}
 
 	// wait first piece to get content length and attribute (eg, response header for http/https)
 	var firstPiece int32
@@ -295,7 +314,25 @@ func (s *streamPeerTask) Start(ctx context.Context) (io.ReadCloser, map[string]s
 	return readCloser, attr, nil
 }
 
+// sendResumedPieces notifies the resumed pieces just like they are downloaded
+func (s *streamPeerTask) sendResumedPieces() {
+	for _, num := range s.resumedPieces {
+		select {
+		case s.successPieceCh <- num:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
 func (s *streamPeerTask) finish() error {
+	// the content with signature is verified before it is reported to scheduler
+	if err := s.verifyTaskSignature(s); err != nil {
+		s.failedCode = base.Code_ClientSignatureVerifyFail
+		s.failedReason = err.Error()
+		s.cleanUnfinished()
+		return err
+	}
 	// send last progress
 	s.once.Do(func() {
 		s.success = true
@@ -305,6 +342,7 @@ func (s *streamPeerTask) finish() error {
 		}
 		// let stream return immediately
 		close(s.streamDone)
+		s.sendHeldPieceResults()
 		// send EOF piece result to scheduler
 		_ = s.peerPacketStream.Send(
 			scheduler.NewEndPieceResult(s.taskID, s.peerID, s.readyPieces.Settled()))
@@ -376,6 +414,12 @@ func (s *streamPeerTask) writeOnePiece(w io.Writer, pieceNum int32) (int64, erro
 func (s *streamPeerTask) backSource() {
 	backSourceCtx, backSourceSpan := tracer.Start(s.ctx, config.SpanBackSource)
 	defer backSourceSpan.End()
+	if s.disableBackSource {
+		s.Errorf(reasonBackSourceDisabled)
+		s.failedReason = reasonBackSourceDisabled
+		s.cleanUnfinished()
+		return
+	}
 	s.contentLength.Store(-1)
 	_ = s.callback.Init(s)
 	err := s.pieceManager.DownloadSource(backSourceCtx, s, s.request)
@@ -457,6 +501,12 @@ func (s *streamPeerTask) writeToPipe(firstPiece int32, pw *io.PipeWriter) {
 						_ = pw.CloseWithError(err)
 						return
 					}
+					if err = s.verifyTaskSignature(s); err != nil {
+						s.span.RecordError(err)
+						s.Errorf("verify signature error: %s", err)
+						_ = pw.CloseWithError(err)
+						return
+					}
 					s.Debugf("all %d pieces wrote to pipe", desired)
 					pw.Close()
 					return
@@ -247,21 +247,23 @@ func TestStreamPeerTask_BackSource_Partial_WithContentLength(t *testing.T) {
 			ScheduleTimeout: clientutil.Duration{Duration: 10 * time.Minute},
 		},
 	}
-	req := &scheduler.PeerTaskRequest{
-		Url: url,
-		UrlMeta: &base.UrlMeta{
-			Tag: "d7y-test",
+	req := &StreamPeerTaskRequest{
+		PeerTaskRequest: scheduler.PeerTaskRequest{
+			Url: url,
+			UrlMeta: &base.UrlMeta{
+				Tag: "d7y-test",
+			},
+			PeerId:   peerID,
+			PeerHost: &scheduler.PeerHost{},
 		},
-		PeerId:   peerID,
-		PeerHost: &scheduler.PeerHost{},
 	}
 	ctx := context.Background()
-	_, pt, _, err := newStreamPeerTask(ctx, ptm, req)
+	_, pt, _, err := newStreamPeerTask(ctx, ptm, req, ptm.perPeerRateLimit)
 	assert.Nil(err, "new stream peer task")
 	pt.SetCallback(&streamPeerTaskCallback{
 		ptm:   ptm,
 		pt:    pt,
-		req:   req,
+		req:   &req.PeerTaskRequest,
 		start: time.Now(),
 	})
 
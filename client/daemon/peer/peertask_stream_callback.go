@@ -49,6 +49,8 @@ func (p *streamPeerTaskCallback) Init(pt Task) error {
 				PeerID: pt.GetPeerID(),
 				TaskID: pt.GetTaskID(),
 			},
+			URL:           p.req.Url,
+			URLMeta:       p.req.UrlMeta,
 			ContentLength: pt.GetContentLength(),
 			TotalPieces:   pt.GetTotalPieces(),
 			PieceMd5Sign:  pt.GetPieceMd5Sign(),
@@ -67,9 +69,10 @@ func (p *streamPeerTaskCallback) Update(pt Task) error {
 				PeerID: pt.GetPeerID(),
 				TaskID: pt.GetTaskID(),
 			},
-			ContentLength: pt.GetContentLength(),
-			TotalPieces:   pt.GetTotalPieces(),
-			PieceMd5Sign:  pt.GetPieceMd5Sign(),
+			ContentLength:  pt.GetContentLength(),
+			TotalPieces:    pt.GetTotalPieces(),
+			PieceMd5Sign:   pt.GetPieceMd5Sign(),
+			FileAttributes: pt.GetFileAttributes(),
 		})
 	if err != nil {
 		pt.Log().Errorf("update task to storage manager failed: %s", err)
@@ -166,3 +169,17 @@ func (p *streamPeerTaskCallback) ValidateDigest(pt Task) error {
 	}
 	return err
 }
+
+func (p *streamPeerTaskCallback) VerifySignature(pt Task) error {
+	err := p.ptm.verifySignature(p.pt.ctx,
+		storage.PeerTaskMetadata{
+			PeerID: pt.GetPeerID(),
+			TaskID: pt.GetTaskID(),
+		}, p.req, true)
+	if err != nil {
+		pt.Log().Errorf("%s", err)
+	} else if p.req.UrlMeta.GetSignature() != nil {
+		pt.Log().Debugf("verified signature")
+	}
+	return err
+}
@@ -22,8 +22,11 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
@@ -41,6 +44,8 @@ type PieceManager interface {
 	DownloadSource(ctx context.Context, pt Task, request *scheduler.PeerTaskRequest) error
 	DownloadPiece(ctx context.Context, peerTask Task, request *DownloadPieceRequest) bool
 	ReadPiece(ctx context.Context, req *storage.ReadPieceRequest) (io.Reader, io.Closer, error)
+	// ImportFile cuts the local file into pieces and writes them into the registered task storage
+	ImportFile(ctx context.Context, meta storage.PeerTaskMetadata, path string, digest string) error
 }
 
 type pieceManager struct {
@@ -394,7 +399,7 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 	pieceSize := pm.computePieceSize(contentLength)
 	// handle resource which content length is unknown
 	if contentLength < 0 {
-		return pm.downloadUnknownLengthSource(ctx, pt, pieceSize, reader)
+		return pm.downloadUnknownLengthSource(ctx, pt, pieceSize, reader, response.Attributes)
 	}
 
 	maxPieceNum := int32(math.Ceil(float64(contentLength) / float64(pieceSize)))
@@ -421,6 +426,7 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 					ContentLength:  contentLength,
 					TotalPieces:    maxPieceNum,
 					GenPieceDigest: true,
+					FileAttributes: response.Attributes,
 				})
 			if err != nil {
 				log.Errorf("update task failed %s", err)
@@ -445,7 +451,8 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, request *sc
 	return nil
 }
 
-func (pm *pieceManager) downloadUnknownLengthSource(ctx context.Context, pt Task, pieceSize uint32, reader io.Reader) error {
+func (pm *pieceManager) downloadUnknownLengthSource(ctx context.Context, pt Task, pieceSize uint32, reader io.Reader,
+	attr *source.FileAttributes) error {
 	var contentLength int64 = -1
 	log := pt.Log()
 	for pieceNum := int32(0); ; pieceNum++ {
@@ -469,6 +476,7 @@ func (pm *pieceManager) downloadUnknownLengthSource(ctx context.Context, pt Task
 						ContentLength:  contentLength,
 						GenPieceDigest: true,
 						TotalPieces:    pt.GetTotalPieces(),
+						FileAttributes: attr,
 					})
 				if er != nil {
 					log.Errorf("update task failed %s", er)
@@ -492,3 +500,91 @@ func (pm *pieceManager) downloadUnknownLengthSource(ctx context.Context, pt Task
 	log.Infof("download from source ok")
 	return nil
 }
+
+func (pm *pieceManager) ImportFile(ctx context.Context, meta storage.PeerTaskMetadata, path string, digest string) error {
+	log := logger.With("peer", meta.PeerID, "task", meta.TaskID, "component", "pieceManager")
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !stat.Mode().IsRegular() {
+		return errors.Errorf("%s is not a regular file", path)
+	}
+	contentLength := stat.Size()
+	// empty file has no piece, it can not be scheduled to other peers
+	if contentLength == 0 {
+		return errors.Errorf("%s is empty", path)
+	}
+
+	var reader io.Reader = file
+	// calc total md5
+	if pm.calculateDigest && digest != "" {
+		reader = digestutils.NewDigestReader(log, reader, digest)
+	}
+
+	pieceSize := pm.computePieceSize(contentLength)
+	maxPieceNum := int32(math.Ceil(float64(contentLength) / float64(pieceSize)))
+	for pieceNum := int32(0); pieceNum < maxPieceNum; pieceNum++ {
+		size := pieceSize
+		offset := uint64(pieceNum) * uint64(pieceSize)
+		// calculate piece size for last piece
+		if int64(offset)+int64(size) > contentLength {
+			size = uint32(contentLength - int64(offset))
+		}
+
+		log.Debugf("import piece %d", pieceNum)
+		pieceReader := io.LimitReader(reader, int64(size))
+		if pm.calculateDigest {
+			pieceReader = digestutils.NewDigestReader(log, pieceReader)
+		}
+		n, err := pm.storageManager.WritePiece(ctx,
+			&storage.WritePieceRequest{
+				PeerTaskMetadata: meta,
+				PieceMetadata: storage.PieceMetadata{
+					Num: pieceNum,
+					// storage manager will get digest from DigestReader, keep empty here is ok
+					Md5:    "",
+					Offset: offset,
+					Range: clientutil.Range{
+						Start:  int64(offset),
+						Length: int64(size),
+					},
+				},
+				Reader: pieceReader,
+			})
+		if err != nil {
+			log.Errorf("import piece %d error: %s", pieceNum, err)
+			return err
+		}
+		if n != int64(size) {
+			log.Errorf("import piece %d size not match, desired: %d, actual: %d", pieceNum, size, n)
+			return storage.ErrShortRead
+		}
+	}
+
+	// drain the reader, digest reader validates the digest when reaching EOF
+	if _, err = io.Copy(io.Discard, reader); err != nil {
+		log.Errorf("validate digest error: %s", err)
+		return err
+	}
+
+	err = pm.storageManager.UpdateTask(ctx,
+		&storage.UpdateTaskRequest{
+			PeerTaskMetadata: meta,
+			ContentLength:    contentLength,
+			TotalPieces:      maxPieceNum,
+			GenPieceDigest:   true,
+		})
+	if err != nil {
+		log.Errorf("update task error: %s", err)
+		return err
+	}
+	log.Infof("import file %s ok, content length: %d, pieces: %d", path, contentLength, maxPieceNum)
+	return nil
+}
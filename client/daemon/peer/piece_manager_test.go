@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -223,3 +224,109 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 		})
 	}
 }
+
+func TestPieceManager_ImportFile(t *testing.T) {
+	assert := testifyassert.New(t)
+	testBytes, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	hash := md5.New()
+	hash.Write(testBytes)
+	digest := hex.EncodeToString(hash.Sum(nil)[:16])
+
+	testCases := []struct {
+		name      string
+		pieceSize uint32
+		digest    string
+		expectErr bool
+	}{
+		{
+			name:      "multiple pieces",
+			pieceSize: 1024,
+		},
+		{
+			name:      "multiple pieces, check digest",
+			pieceSize: 1024,
+			digest:    digest,
+		},
+		{
+			name:      "one piece",
+			pieceSize: uint32(len(testBytes)) + 1,
+		},
+		{
+			name:      "digest not match",
+			pieceSize: 1024,
+			digest:    "00000000000000000000000000000000",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				meta = storage.PeerTaskMetadata{
+					PeerID: "peer0",
+					TaskID: "task0",
+				}
+				output = path.Join(t.TempDir(), "output")
+			)
+			storageManager, _ := storage.NewStorageManager(
+				config.SimpleLocalTaskStoreStrategy,
+				&config.StorageOption{
+					DataPath: t.TempDir(),
+					TaskExpireTime: clientutil.Duration{
+						Duration: -1 * time.Second,
+					},
+				}, func(request storage.CommonTaskRequest) {})
+			defer storageManager.CleanUp()
+
+			err := storageManager.RegisterTask(context.Background(),
+				storage.RegisterTaskRequest{
+					CommonTaskRequest: storage.CommonTaskRequest{
+						PeerID: meta.PeerID,
+						TaskID: meta.TaskID,
+					},
+					ContentLength: -1,
+					TotalPieces:   -1,
+				})
+			assert.Nil(err)
+
+			pm, err := NewPieceManager(storageManager, 30*time.Second)
+			assert.Nil(err)
+			pm.(*pieceManager).computePieceSize = func(length int64) uint32 {
+				return tc.pieceSize
+			}
+
+			err = pm.ImportFile(context.Background(), meta, test.File, tc.digest)
+			if tc.expectErr {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+
+			packet, err := storageManager.GetPieces(context.Background(), &base.PieceTaskRequest{
+				TaskId: meta.TaskID,
+				DstPid: meta.PeerID,
+				Limit:  1024,
+			})
+			assert.Nil(err)
+			assert.Equal(int64(len(testBytes)), packet.ContentLength)
+			assert.Equal(int32((len(testBytes)+int(tc.pieceSize)-1)/int(tc.pieceSize)), packet.TotalPiece)
+			assert.Equal(int(packet.TotalPiece), len(packet.PieceInfos))
+			assert.NotEmpty(packet.PieceMd5Sign)
+
+			err = storageManager.Store(context.Background(),
+				&storage.StoreRequest{
+					CommonTaskRequest: storage.CommonTaskRequest{
+						PeerID:      meta.PeerID,
+						TaskID:      meta.TaskID,
+						Destination: output,
+					},
+				})
+			assert.Nil(err)
+
+			outputBytes, err := os.ReadFile(output)
+			assert.Nil(err, "load output file")
+			assert.Equal(testBytes, outputBytes, "output and desired output must match")
+		})
+	}
+}
@@ -0,0 +1,442 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+const (
+	defaultPrefetchConcurrency = 4
+
+	// prefetchBlobTimeout is the timeout of prefetching one blob, the hung upstream does not block the pull forever
+	prefetchBlobTimeout = 30 * time.Minute
+
+	// maxFinishedImagePulls is the max count of finished image pulls kept for the progress api
+	maxFinishedImagePulls = 100
+
+	imagePullRunning   = "running"
+	imagePullSucceeded = "succeeded"
+	imagePullFailed    = "failed"
+)
+
+// imageManifest is the part of docker image manifest v2 schema 2 and OCI image manifest used to prefetch,
+// image index and manifest list have no layers, the client requests the manifest of its platform later
+type imageManifest struct {
+	Config *descriptor  `json:"config"`
+	Layers []descriptor `json:"layers"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// imagePuller prefetches the layers of the image manifests pulled through registry mirrors,
+// and records the progress of every image pull
+type imagePuller struct {
+	// ctx is canceled when the proxy is stopped, the running prefetches are canceled with it
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	pulls map[string]*imagePull
+	// blobs are the blobs being downloaded by prefetch or client. The peer task manager only reuses the
+	// completed tasks, so the prefetch of the same blob waits for the running one, then it is served from
+	// local storage instead of downloading again. The client never waits for prefetch to stream the blob,
+	// it preempts the running prefetch of the blob instead
+	blobs map[string]*blobDownload
+}
+
+// blobDownload is a running download of a blob
+type blobDownload struct {
+	// done is closed when the download is done
+	done chan struct{}
+	// cancel cancels the download of prefetch when it is preempted by client, it is nil for client
+	cancel context.CancelFunc
+	// preempted is protected by imagePuller.mu
+	preempted bool
+}
+
+// imagePull is the progress of prefetching the layers of one image manifest
+type imagePull struct {
+	image  string
+	digest string
+	layers int
+	total  int64
+	start  time.Time
+
+	completedLayers int32
+	failedLayers    int32
+	completedBytes  int64
+
+	// state and end are protected by imagePuller.mu
+	state string
+	end   time.Time
+}
+
+// imagePullStatus is the progress of an image pull in the response of /images
+type imagePullStatus struct {
+	Image           string     `json:"image"`
+	Digest          string     `json:"digest"`
+	State           string     `json:"state"`
+	Layers          int        `json:"layers"`
+	CompletedLayers int32      `json:"completedLayers"`
+	FailedLayers    int32      `json:"failedLayers"`
+	TotalBytes      int64      `json:"totalBytes"`
+	CompletedBytes  int64      `json:"completedBytes"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+}
+
+func newImagePuller() *imagePuller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &imagePuller{ctx: ctx, cancel: cancel, pulls: map[string]*imagePull{}, blobs: map[string]*blobDownload{}}
+}
+
+// stop cancels the running prefetches
+func (p *imagePuller) stop() {
+	p.cancel()
+}
+
+// RoundTripper returns a http.RoundTripper which sends the requests through rt, the layers of the image
+// manifests responded by rt are prefetched through rt in background with at most concurrency layers at a time
+func (p *imagePuller) RoundTripper(rt http.RoundTripper, concurrency int) http.RoundTripper {
+	if concurrency <= 0 {
+		concurrency = defaultPrefetchConcurrency
+	}
+	return &prefetchTransport{imagePuller: p, next: rt, concurrency: int64(concurrency)}
+}
+
+type prefetchTransport struct {
+	*imagePuller
+	next        http.RoundTripper
+	concurrency int64
+}
+
+func (t *prefetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	match := ociPathReg.FindStringSubmatch(req.URL.Path)
+	if match != nil && match[2] == "blobs" && req.Method == http.MethodGet {
+		return t.roundTripBlob(req, match[3])
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || req.Method != http.MethodGet {
+		return resp, err
+	}
+	if match == nil || match[2] != "manifests" {
+		return resp, nil
+	}
+	repo, ref := match[1], match[3]
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxManifestSize {
+		resp.Body = &readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var manifest imageManifest
+	if err := json.Unmarshal(body, &manifest); err != nil || len(manifest.Layers) == 0 {
+		return resp, nil
+	}
+	digest := resp.Header.Get(headerDockerContentDigest)
+	if !isDigest(digest) {
+		digest = "sha256:" + sha256Hex(body)
+	}
+	image := req.URL.Host + "/" + repo
+	if isDigest(ref) {
+		image += "@" + ref
+	} else {
+		image += ":" + ref
+	}
+	t.prefetch(req, image, digest, &manifest)
+	return resp, nil
+}
+
+// prefetch downloads the config and layers of manifest in background, it is skipped when the same image
+// manifest is being prefetched
+func (t *prefetchTransport) prefetch(req *http.Request, image, digest string, manifest *imageManifest) {
+	blobs := manifest.Layers
+	if manifest.Config != nil && manifest.Config.Digest != "" {
+		blobs = append([]descriptor{*manifest.Config}, blobs...)
+	}
+	pull := &imagePull{image: image, digest: digest, layers: len(blobs), start: time.Now(), state: imagePullRunning}
+	for _, b := range blobs {
+		pull.total += b.Size
+	}
+
+	key := req.URL.Host + "/" + digest
+	t.mu.Lock()
+	if running, ok := t.pulls[key]; ok && running.state == imagePullRunning {
+		t.mu.Unlock()
+		logger.Debugf("image %s is being prefetched", image)
+		return
+	}
+	t.pulls[key] = pull
+	t.mu.Unlock()
+	metrics.ProxyImagePrefetchCount.Add(1)
+	logger.Infof("start to prefetch %d blobs of image %s, digest: %s", len(blobs), image, digest)
+
+	// the headers of client like authorization and dragonfly headers are used to download the blobs
+	header := req.Header.Clone()
+	for _, k := range []string{headers.Accept, headers.Range, headers.IfRange, headers.IfNoneMatch, headers.IfModifiedSince} {
+		header.Del(k)
+	}
+	base := *req.URL
+	base.RawPath = ""
+	repo := ociPathReg.FindStringSubmatch(req.URL.Path)[1]
+
+	go func() {
+		// the prefetch outlives the manifest request of client, it lives until the proxy is stopped
+		ctx := t.ctx
+		sem := semaphore.NewWeighted(t.concurrency)
+		eg := errgroup.Group{}
+		for _, b := range blobs {
+			b := b
+			if err := sem.Acquire(ctx, 1); err != nil {
+				break
+			}
+			eg.Go(func() error {
+				defer sem.Release(1)
+				blobCtx, cancel := context.WithTimeout(ctx, prefetchBlobTimeout)
+				defer cancel()
+				u := base
+				u.Path = "/v2/" + repo + "/blobs/" + b.Digest
+				blobReq, _ := http.NewRequestWithContext(blobCtx, http.MethodGet, u.String(), nil)
+				blobReq.Header = header.Clone()
+				if err := t.download(blobReq, b.Digest, pull); err != nil {
+					atomic.AddInt32(&pull.failedLayers, 1)
+					logger.Warnf("prefetch blob %s of image %s error: %s", b.Digest, image, err)
+					return err
+				}
+				atomic.AddInt32(&pull.completedLayers, 1)
+				return nil
+			})
+		}
+		err := eg.Wait()
+		t.finish(pull, err)
+	}()
+}
+
+// roundTripBlob sends the blob request of client through next without waiting for prefetch, the running prefetch
+// of the same blob is preempted and downloads again after the client, the download of req is done when the
+// response body is closed
+func (t *prefetchTransport) roundTripBlob(req *http.Request, digest string) (*http.Response, error) {
+	key := req.URL.Host + "/" + digest
+	var d *blobDownload
+	t.mu.Lock()
+	running, ok := t.blobs[key]
+	if !ok || running.cancel != nil {
+		if ok {
+			logger.Debugf("blob %s is being prefetched, preempt it", key)
+			running.preempted = true
+			running.cancel()
+		}
+		d = &blobDownload{done: make(chan struct{})}
+		t.blobs[key] = d
+	}
+	t.mu.Unlock()
+
+	resp, err := t.next.RoundTrip(req)
+	if d == nil {
+		// the blob is being downloaded by another client
+		return resp, err
+	}
+	if err != nil {
+		t.release(key, d)
+		return nil, err
+	}
+	var once sync.Once
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() {
+		once.Do(func() { t.release(key, d) })
+	}}
+	return resp, nil
+}
+
+// release removes the download d of blob key and wakes up the prefetches waiting for it
+func (t *prefetchTransport) release(key string, d *blobDownload) {
+	t.mu.Lock()
+	if t.blobs[key] == d {
+		delete(t.blobs, key)
+	}
+	t.mu.Unlock()
+	close(d.done)
+}
+
+// releaseBody calls release when it is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// download reads the whole blob to make the daemon store it, the read bytes are added to the progress of pull.
+// It waits for the running download of the same blob, and downloads again when it is preempted by client
+func (t *prefetchTransport) download(req *http.Request, digest string, pull *imagePull) error {
+	key := req.URL.Host + "/" + digest
+	for {
+		ctx, cancel := context.WithCancel(req.Context())
+		d := &blobDownload{done: make(chan struct{}), cancel: cancel}
+		t.mu.Lock()
+		running, ok := t.blobs[key]
+		if !ok {
+			t.blobs[key] = d
+		}
+		t.mu.Unlock()
+		if ok {
+			cancel()
+			logger.Debugf("blob %s is being downloaded, wait for it", key)
+			select {
+			case <-running.done:
+				continue
+			case <-req.Context().Done():
+				return req.Context().Err()
+			}
+		}
+
+		n, err := t.downloadOnce(req.WithContext(ctx), pull)
+		cancel()
+		t.release(key, d)
+		t.mu.Lock()
+		preempted := d.preempted
+		t.mu.Unlock()
+		if err == nil || !preempted || req.Context().Err() != nil {
+			return err
+		}
+		// the blob is read again after the client, it is served from local storage then
+		atomic.AddInt64(&pull.completedBytes, -n)
+		logger.Debugf("prefetch of blob %s is preempted by client", key)
+	}
+}
+
+// downloadOnce sends req through next and reads the whole response body, it returns the read bytes
+func (t *prefetchTransport) downloadOnce(req *http.Request, pull *imagePull) (int64, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.Copy(&progressWriter{pull}, resp.Body)
+}
+
+type progressWriter struct {
+	pull *imagePull
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&w.pull.completedBytes, int64(len(p)))
+	metrics.ProxyImagePrefetchBytesCount.Add(float64(len(p)))
+	return len(p), nil
+}
+
+func (p *imagePuller) finish(pull *imagePull, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pull.end = time.Now()
+	pull.state = imagePullSucceeded
+	if err != nil {
+		pull.state = imagePullFailed
+		metrics.ProxyImagePrefetchFailedCount.Add(1)
+	}
+	logger.Infof("prefetch image %s %s %s, %d/%d blobs, %d bytes, cost %s", pull.image, pull.digest, pull.state,
+		atomic.LoadInt32(&pull.completedLayers), pull.layers, atomic.LoadInt64(&pull.completedBytes), pull.end.Sub(pull.start))
+
+	// remove the oldest finished pulls
+	var finished []string
+	for k, v := range p.pulls {
+		if v.state != imagePullRunning {
+			finished = append(finished, k)
+		}
+	}
+	if len(finished) <= maxFinishedImagePulls {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return p.pulls[finished[i]].end.Before(p.pulls[finished[j]].end)
+	})
+	for _, k := range finished[:len(finished)-maxFinishedImagePulls] {
+		delete(p.pulls, k)
+	}
+}
+
+// list returns the progress of image pulls, the latest started is the first
+func (p *imagePuller) list() []*imagePullStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]*imagePullStatus, 0, len(p.pulls))
+	for _, pull := range p.pulls {
+		status := &imagePullStatus{
+			Image:           pull.image,
+			Digest:          pull.digest,
+			State:           pull.state,
+			Layers:          pull.layers,
+			CompletedLayers: atomic.LoadInt32(&pull.completedLayers),
+			FailedLayers:    atomic.LoadInt32(&pull.failedLayers),
+			TotalBytes:      pull.total,
+			CompletedBytes:  atomic.LoadInt64(&pull.completedBytes),
+			StartedAt:       pull.start,
+		}
+		if !pull.end.IsZero() {
+			end := pull.end
+			status.FinishedAt = &end
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+	return result
+}
+
+// getImagePulls returns the progress of the images prefetched by registry mirrors.
+func (p *imagePuller) getImagePulls(w http.ResponseWriter, r *http.Request) {
+	logger.Debugf("access: %s", r.URL.String())
+	w.Header().Set(headers.ContentType, "application/json")
+	if err := json.NewEncoder(w).Encode(p.list()); err != nil {
+		logger.Errorf("failed to encode image pulls json: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImagePuller_Prefetch(t *testing.T) {
+	blobs := map[string]string{}
+	var layers []string
+	for _, content := range []string{"config", "layer-1", "layer-2", "layer-3"} {
+		digest := "sha256:" + sha256Hex([]byte(content))
+		blobs[digest] = content
+		layers = append(layers, fmt.Sprintf(`{"digest":"%s","size":%d}`, digest, len(content)))
+	}
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":%s,"layers":[%s]}`, layers[0], strings.Join(layers[1:], ","))
+	index := `{"schemaVersion":2,"manifests":[{"digest":"sha256:0000000000000000000000000000000000000000000000000000000000000000"}]}`
+
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := ociPathReg.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case match[2] == "manifests" && match[3] == "latest":
+			_, _ = w.Write([]byte(manifest))
+		case match[2] == "manifests":
+			_, _ = w.Write([]byte(index))
+		case blobs[match[3]] != "":
+			mu.Lock()
+			requested = append(requested, match[3])
+			mu.Unlock()
+			assert.Equal(t, "Bearer client", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(blobs[match[3]]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+
+	images := newImagePuller()
+	rt := images.RoundTripper(http.DefaultTransport, 2)
+
+	// the image index has no layers
+	code, body := mirrorGet(t, rt, registry.URL+"/v2/library/alpine/manifests/"+
+		"sha256:1111111111111111111111111111111111111111111111111111111111111111")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, index, body)
+	assert.Equal(t, 0, len(images.list()))
+
+	req, _ := http.NewRequest(http.MethodGet, registry.URL+"/v2/library/alpine/manifests/latest", nil)
+	req.Header.Set("Authorization", "Bearer client")
+	resp, err := rt.RoundTrip(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	var status *imagePullStatus
+	for i := 0; i < 100; i++ {
+		pulls := images.list()
+		if assert.Equal(t, 1, len(pulls)) && pulls[0].State != imagePullRunning {
+			status = pulls[0]
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !assert.NotNil(t, status) {
+		return
+	}
+	assert.Equal(t, imagePullSucceeded, status.State)
+	assert.Equal(t, strings.TrimPrefix(registry.URL, "http://")+"/library/alpine:latest", status.Image)
+	assert.Equal(t, "sha256:"+sha256Hex([]byte(manifest)), status.Digest)
+	assert.Equal(t, 4, status.Layers)
+	assert.Equal(t, int32(4), status.CompletedLayers)
+	assert.Equal(t, status.TotalBytes, status.CompletedBytes)
+	assert.NotNil(t, status.FinishedAt)
+	mu.Lock()
+	assert.Equal(t, 4, len(requested))
+	mu.Unlock()
+}
+
+func TestImagePuller_PrefetchDedupe(t *testing.T) {
+	blob := "layer"
+	digest := "sha256:" + sha256Hex([]byte(blob))
+	manifest := `{"schemaVersion":2,"annotations":{"tag":"%s"},"layers":[{"digest":"%s","size":%d}]}`
+
+	var (
+		mu                  sync.Mutex
+		running, maxRunning int
+		requests            int
+	)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := ociPathReg.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match[2] == "manifests" {
+			_, _ = w.Write([]byte(fmt.Sprintf(manifest, match[3], digest, len(blob))))
+			return
+		}
+		mu.Lock()
+		running++
+		requests++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(blob))
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}))
+	defer registry.Close()
+
+	// the images share the same layer, the prefetch of the second image waits for the first one
+	images := newImagePuller()
+	rt := images.RoundTripper(http.DefaultTransport, 1)
+	for _, tag := range []string{"latest", "3.15"} {
+		code, _ := mirrorGet(t, rt, registry.URL+"/v2/library/alpine/manifests/"+tag)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	waitImagePulls(images)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, maxRunning, "the same blob is never prefetched at the same time")
+	assert.Equal(t, 2, requests)
+	for _, pull := range images.list() {
+		assert.Equal(t, imagePullSucceeded, pull.State)
+	}
+}
+
+func TestImagePuller_PrefetchPreempted(t *testing.T) {
+	blob := "layer"
+	digest := "sha256:" + sha256Hex([]byte(blob))
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"layers":[{"digest":"%s","size":%d}]}`, digest, len(blob))
+
+	var (
+		mu       sync.Mutex
+		requests int
+		started  = make(chan struct{})
+	)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := ociPathReg.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match[2] == "manifests" {
+			_, _ = w.Write([]byte(manifest))
+			return
+		}
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+		if first {
+			// the first prefetch hangs until it is preempted
+			close(started)
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		_, _ = w.Write([]byte(blob))
+	}))
+	defer registry.Close()
+
+	images := newImagePuller()
+	rt := images.RoundTripper(http.DefaultTransport, 1)
+	code, _ := mirrorGet(t, rt, registry.URL+"/v2/library/alpine/manifests/latest")
+	assert.Equal(t, http.StatusOK, code)
+	<-started
+
+	// the client does not wait for the prefetch
+	start := time.Now()
+	code, body := mirrorGet(t, rt, registry.URL+"/v2/library/alpine/blobs/"+digest)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, blob, body)
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+
+	waitImagePulls(images)
+	pull := images.list()[0]
+	assert.Equal(t, imagePullSucceeded, pull.State)
+	assert.Equal(t, int32(1), pull.CompletedLayers)
+	assert.Equal(t, int64(len(blob)), pull.CompletedBytes)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, requests, "the prefetch downloads again after the client")
+}
+
+func TestImagePuller_Stop(t *testing.T) {
+	manifest := `{"schemaVersion":2,"layers":[{"digest":"sha256:0000000000000000000000000000000000000000000000000000000000000000","size":1}]}`
+	started := make(chan struct{})
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			_, _ = w.Write([]byte(manifest))
+			return
+		}
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer registry.Close()
+
+	images := newImagePuller()
+	rt := images.RoundTripper(http.DefaultTransport, 1)
+	code, _ := mirrorGet(t, rt, registry.URL+"/v2/library/alpine/manifests/latest")
+	assert.Equal(t, http.StatusOK, code)
+	<-started
+
+	images.stop()
+	waitImagePulls(images)
+	assert.Equal(t, imagePullFailed, images.list()[0].State)
+}
+
+// waitImagePulls waits at most 1 second for the running image pulls
+func waitImagePulls(images *imagePuller) {
+	for i := 0; i < 100; i++ {
+		running := false
+		for _, pull := range images.list() {
+			running = running || pull.State == imagePullRunning
+		}
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
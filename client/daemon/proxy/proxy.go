@@ -27,6 +27,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-http-utils/headers"
@@ -35,6 +36,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/semconv"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http2"
 	"golang.org/x/sync/semaphore"
 
 	"d7y.io/dragonfly/v2/client/config"
@@ -57,12 +59,24 @@ var (
 	portHTTPS = 443
 )
 
+// maxPooledTransports is the max count of upstream hosts whose transports are pooled
+const maxPooledTransports = 100
+
 // Proxy is a http proxy handler. It proxies requests with dragonfly
 // if any defined proxy rules is matched
 type Proxy struct {
 	// reverse proxy upstream url for the default registry
 	registry *config.RegistryMirror
 
+	// mirror is the mirror of the default registry, it is nil when the default registry has no upstream
+	mirror *registryMirror
+
+	// hostMirrors stores the mirrors keyed by registry host in map[string]*registryMirror, it is reloadable
+	hostMirrors atomic.Value
+
+	// images prefetches the layers of images pulled through registry mirrors and records the progress
+	images *imagePuller
+
 	// proxy rules
 	rules []*config.Proxy
 
@@ -100,6 +114,14 @@ type Proxy struct {
 
 	// dumpHTTPContent indicates to dump http request header and response header
 	dumpHTTPContent bool
+
+	// h2s serves HTTP/2 of the proxy listener and the hijacked TLS connections, it is nil when HTTP/2 is disabled
+	h2s *http2.Server
+
+	// transports pools the transports by upstream host, so the connections to upstream are reused across
+	// requests and hijacked connections
+	transports     *lru.Cache
+	transportsLock sync.Mutex
 }
 
 // Option is a functional option for configuring the proxy
@@ -133,6 +155,15 @@ func WithHTTPSHosts(hosts ...*config.HijackHost) Option {
 func WithRegistryMirror(r *config.RegistryMirror) Option {
 	return func(p *Proxy) *Proxy {
 		p.registry = r
+		p.mirror = newRegistryMirror(r)
+		return p
+	}
+}
+
+// WithRegistryMirrors sets the mirrors of registries chosen by host
+func WithRegistryMirrors(mirrors []*config.RegistryMirror) Option {
+	return func(p *Proxy) *Proxy {
+		p.setRegistryMirrors(mirrors)
 		return p
 	}
 }
@@ -148,17 +179,13 @@ func WithCert(cert *tls.Certificate) Option {
 // WithDirectHandler sets the handler for non-proxy requests
 func WithDirectHandler(h *http.ServeMux) Option {
 	return func(p *Proxy) *Proxy {
-		if p.registry == nil || p.registry.Remote == nil || p.registry.Remote.URL == nil {
-			logger.Warnf("registry mirror url is empty, registry mirror feature is disabled")
-			h.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, fmt.Sprintf("registry mirror feature is disabled"), http.StatusNotFound)
-			})
-			p.directHandler = h
-			return p
+		if p.mirror == nil && len(p.getRegistryMirrors()) == 0 {
+			logger.Warnf("registry mirror url is empty, registry mirror feature is disabled until registry mirrors are loaded")
 		}
 		// Make sure the root handler of the given server mux is the
 		// registry mirror reverse proxy
 		h.HandleFunc("/", p.mirrorRegistry)
+		h.HandleFunc("/images", p.images.getImagePulls)
 		p.directHandler = h
 		return p
 	}
@@ -213,6 +240,16 @@ func WithDumpHTTPContent(dump bool) Option {
 	}
 }
 
+// WithHTTP2 enables HTTP/2 of the proxy
+func WithHTTP2(opt *config.HTTP2Option) Option {
+	return func(p *Proxy) *Proxy {
+		if opt != nil && opt.Enable {
+			p.h2s = &http2.Server{MaxConcurrentStreams: opt.MaxConcurrentStreams}
+		}
+		return p
+	}
+}
+
 // NewProxy returns a new transparent proxy from the given options
 func NewProxy(options ...Option) (*Proxy, error) {
 	return NewProxyWithOptions(options...)
@@ -223,6 +260,14 @@ func NewProxyWithOptions(options ...Option) (*Proxy, error) {
 	proxy := &Proxy{
 		directHandler: http.NewServeMux(),
 		tracer:        otel.Tracer("dfget-daemon-proxy"),
+		images:        newImagePuller(),
+		transports:    lru.New(maxPooledTransports),
+	}
+	proxy.hostMirrors.Store(map[string]*registryMirror{})
+	proxy.transports.OnEvicted = func(_ lru.Key, value interface{}) {
+		if c, ok := value.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
 	}
 
 	for _, opt := range options {
@@ -237,6 +282,7 @@ func (proxy *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	metrics.ProxyRequestCount.WithLabelValues(r.Method).Add(1)
 	metrics.ProxyRequestRunningCount.WithLabelValues(r.Method).Add(1)
 	defer metrics.ProxyRequestRunningCount.WithLabelValues(r.Method).Sub(1)
+	defer trackStream(r.Proto)()
 
 	ctx, span := proxy.tracer.Start(r.Context(), config.SpanProxy)
 	span.SetAttributes(config.AttributePeerHost.String(proxy.peerHost.Uuid))
@@ -333,7 +379,7 @@ func parseBasicAuth(auth string) (username, password string, ok bool) {
 }
 
 func (proxy *Proxy) handleHTTP(span trace.Span, w http.ResponseWriter, req *http.Request) {
-	resp, err := proxy.newTransport(nil).RoundTrip(req)
+	resp, err := proxy.transport("", nil).RoundTrip(req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		return
@@ -371,7 +417,12 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debugf("hijack https request to %s", r.Host)
 
+	host, _, _ := net.SplitHostPort(r.Host)
+	cConfig.ServerName = host
 	sConfig := new(tls.Config)
+	if proxy.h2s != nil {
+		sConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+	}
 	if proxy.cert.Leaf != nil && proxy.cert.Leaf.IsCA {
 		if proxy.certCache == nil { // Initialize proxy.certCache on first access. (Lazy init)
 			proxy.certCache = lru.New(100) // Default max entries size = 100
@@ -380,9 +431,7 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 			proxy.cert.Leaf.PublicKey,
 			proxy.cert.PrivateKey,
 			proxy.cert.Leaf.SignatureAlgorithm}
-		host, _, _ := net.SplitHostPort(r.Host)
 		sConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-			cConfig.ServerName = host
 			// It's assumed that `hello.ServerName` is always same as `host`, in practice.
 			cacheKey := host
 			cached, hit := proxy.certCache.Get(cacheKey)
@@ -404,20 +453,13 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 		sConfig.Certificates = []tls.Certificate{*proxy.cert}
 	}
 
-	sConn, err := handshake(w, sConfig)
+	sConn, err := handshake(w, r, sConfig)
 	if err != nil {
 		logger.Errorf("handshake failed for %s: %v", r.Host, err)
 		return
 	}
 	defer sConn.Close()
 
-	cConn, err := tls.Dial("tcp", r.Host, cConfig)
-	if err != nil {
-		logger.Errorf("dial failed for %s: %v", r.Host, err)
-		return
-	}
-	cConn.Close()
-
 	rp := &httputil.ReverseProxy{
 		Director: func(req *http.Request) {
 			req.URL.Host = req.Host
@@ -430,18 +472,24 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		},
-		Transport: proxy.newTransport(cConfig),
+		Transport: proxy.transport(r.Host, cConfig),
 	}
 
-	// We have to wait until the connection is closed
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	// NOTE: http.Serve always returns a non-nil error
-	err = http.Serve(&singleUseListener{&customCloseConn{sConn, wg.Done}}, rp)
-	if err != errServerClosed && err != http.ErrServerClosed {
+	if err = proxy.serveTLSConn(sConn, rp); err != nil {
 		logger.Errorf("failed to accept incoming HTTP connections: %v", err)
 	}
-	wg.Wait()
+}
+
+// transport returns the pooled transport of the upstream host, it is created with tlsConfig when absent
+func (proxy *Proxy) transport(host string, tlsConfig *tls.Config) http.RoundTripper {
+	proxy.transportsLock.Lock()
+	defer proxy.transportsLock.Unlock()
+	if rt, ok := proxy.transports.Get(host); ok {
+		return rt.(http.RoundTripper)
+	}
+	rt := proxy.newTransport(tlsConfig)
+	proxy.transports.Add(host, rt)
+	return rt
 }
 
 func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
@@ -449,7 +497,7 @@ func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
 		transport.WithPeerHost(proxy.peerHost),
 		transport.WithPeerTaskManager(proxy.peerTaskManager),
 		transport.WithTLS(tlsConfig),
-		transport.WithCondition(proxy.shouldUseDragonfly),
+		transport.WithRuleCondition(proxy.matchRule),
 		transport.WithDefaultFilter(proxy.defaultFilter),
 		transport.WithDefaultBiz(bizTag),
 		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
@@ -458,21 +506,31 @@ func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
 }
 
 func (proxy *Proxy) mirrorRegistry(w http.ResponseWriter, r *http.Request) {
-	reverseProxy := newReverseProxy(proxy.registry)
+	mirror := proxy.selectMirror(r)
+	if mirror == nil {
+		http.Error(w, "registry mirror feature is disabled", http.StatusNotFound)
+		return
+	}
+
+	reverseProxy := newReverseProxy()
 	t, err := transport.New(
 		transport.WithPeerHost(proxy.peerHost),
 		transport.WithPeerTaskManager(proxy.peerTaskManager),
-		transport.WithTLS(proxy.registry.TLSConfig()),
-		transport.WithCondition(proxy.shouldUseDragonflyForMirror),
+		transport.WithTLS(mirror.TLSConfig()),
+		transport.WithRuleCondition(func(req *http.Request) (*config.Proxy, bool) {
+			return proxy.matchRuleForRegistry(mirror.RegistryMirror, req)
+		}),
 		transport.WithDefaultFilter(proxy.defaultFilter),
 		transport.WithDefaultBiz(bizTag),
 		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
+		transport.WithContentAddressedBlobs(mirror.oci != nil),
 	)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to get transport: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	reverseProxy.Transport = t
+	reverseProxy.Transport = mirror.RoundTripper(t, proxy.images)
 	reverseProxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
 		rw.WriteHeader(http.StatusInternalServerError)
 		// write error string to response body
@@ -483,6 +541,26 @@ func (proxy *Proxy) mirrorRegistry(w http.ResponseWriter, r *http.Request) {
 	reverseProxy.ServeHTTP(w, r)
 }
 
+// selectMirror returns the mirror of the registry host of the request, the mirror of default registry is used
+// when no mirror matches
+func (proxy *Proxy) selectMirror(r *http.Request) *registryMirror {
+	if host := registryHost(r); host != "" {
+		if m, ok := proxy.getRegistryMirrors()[host]; ok {
+			return m
+		}
+	}
+	return proxy.mirror
+}
+
+func (proxy *Proxy) getRegistryMirrors() map[string]*registryMirror {
+	return proxy.hostMirrors.Load().(map[string]*registryMirror)
+}
+
+// setRegistryMirrors replaces the mirrors of registries chosen by host
+func (proxy *Proxy) setRegistryMirrors(mirrors []*config.RegistryMirror) {
+	proxy.hostMirrors.Store(newRegistryMirrors(mirrors))
+}
+
 // remoteConfig returns the tls.Config used to connect to the given remote host.
 // If the host should not be hijacked, and it will return nil.
 func (proxy *Proxy) remoteConfig(host string) *tls.Config {
@@ -538,8 +616,16 @@ func (proxy *Proxy) checkWhiteList(r *http.Request) bool {
 // also change the scheme of the given request if the matched rule has
 // UseHTTPS = true
 func (proxy *Proxy) shouldUseDragonfly(req *http.Request) bool {
+	_, use := proxy.matchRule(req)
+	return use
+}
+
+// matchRule returns the first proxy rule matching the request and whether we should
+// use dragonfly to proxy it, the request is rewritten like shouldUseDragonfly.
+// Requests of rules with source pattern are proxied directly.
+func (proxy *Proxy) matchRule(req *http.Request) (*config.Proxy, bool) {
 	if req.Method != http.MethodGet {
-		return false
+		return nil, false
 	}
 
 	for _, rule := range proxy.rules {
@@ -551,7 +637,7 @@ func (proxy *Proxy) shouldUseDragonfly(req *http.Request) bool {
 				u, err := url.Parse(rule.Regx.ReplaceAllString(req.URL.String(), rule.Redirect))
 				if err != nil {
 					logger.Errorf("failed to rewrite url", err)
-					return false
+					return rule, false
 				}
 				req.URL = u
 				req.Host = req.URL.Host
@@ -560,22 +646,35 @@ func (proxy *Proxy) shouldUseDragonfly(req *http.Request) bool {
 				req.URL.Host = rule.Redirect
 				req.Host = rule.Redirect
 			}
-			return !rule.Direct
+			return rule, !rule.Direct && rule.Pattern != config.PatternSource
 		}
 	}
-	return false
+	return nil, false
 }
 
 // shouldUseDragonflyForMirror returns whether we should use dragonfly to proxy a request
 // when we use registry mirror.
 func (proxy *Proxy) shouldUseDragonflyForMirror(req *http.Request) bool {
-	if proxy.registry == nil || proxy.registry.Direct {
-		return false
+	return proxy.shouldUseDragonflyForRegistry(proxy.registry, req)
+}
+
+// shouldUseDragonflyForRegistry returns whether we should use dragonfly to proxy a request
+// with the mirror of a registry.
+func (proxy *Proxy) shouldUseDragonflyForRegistry(registry *config.RegistryMirror, req *http.Request) bool {
+	_, use := proxy.matchRuleForRegistry(registry, req)
+	return use
+}
+
+// matchRuleForRegistry returns the matched proxy rule when the registry uses proxies,
+// and whether we should use dragonfly to proxy a request with the mirror of a registry.
+func (proxy *Proxy) matchRuleForRegistry(registry *config.RegistryMirror, req *http.Request) (*config.Proxy, bool) {
+	if registry == nil || registry.Direct {
+		return nil, false
 	}
-	if proxy.registry.UseProxies {
-		return proxy.shouldUseDragonfly(req)
+	if registry.UseProxies {
+		return proxy.matchRule(req)
 	}
-	return transport.NeedUseDragonfly(req)
+	return nil, transport.NeedUseDragonfly(req)
 }
 
 // tunnelHTTPS handles a CONNECT request and proxy an https request through an
@@ -586,25 +685,21 @@ func tunnelHTTPS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	hijacker, ok := w.(http.Hijacker)
-	if !ok {
-		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
-		return
-	}
-	clientConn, _, err := hijacker.Hijack()
+	clientConn, err := connect(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		dst.Close()
+		logger.Errorf("connect failed for %s: %v", r.Host, err)
+		return
 	}
 
 	go func() {
 		if err := copyAndClose(dst, clientConn); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logger.Debugf("tunnel to %s closed: %v", r.Host, err)
 		}
 	}()
 
 	if err := copyAndClose(clientConn, dst); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Debugf("tunnel from %s closed: %v", r.Host, err)
 	}
 }
 
@@ -625,16 +720,11 @@ func copyHeader(dst, src http.Header) {
 	}
 }
 
-// handshake hijacks w's underlying net.Conn, responds to the CONNECT request
-// and manually performs the TLS handshake.
-func handshake(w http.ResponseWriter, config *tls.Config) (net.Conn, error) {
-	raw, _, err := w.(http.Hijacker).Hijack()
+// handshake responds to the CONNECT request and manually performs the TLS handshake
+// in the tunnel.
+func handshake(w http.ResponseWriter, r *http.Request, config *tls.Config) (net.Conn, error) {
+	raw, err := connect(w, r)
 	if err != nil {
-		http.Error(w, "no upstream", http.StatusServiceUnavailable)
-		return nil, err
-	}
-	if _, err = raw.Write(okHeader); err != nil {
-		raw.Close()
 		return nil, err
 	}
 	conn := tls.Server(raw, config)
@@ -0,0 +1,168 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+)
+
+// Handler returns the handler of the proxy listener, HTTP/2 without TLS is served too when HTTP/2 is enabled
+func (proxy *Proxy) Handler() http.Handler {
+	if proxy.h2s == nil {
+		return proxy
+	}
+	return h2c.NewHandler(proxy, proxy.h2s)
+}
+
+// serveTLSConn serves the requests in the hijacked TLS connection with handler until the connection is closed,
+// HTTP/2 is used when it is negotiated
+func (proxy *Proxy) serveTLSConn(conn net.Conn, handler http.Handler) error {
+	handler = countStreams(handler)
+	if tlsConn, ok := conn.(*tls.Conn); ok && proxy.h2s != nil &&
+		tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+		proxy.h2s.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		return nil
+	}
+
+	// We have to wait until the connection is closed
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	// NOTE: http.Serve always returns a non-nil error
+	err := http.Serve(&singleUseListener{&customCloseConn{conn, wg.Done}}, handler)
+	wg.Wait()
+	if err != errServerClosed && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// trackStream counts the active stream of proto, the returned function is called when the stream ends
+func trackStream(proto string) func() {
+	metrics.ProxyActiveStreamCount.WithLabelValues(proto).Add(1)
+	return func() {
+		metrics.ProxyActiveStreamCount.WithLabelValues(proto).Sub(1)
+	}
+}
+
+func countStreams(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer trackStream(r.Proto)()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// connect responds to the CONNECT request and returns the connection of the tunnel, it is the stream
+// of the request for HTTP/2, otherwise the hijacked connection of w.
+// Extended CONNECT of RFC 8441 is not supported, the http2 server does not advertise
+// SETTINGS_ENABLE_CONNECT_PROTOCOL and resets the requests with :protocol pseudo header
+func connect(w http.ResponseWriter, r *http.Request) (net.Conn, error) {
+	if r.ProtoMajor == 2 {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return nil, errors.New("streaming not supported")
+		}
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		return &streamConn{body: r.Body, w: w, flusher: flusher, done: make(chan struct{})}, nil
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
+		return nil, errors.New("hijacking not supported")
+	}
+	raw, _, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, "no upstream", http.StatusServiceUnavailable)
+		return nil, err
+	}
+	if _, err = raw.Write(okHeader); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return raw, nil
+}
+
+// streamConn is a net.Conn over the stream of an HTTP/2 CONNECT request, it reads the request body
+// and writes the response body. The handler of the request must not return before the conn is closed.
+type streamConn struct {
+	body    io.ReadCloser
+	w       io.Writer
+	flusher http.Flusher
+
+	mu        sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *streamConn) Read(p []byte) (int, error) {
+	return c.body.Read(p)
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return 0, net.ErrClosed
+	default:
+	}
+	n, err := c.w.Write(p)
+	if err == nil {
+		c.flusher.Flush()
+	}
+	return n, err
+}
+
+func (c *streamConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		close(c.done)
+		c.mu.Unlock()
+		c.body.Close()
+	})
+	return nil
+}
+
+func (c *streamConn) LocalAddr() net.Addr { return streamAddr{} }
+
+func (c *streamConn) RemoteAddr() net.Addr { return streamAddr{} }
+
+// the deadlines are not supported, the stream is closed when the request is canceled
+func (c *streamConn) SetDeadline(t time.Time) error { return nil }
+
+func (c *streamConn) SetReadDeadline(t time.Time) error { return nil }
+
+func (c *streamConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type streamAddr struct{}
+
+func (streamAddr) Network() string { return "http2" }
+
+func (streamAddr) String() string { return "http2-stream" }
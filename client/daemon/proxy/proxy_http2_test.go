@@ -0,0 +1,105 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
+)
+
+func TestProxy_HTTP2Connect(t *testing.T) {
+	// the upstream echoes the data in tunnel
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	proxy, err := NewProxy(WithPeerHost(&scheduler.PeerHost{}), WithHTTP2(&config.HTTP2Option{Enable: true}))
+	assert.Nil(t, err)
+	server := httptest.NewServer(proxy.Handler())
+	defer server.Close()
+
+	// HTTP/2 with prior knowledge over tcp
+	client := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	defer client.CloseIdleConnections()
+	proxyURL, _ := url.Parse(server.URL)
+
+	for i := 0; i < 2; i++ {
+		pr, pw := io.Pipe()
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Scheme: "http", Host: proxyURL.Host},
+			Host:   upstream.Addr().String(),
+			Header: http.Header{},
+			Body:   pr,
+		}
+		resp, err := client.RoundTrip(req)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, resp.ProtoMajor)
+
+		_, err = pw.Write([]byte("ping"))
+		assert.Nil(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(resp.Body, buf)
+		assert.Nil(t, err)
+		assert.Equal(t, "ping", string(buf))
+
+		pw.Close()
+		resp.Body.Close()
+	}
+}
+
+func TestProxy_Transport(t *testing.T) {
+	proxy, err := NewProxy()
+	assert.Nil(t, err)
+
+	rt := proxy.transport("example.com:443", &tls.Config{})
+	assert.True(t, rt == proxy.transport("example.com:443", nil))
+	assert.False(t, rt == proxy.transport("d7y.io:443", &tls.Config{}))
+}
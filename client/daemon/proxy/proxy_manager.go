@@ -17,6 +17,7 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -37,6 +38,8 @@ import (
 )
 
 type Manager interface {
+	// OnNotify reloads the registry mirrors from dynconfig
+	config.Observer
 	Serve(net.Listener) error
 	ServeSNI(net.Listener) error
 	Stop() error
@@ -47,6 +50,9 @@ type proxyManager struct {
 	*http.Server
 	*Proxy
 	config.ListenOption
+
+	// registryMirrors is the json of registry mirrors loaded from dynconfig, it is used to skip the unchanged config
+	registryMirrors []byte
 }
 
 var _ Manager = (*proxyManager)(nil)
@@ -71,6 +77,7 @@ func NewProxyManager(peerHost *scheduler.PeerHost, peerTaskManager peer.TaskMana
 		WithDefaultFilter(opts.DefaultFilter),
 		WithBasicAuth(opts.BasicAuth),
 		WithDumpHTTPContent(opts.DumpHTTPContent),
+		WithHTTP2(opts.HTTP2),
 	}
 
 	if registry != nil {
@@ -78,6 +85,13 @@ func NewProxyManager(peerHost *scheduler.PeerHost, peerTaskManager peer.TaskMana
 		options = append(options, WithRegistryMirror(registry))
 	}
 
+	if len(opts.RegistryMirrors) > 0 {
+		for _, m := range opts.RegistryMirrors {
+			logger.Infof("registry mirror of %s: %v", m.Host, m.Upstreams())
+		}
+		options = append(options, WithRegistryMirrors(opts.RegistryMirrors))
+	}
+
 	if len(proxies) > 0 {
 		logger.Infof("load %d proxy rules", len(proxies))
 		for i, r := range proxies {
@@ -121,7 +135,7 @@ func NewProxyManager(peerHost *scheduler.PeerHost, peerTaskManager peer.TaskMana
 
 func (pm *proxyManager) Serve(listener net.Listener) error {
 	_ = WithDirectHandler(newDirectHandler())(pm.Proxy)
-	pm.Server.Handler = pm.Proxy
+	pm.Server.Handler = pm.Proxy.Handler()
 	return pm.Server.Serve(listener)
 }
 
@@ -130,6 +144,9 @@ func (pm *proxyManager) ServeSNI(listener net.Listener) error {
 }
 
 func (pm *proxyManager) Stop() error {
+	if pm.Proxy != nil {
+		pm.Proxy.images.stop()
+	}
 	return pm.Server.Shutdown(context.Background())
 }
 
@@ -137,6 +154,36 @@ func (pm *proxyManager) IsEnabled() bool {
 	return pm.ListenOption.TCPListen != nil && pm.ListenOption.TCPListen.PortRange.Start != 0
 }
 
+// OnNotify replaces the registry mirrors with the ones in the client config of scheduler cluster,
+// the local registry mirrors are kept when manager does not configure them
+func (pm *proxyManager) OnNotify(data *config.DynconfigData) {
+	if pm.Proxy == nil {
+		return
+	}
+
+	mirrors, err := data.GetRegistryMirrors()
+	if err != nil {
+		logger.Errorf("get registry mirrors from dynconfig error: %s", err)
+		return
+	}
+	if len(mirrors) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(mirrors)
+	if err != nil {
+		logger.Errorf("marshal registry mirrors error: %s", err)
+		return
+	}
+	if bytes.Equal(pm.registryMirrors, b) {
+		return
+	}
+
+	pm.setRegistryMirrors(mirrors)
+	pm.registryMirrors = b
+	logger.Infof("registry mirrors have been updated: %s", string(b))
+}
+
 func newDirectHandler() *http.ServeMux {
 	s := http.DefaultServeMux
 	s.HandleFunc("/args", getArgs)
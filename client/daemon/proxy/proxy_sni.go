@@ -22,11 +22,11 @@ import (
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"sync"
 	"time"
 
 	"github.com/golang/groupcache/lru"
 	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 )
@@ -70,6 +70,9 @@ func handshakeTLSConn(clientConn net.Conn, config *tls.Config) (net.Conn, error)
 func (proxy *Proxy) handleTLSConn(clientConn net.Conn, port int) {
 	var serverName string
 	sConfig := new(tls.Config)
+	if proxy.h2s != nil {
+		sConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+	}
 	if !proxy.cert.Leaf.IsCA {
 		sConfig.Certificates = []tls.Certificate{*proxy.cert}
 	} else {
@@ -122,16 +125,10 @@ func (proxy *Proxy) handleTLSConn(clientConn net.Conn, port int) {
 				}
 			}
 		},
-		Transport: proxy.newTransport(proxy.remoteConfig(serverName)),
+		Transport: proxy.transport(serverName, proxy.remoteConfig(serverName)),
 	}
 
-	// We have to wait until the connection is closed
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	// NOTE: http.Serve always returns a non-nil error
-	err = http.Serve(&singleUseListener{&customCloseConn{tlsConn, wg.Done}}, rp)
-	if err != errServerClosed && err != http.ErrServerClosed {
+	if err = proxy.serveTLSConn(tlsConn, rp); err != nil {
 		logger.Errorf("failed to accept incoming HTTPS connections: %v", err)
 	}
-	wg.Wait()
 }
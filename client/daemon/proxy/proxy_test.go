@@ -235,3 +235,37 @@ func TestMatchWithRedirect(t *testing.T) {
 		TestMirror(t)
 
 }
+
+func TestMatchRuleWithPattern(t *testing.T) {
+	a := assert.New(t)
+	source, err := config.NewProxy("blobs/source", false, false, "")
+	if !a.Nil(err) {
+		return
+	}
+	source.Pattern = config.PatternSource
+	cdn, err := config.NewProxy("blobs/cdn", false, false, "")
+	if !a.Nil(err) {
+		return
+	}
+	cdn.Pattern = config.PatternCDN
+
+	tp, err := NewProxy(WithRules([]*config.Proxy{source, cdn}))
+	if !a.Nil(err) {
+		return
+	}
+
+	req, _ := http.NewRequest("GET", "http://h/blobs/source", nil)
+	rule, use := tp.matchRule(req)
+	a.Equal(source, rule)
+	a.False(use)
+
+	req, _ = http.NewRequest("GET", "http://h/blobs/cdn", nil)
+	rule, use = tp.matchRule(req)
+	a.Equal(cdn, rule)
+	a.True(use)
+
+	req, _ = http.NewRequest("GET", "http://h/manifests/latest", nil)
+	rule, use = tp.matchRule(req)
+	a.Nil(rule)
+	a.False(use)
+}
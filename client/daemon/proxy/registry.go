@@ -0,0 +1,473 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/golang/groupcache/lru"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+const (
+	defaultManifestTTL       = time.Minute
+	defaultManifestCacheSize = 1000
+
+	// maxManifestSize is the max size of the manifest to cache, larger manifests are proxied without caching
+	maxManifestSize = 4 << 20
+
+	// defaultTokenExpiresIn is the expiration of the bearer token in seconds when the token server
+	// does not return it, see https://docs.docker.com/registry/spec/auth/token/
+	defaultTokenExpiresIn = 60
+	// tokenExpireMargin renews the bearer token before it expires
+	tokenExpireMargin = 5 * time.Second
+
+	registryRequestTimeout = 30 * time.Second
+
+	headerDockerContentDigest = "Docker-Content-Digest"
+)
+
+// ociPathReg matches the manifest and blob path of OCI distribution api: /v2/<name>/<manifests|blobs>/<reference>
+var ociPathReg = regexp.MustCompile("^/v2/(.+)/(manifests|blobs)/([^/]+)$")
+
+// ociRegistry caches the manifests and bearer tokens of registries for the registry mirror,
+// it is shared by all requests of the mirror
+type ociRegistry struct {
+	manifests *manifestCache
+	tokens    *tokenCache
+}
+
+func newOCIRegistry(opt *config.OCIOption, tlsConfig *tls.Config) *ociRegistry {
+	ttl, size := opt.ManifestTTL.Duration, opt.ManifestCacheSize
+	if ttl == 0 {
+		ttl = defaultManifestTTL
+	}
+	if size == 0 {
+		size = defaultManifestCacheSize
+	}
+	return &ociRegistry{
+		manifests: &manifestCache{ttl: ttl, cache: lru.New(size)},
+		tokens: &tokenCache{
+			client: &http.Client{
+				Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+				Timeout:   registryRequestTimeout,
+			},
+			challenges: map[string]*authChallenge{},
+			tokens:     map[string]*bearerToken{},
+		},
+	}
+}
+
+// RoundTripper returns a http.RoundTripper which sends the requests of registry api through rt
+func (o *ociRegistry) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &ociTransport{ociRegistry: o, next: rt}
+}
+
+type ociTransport struct {
+	*ociRegistry
+	next http.RoundTripper
+}
+
+func (t *ociTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	match := ociPathReg.FindStringSubmatch(req.URL.Path)
+	if match == nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return t.next.RoundTrip(req)
+	}
+	repo, kind, ref := match[1], match[2], match[3]
+
+	// the credentials of client are passed through, the mirror requests tokens only for anonymous clients
+	clientAuth := req.Header.Get(headers.Authorization) != ""
+	if !clientAuth {
+		auth, err := t.tokens.authorize(req.URL, repo)
+		if err != nil {
+			logger.Warnf("request token of %s for %s error: %s", req.URL.Host, repo, err)
+		} else if auth != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set(headers.Authorization, auth)
+		}
+	}
+
+	// the manifests fetched with the credentials of client are never cached, otherwise they are served to
+	// the anonymous clients, only the manifests fetched anonymously or with the token of mirror are shared
+	if kind == "blobs" || clientAuth {
+		return t.roundTrip(req, clientAuth, repo)
+	}
+
+	if entry := t.manifests.get(req.URL.Host, repo, ref, req.Header.Values(headers.Accept)); entry != nil {
+		logger.Debugf("manifest cache hit: %s", req.URL.String())
+		metrics.ProxyManifestCacheHitCount.Add(1)
+		return entry.response(req), nil
+	}
+	metrics.ProxyManifestCacheMissCount.Add(1)
+
+	resp, err := t.roundTrip(req, clientAuth, repo)
+	if err != nil || resp.StatusCode != http.StatusOK || req.Method != http.MethodGet {
+		return resp, err
+	}
+	return t.manifests.store(req, repo, ref, resp)
+}
+
+// roundTrip retries once with a new token when the registry rejects the request of anonymous client
+func (t *ociTransport) roundTrip(req *http.Request, clientAuth bool, repo string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || clientAuth || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := parseBearerChallenge(resp.Header.Get(headers.WWWAuthenticate))
+	if challenge == nil {
+		return resp, nil
+	}
+	t.tokens.setChallenge(req.URL.Host, challenge)
+	t.tokens.invalidate(req.URL.Host, repo)
+	auth, err := t.tokens.authorize(req.URL, repo)
+	if err != nil || auth == "" {
+		logger.Warnf("request token of %s for %s error: %v", req.URL.Host, repo, err)
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	req = req.Clone(req.Context())
+	req.Header.Set(headers.Authorization, auth)
+	return t.next.RoundTrip(req)
+}
+
+// manifestCache caches manifests by digest, and the manifests referenced by tag with ttl
+type manifestCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	cache *lru.Cache
+}
+
+type manifestEntry struct {
+	header http.Header
+	body   []byte
+	// expire is zero for the manifest referenced by digest, it never changes
+	expire time.Time
+}
+
+// manifestKey returns the cache key of the manifest, the manifest of a tag depends on the accepted media types
+func manifestKey(host, repo, ref string, accept []string) string {
+	if isDigest(ref) {
+		return host + "/" + repo + "@" + ref
+	}
+	return host + "/" + repo + ":" + ref + " " + strings.Join(accept, ",")
+}
+
+func (c *manifestCache) get(host, repo, ref string, accept []string) *manifestEntry {
+	key := manifestKey(host, repo, ref, accept)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.cache.Get(key)
+	if !ok {
+		return nil
+	}
+	entry := v.(*manifestEntry)
+	if !entry.expire.IsZero() && time.Now().After(entry.expire) {
+		c.cache.Remove(key)
+		return nil
+	}
+	return entry
+}
+
+// store caches the manifest in resp and returns a response with the same content,
+// the manifest referenced by digest is cached only when the content matches the digest
+func (c *manifestCache) store(req *http.Request, repo, ref string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxManifestSize {
+		resp.Body = &readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	hdr := http.Header{}
+	for _, k := range []string{headers.ContentType, headerDockerContentDigest} {
+		if v := resp.Header.Get(k); v != "" {
+			hdr.Set(k, v)
+		}
+	}
+	digest := "sha256:" + sha256Hex(body)
+	host := req.URL.Host
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if isDigest(ref) {
+		if ref == digest {
+			c.cache.Add(manifestKey(host, repo, ref, nil), &manifestEntry{header: hdr, body: body})
+		}
+		return resp, nil
+	}
+	c.cache.Add(manifestKey(host, repo, ref, req.Header.Values(headers.Accept)),
+		&manifestEntry{header: hdr, body: body, expire: time.Now().Add(c.ttl)})
+	c.cache.Add(manifestKey(host, repo, digest, nil), &manifestEntry{header: hdr, body: body})
+	return resp, nil
+}
+
+func (e *manifestEntry) response(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        http.StatusText(http.StatusOK),
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          http.NoBody,
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+	resp.Header.Set(headers.ContentLength, strconv.Itoa(len(e.body)))
+	if req.Method != http.MethodHead {
+		resp.Body = io.NopCloser(bytes.NewReader(e.body))
+	}
+	return resp
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func isDigest(ref string) bool {
+	return strings.Contains(ref, ":")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenCache requests and caches the bearer tokens of registries per repository scope
+type tokenCache struct {
+	client *http.Client
+	group  singleflight.Group
+
+	mu sync.Mutex
+	// challenges is keyed by registry host, nil challenge means the registry does not require bearer token
+	challenges map[string]*authChallenge
+	// tokens is keyed by registry host and scope
+	tokens map[string]*bearerToken
+}
+
+type authChallenge struct {
+	realm   string
+	service string
+}
+
+type bearerToken struct {
+	token  string
+	expire time.Time
+}
+
+func tokenKey(host, scope string) string {
+	return host + " " + scope
+}
+
+func pullScope(repo string) string {
+	return fmt.Sprintf("repository:%s:pull", repo)
+}
+
+// authorize returns the value of authorization header to pull repo from the registry of u,
+// it is empty when the registry does not require bearer token
+func (c *tokenCache) authorize(u *url.URL, repo string) (string, error) {
+	challenge, err := c.challenge(u)
+	if err != nil || challenge == nil {
+		return "", err
+	}
+
+	scope := pullScope(repo)
+	key := tokenKey(u.Host, scope)
+	c.mu.Lock()
+	token := c.tokens[key]
+	c.mu.Unlock()
+	if token != nil && time.Now().Before(token.expire) {
+		return "Bearer " + token.token, nil
+	}
+
+	v, err, _ := c.group.Do("token "+key, func() (interface{}, error) {
+		token, err := c.requestToken(challenge, scope)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// remove the expired tokens of the other repositories
+		for k, t := range c.tokens {
+			if time.Now().After(t.expire) {
+				delete(c.tokens, k)
+			}
+		}
+		c.tokens[key] = token
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + v.(*bearerToken).token, nil
+}
+
+// challenge returns the bearer challenge of the registry, the registry is pinged when the challenge is unknown
+func (c *tokenCache) challenge(u *url.URL) (*authChallenge, error) {
+	c.mu.Lock()
+	challenge, ok := c.challenges[u.Host]
+	c.mu.Unlock()
+	if ok {
+		return challenge, nil
+	}
+
+	v, err, _ := c.group.Do("ping "+u.Host, func() (interface{}, error) {
+		resp, err := c.client.Get(fmt.Sprintf("%s://%s/v2/", u.Scheme, u.Host))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxManifestSize))
+
+		var challenge *authChallenge
+		if resp.StatusCode == http.StatusUnauthorized {
+			challenge = parseBearerChallenge(resp.Header.Get(headers.WWWAuthenticate))
+		}
+		c.setChallenge(u.Host, challenge)
+		return challenge, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*authChallenge), nil
+}
+
+func (c *tokenCache) setChallenge(host string, challenge *authChallenge) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenges[host] = challenge
+}
+
+// invalidate removes the token of repo which is rejected by the registry
+func (c *tokenCache) invalidate(host, repo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, tokenKey(host, pullScope(repo)))
+}
+
+func (c *tokenCache) requestToken(challenge *authChallenge, scope string) (*bearerToken, error) {
+	u, err := url.Parse(challenge.realm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse realm %s", challenge.realm)
+	}
+	query := u.Query()
+	if challenge.service != "" {
+		query.Set("service", challenge.service)
+	}
+	query.Set("scope", scope)
+	u.RawQuery = query.Encode()
+
+	resp, err := c.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("request token from %s: %s", challenge.realm, resp.Status)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&result); err != nil {
+		return nil, errors.Wrapf(err, "decode token from %s", challenge.realm)
+	}
+	if result.Token == "" {
+		result.Token = result.AccessToken
+	}
+	if result.Token == "" {
+		return nil, errors.Errorf("empty token from %s", challenge.realm)
+	}
+	if result.ExpiresIn <= 0 {
+		result.ExpiresIn = defaultTokenExpiresIn
+	}
+	metrics.ProxyRegistryTokenCount.Add(1)
+	logger.Debugf("request token from %s for %s, expires in %d seconds", challenge.realm, scope, result.ExpiresIn)
+	return &bearerToken{
+		token:  result.Token,
+		expire: time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - tokenExpireMargin),
+	}, nil
+}
+
+// parseBearerChallenge parses the WWW-Authenticate header in format of
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull",
+// it returns nil when the header is not a bearer challenge
+func parseBearerChallenge(header string) *authChallenge {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil
+	}
+	params := map[string]string{}
+	s := strings.TrimSpace(header[len(prefix):])
+	for s != "" {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimSpace(s[i+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			// quoted value may contain comma, for example scope="repository:foo:pull,push"
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				break
+			}
+			value, s = s[1:end+1], s[end+2:]
+		} else if end := strings.IndexByte(s, ','); end >= 0 {
+			value, s = s[:end], s[end:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = strings.TrimSpace(value)
+		s = strings.TrimLeft(s, ", ")
+	}
+	if params["realm"] == "" {
+		return nil
+	}
+	return &authChallenge{realm: params["realm"], service: params["service"]}
+}
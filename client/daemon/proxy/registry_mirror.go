@@ -0,0 +1,184 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// upstreamFailureCooldown is how long a failed upstream is tried after the healthy ones
+const upstreamFailureCooldown = 30 * time.Second
+
+// containerdNamespaceQuery is the query containerd adds to the requests of mirrors, its value is the registry host
+const containerdNamespaceQuery = "ns"
+
+// registryMirror routes the requests of a registry to its upstreams, the next upstream is tried when
+// the previous one fails with 5xx or error
+type registryMirror struct {
+	*config.RegistryMirror
+
+	upstreams []*upstream
+
+	// oci caches manifests and tokens of the mirror, it is nil when the registry aware mode is disabled
+	oci *ociRegistry
+}
+
+type upstream struct {
+	url *url.URL
+	// failedAt is the unix nano time of the last failure, it is zero when the upstream is healthy
+	failedAt int64
+}
+
+// newRegistryMirror returns nil when the mirror has no upstream
+func newRegistryMirror(r *config.RegistryMirror) *registryMirror {
+	if r == nil || (len(r.Upstreams()) == 0 && !r.DynamicRemote) {
+		return nil
+	}
+
+	m := &registryMirror{RegistryMirror: r}
+	for _, u := range r.Upstreams() {
+		m.upstreams = append(m.upstreams, &upstream{url: u})
+	}
+	if r.OCI != nil && r.OCI.Enable {
+		m.oci = newOCIRegistry(r.OCI, r.TLSConfig())
+	}
+	return m
+}
+
+// newRegistryMirrors returns the mirrors keyed by registry host
+func newRegistryMirrors(mirrors []*config.RegistryMirror) map[string]*registryMirror {
+	hostMirrors := map[string]*registryMirror{}
+	for _, r := range mirrors {
+		if m := newRegistryMirror(r); m != nil {
+			hostMirrors[r.Host] = m
+		}
+	}
+	return hostMirrors
+}
+
+// registryHost returns the registry host of the mirror request, it is empty when unknown.
+// The "ns" query of containerd is preferred, it is the registry name in image reference, eg: docker.io,
+// and the header is the server of registry, eg: https://registry-1.docker.io
+func registryHost(req *http.Request) string {
+	if ns := req.URL.Query().Get(containerdNamespaceQuery); ns != "" {
+		return ns
+	}
+	if reg := req.Header.Get(config.HeaderDragonflyRegistry); reg != "" {
+		if u, err := url.Parse(reg); err == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	return ""
+}
+
+// RoundTripper returns a http.RoundTripper which sends the requests to the upstreams of mirror through rt,
+// the layers of pulled images are prefetched by images when the prefetch of mirror is enabled
+func (m *registryMirror) RoundTripper(rt http.RoundTripper, images *imagePuller) http.RoundTripper {
+	if m.oci != nil {
+		rt = m.oci.RoundTripper(rt)
+		if images != nil && m.OCI.Prefetch && !m.Direct {
+			rt = images.RoundTripper(rt, m.OCI.PrefetchConcurrency)
+		}
+	}
+	return &failoverTransport{mirror: m, next: rt}
+}
+
+// selectUpstreams returns the upstreams for req in order, the healthy ones are before the failed ones
+func (m *registryMirror) selectUpstreams(req *http.Request) []*upstream {
+	if m.DynamicRemote {
+		if reg := req.Header.Get(config.HeaderDragonflyRegistry); reg != "" {
+			if u, err := url.Parse(reg); err == nil {
+				logger.Debugf("dynamic host url: %s", reg)
+				return []*upstream{{url: u}}
+			}
+		}
+	}
+
+	var healthy, failed []*upstream
+	for _, u := range m.upstreams {
+		if u.healthy() {
+			healthy = append(healthy, u)
+		} else {
+			failed = append(failed, u)
+		}
+	}
+	return append(healthy, failed...)
+}
+
+func (u *upstream) healthy() bool {
+	failedAt := atomic.LoadInt64(&u.failedAt)
+	return failedAt == 0 || time.Since(time.Unix(0, failedAt)) > upstreamFailureCooldown
+}
+
+// report updates the health of upstream with the result of request, it returns whether the request is failed
+func (u *upstream) report(resp *http.Response, err error) bool {
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		atomic.StoreInt64(&u.failedAt, time.Now().UnixNano())
+		return true
+	}
+	atomic.StoreInt64(&u.failedAt, 0)
+	return false
+}
+
+type failoverTransport struct {
+	mirror *registryMirror
+	next   http.RoundTripper
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	upstreams := t.mirror.selectUpstreams(req)
+	if len(upstreams) == 0 {
+		return nil, errors.Errorf("registry mirror %s has no upstream", t.mirror.Host)
+	}
+	// the request with body can not be resent
+	if req.Body != nil && req.Body != http.NoBody {
+		upstreams = upstreams[:1]
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i, u := range upstreams {
+		outreq := req.Clone(req.Context())
+		setTarget(outreq, u.url)
+		resp, err = t.next.RoundTrip(outreq)
+		// the request is canceled by client, it is not the failure of upstream
+		if req.Context().Err() != nil {
+			break
+		}
+		if !u.report(resp, err) || i == len(upstreams)-1 {
+			break
+		}
+
+		if err != nil {
+			logger.Warnf("registry mirror upstream %s error: %s, try next upstream", u.url, err)
+		} else {
+			logger.Warnf("registry mirror upstream %s response %s, try next upstream", u.url, resp.Status)
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,149 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+type testUpstream struct {
+	*httptest.Server
+	requests int32
+	status   int32
+}
+
+func newTestUpstream(name string, status int) *testUpstream {
+	u := &testUpstream{status: int32(status)}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&u.requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&u.status)))
+		_, _ = io.WriteString(w, name+r.URL.Path)
+	}))
+	return u
+}
+
+func newTestMirror(t *testing.T, host string, upstreams ...*testUpstream) *config.RegistryMirror {
+	r := &config.RegistryMirror{Host: host}
+	for i, upstream := range upstreams {
+		u, err := url.Parse(upstream.URL)
+		assert.Nil(t, err)
+		if i == 0 {
+			r.Remote = &config.URL{URL: u}
+		} else {
+			r.Endpoints = append(r.Endpoints, &config.URL{URL: u})
+		}
+	}
+	return r
+}
+
+func mirrorGet(t *testing.T, rt http.RoundTripper, rawURL string) (int, string) {
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	resp, err := rt.RoundTrip(req)
+	if !assert.Nil(t, err) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRegistryMirror_Failover(t *testing.T) {
+	first := newTestUpstream("first", http.StatusServiceUnavailable)
+	defer first.Close()
+	second := newTestUpstream("second", http.StatusOK)
+	defer second.Close()
+
+	mirror := newRegistryMirror(newTestMirror(t, "ghcr.io", first, second))
+	rt := mirror.RoundTripper(http.DefaultTransport, nil)
+
+	code, body := mirrorGet(t, rt, "http://127.0.0.1/v2/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "second/v2/", body)
+	assert.Equal(t, int32(1), first.requests)
+
+	// the failed upstream is tried after the healthy ones
+	code, body = mirrorGet(t, rt, "http://127.0.0.1/v2/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "second/v2/", body)
+	assert.Equal(t, int32(1), first.requests)
+
+	// all upstreams are failed, the last response is returned
+	atomic.StoreInt32(&second.status, http.StatusBadGateway)
+	code, _ = mirrorGet(t, rt, "http://127.0.0.1/v2/")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, int32(2), first.requests)
+	assert.Equal(t, int32(3), second.requests)
+
+	// the client error is not the failure of upstream
+	atomic.StoreInt32(&first.status, http.StatusNotFound)
+	atomic.StoreInt32(&second.status, http.StatusNotFound)
+	for _, u := range mirror.upstreams {
+		u.failedAt = 0
+	}
+	code, body = mirrorGet(t, rt, "http://127.0.0.1/v2/foo")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "first/v2/foo", body)
+}
+
+func TestProxy_SelectMirror(t *testing.T) {
+	dockerHub := newTestUpstream("docker.io", http.StatusOK)
+	defer dockerHub.Close()
+	ghcr := newTestUpstream("ghcr.io", http.StatusOK)
+	defer ghcr.Close()
+
+	proxy, err := NewProxy(
+		WithRegistryMirror(newTestMirror(t, "", dockerHub)),
+		WithRegistryMirrors([]*config.RegistryMirror{newTestMirror(t, "ghcr.io", ghcr)}))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		url    string
+		header string
+		expect *testUpstream
+	}{
+		{url: "http://127.0.0.1/v2/foo/manifests/latest", expect: dockerHub},
+		{url: "http://127.0.0.1/v2/foo/manifests/latest?ns=ghcr.io", expect: ghcr},
+		{url: "http://127.0.0.1/v2/foo/manifests/latest?ns=quay.io", expect: dockerHub},
+		{url: "http://127.0.0.1/v2/foo/manifests/latest", header: "https://ghcr.io", expect: ghcr},
+		// the header generated by hack/gen-containerd-hosts.sh is the server of registry, "ns" is preferred
+		{url: "http://127.0.0.1/v2/foo/manifests/latest?ns=ghcr.io", header: "https://pkg-containers.ghcr.io", expect: ghcr},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.header != "" {
+			req.Header.Set(config.HeaderDragonflyRegistry, tc.header)
+		}
+		mirror := proxy.selectMirror(req)
+		if assert.NotNil(t, mirror, tc.url) {
+			assert.Equal(t, tc.expect.URL, mirror.Upstreams()[0].String(), tc.url)
+		}
+	}
+
+	// reload the registry mirrors
+	proxy.setRegistryMirrors(nil)
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/v2/?ns=ghcr.io", nil)
+	assert.Equal(t, proxy.mirror, proxy.selectMirror(req))
+}
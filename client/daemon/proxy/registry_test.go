@@ -0,0 +1,199 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+func TestParseBearerChallenge(t *testing.T) {
+	tests := []struct {
+		header string
+		expect *authChallenge
+	}{
+		{
+			header: `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`,
+			expect: &authChallenge{realm: "https://auth.docker.io/token", service: "registry.docker.io"},
+		},
+		{
+			header: `bearer scope="repository:foo:pull,push", realm="https://ghcr.io/token"`,
+			expect: &authChallenge{realm: "https://ghcr.io/token"},
+		},
+		{
+			header: `Bearer realm=https://quay.io/v2/auth,service=quay.io`,
+			expect: &authChallenge{realm: "https://quay.io/v2/auth", service: "quay.io"},
+		},
+		{
+			header: `Basic realm="harbor"`,
+		},
+		{
+			header: `Bearer service="registry.docker.io"`,
+		},
+		{
+			header: "",
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expect, parseBearerChallenge(tc.header), tc.header)
+	}
+}
+
+type testRegistry struct {
+	*httptest.Server
+	tokenRequests    int32
+	manifestRequests int32
+	manifest         string
+}
+
+// newTestRegistry starts a registry which requires the bearer token from its own token server
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{manifest: `{"schemaVersion":2}`}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&r.tokenRequests, 1)
+		assert.Equal(t, "test", req.URL.Query().Get("service"))
+		fmt.Fprintf(w, `{"token":"%s","expires_in":300}`, req.URL.Query().Get("scope"))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		repo := "unknown"
+		if match := ociPathReg.FindStringSubmatch(req.URL.Path); match != nil {
+			repo = match[1]
+		}
+		auth := req.Header.Get(headers.Authorization)
+		// the private repositories are only accessible with the credentials of client
+		if (auth != "Bearer "+pullScope(repo) || strings.HasPrefix(repo, "private/")) && auth != "Bearer client" {
+			w.Header().Set(headers.WWWAuthenticate, fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&r.manifestRequests, 1)
+		w.Header().Set(headers.ContentType, "application/vnd.oci.image.manifest.v1+json")
+		_, _ = io.WriteString(w, r.manifest)
+	})
+	r.Server = httptest.NewServer(mux)
+	return r
+}
+
+func (r *testRegistry) get(t *testing.T, rt http.RoundTripper, path string, hdr map[string]string) (int, string) {
+	req, _ := http.NewRequest(http.MethodGet, r.URL+path, nil)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	resp, err := rt.RoundTrip(req)
+	if !assert.Nil(t, err) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestOCIRegistry_Manifest(t *testing.T) {
+	registry := newTestRegistry(t)
+	defer registry.Close()
+	digest := "sha256:" + sha256Hex([]byte(registry.manifest))
+
+	rt := newOCIRegistry(&config.OCIOption{Enable: true}, nil).RoundTripper(http.DefaultTransport)
+	for i := 0; i < 2; i++ {
+		code, body := registry.get(t, rt, "/v2/library/alpine/manifests/latest", nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, registry.manifest, body)
+	}
+	code, body := registry.get(t, rt, "/v2/library/alpine/manifests/"+digest, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, registry.manifest, body)
+	assert.Equal(t, int32(1), registry.manifestRequests)
+	assert.Equal(t, int32(1), registry.tokenRequests)
+
+	// the token is cached per repository
+	code, _ = registry.get(t, rt, "/v2/library/nginx/manifests/latest", nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = registry.get(t, rt, "/v2/library/nginx/manifests/stable", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(3), registry.manifestRequests)
+	assert.Equal(t, int32(2), registry.tokenRequests)
+
+	// the credentials of client are passed through
+	code, _ = registry.get(t, rt, "/v2/library/busybox/manifests/latest", map[string]string{headers.Authorization: "Bearer client"})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = registry.get(t, rt, "/v2/library/redis/manifests/latest", map[string]string{headers.Authorization: "Bearer invalid"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, int32(2), registry.tokenRequests)
+}
+
+func TestOCIRegistry_ManifestClientAuth(t *testing.T) {
+	registry := newTestRegistry(t)
+	defer registry.Close()
+	digest := "sha256:" + sha256Hex([]byte(registry.manifest))
+	clientAuth := map[string]string{headers.Authorization: "Bearer client"}
+
+	rt := newOCIRegistry(&config.OCIOption{Enable: true}, nil).RoundTripper(http.DefaultTransport)
+	for i := 0; i < 2; i++ {
+		code, body := registry.get(t, rt, "/v2/private/app/manifests/latest", clientAuth)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, registry.manifest, body)
+	}
+	assert.Equal(t, int32(2), registry.manifestRequests, "manifests fetched with credentials of client are not cached")
+
+	// the anonymous client misses the cache and is rejected by registry
+	for _, ref := range []string{"latest", digest} {
+		code, _ := registry.get(t, rt, "/v2/private/app/manifests/"+ref, nil)
+		assert.Equal(t, http.StatusUnauthorized, code, ref)
+	}
+	assert.Equal(t, int32(2), registry.manifestRequests)
+}
+
+func TestOCIRegistry_ManifestTTL(t *testing.T) {
+	registry := newTestRegistry(t)
+	defer registry.Close()
+
+	rt := newOCIRegistry(&config.OCIOption{
+		Enable:      true,
+		ManifestTTL: clientutil.Duration{Duration: 50 * time.Millisecond},
+	}, nil).RoundTripper(http.DefaultTransport)
+	_, body := registry.get(t, rt, "/v2/library/alpine/manifests/latest", nil)
+	assert.Equal(t, registry.manifest, body)
+
+	// the tag is updated in registry
+	registry.manifest = `{"schemaVersion":2,"layers":[]}`
+	_, body = registry.get(t, rt, "/v2/library/alpine/manifests/latest", nil)
+	assert.NotEqual(t, registry.manifest, body)
+
+	time.Sleep(100 * time.Millisecond)
+	_, body = registry.get(t, rt, "/v2/library/alpine/manifests/latest", nil)
+	assert.Equal(t, registry.manifest, body)
+	assert.Equal(t, int32(2), registry.manifestRequests)
+
+	// different media types of the same tag are cached separately
+	_, body = registry.get(t, rt, "/v2/library/alpine/manifests/latest", map[string]string{headers.Accept: "application/vnd.oci.image.index.v1+json"})
+	assert.Equal(t, registry.manifest, body)
+	assert.Equal(t, int32(3), registry.manifestRequests)
+}
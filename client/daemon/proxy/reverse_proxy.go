@@ -21,45 +21,32 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
-
-	"d7y.io/dragonfly/v2/client/config"
-	logger "d7y.io/dragonfly/v2/internal/dflog"
 )
 
-func newReverseProxy(mirror *config.RegistryMirror) *httputil.ReverseProxy {
-	reverseProxy := httputil.NewSingleHostReverseProxy(mirror.Remote.URL)
-	if mirror.DynamicRemote {
-		reverseProxy.Director = newDynamicDirector(mirror.Remote.URL)
+// newReverseProxy returns a reverse proxy for the registry mirror, the upstream of the request
+// is set by the transport of the mirror
+func newReverseProxy() *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			if _, ok := req.Header["User-Agent"]; !ok {
+				// explicitly disable User-Agent so it's not set to default value
+				req.Header.Set("User-Agent", "")
+			}
+		},
 	}
-	return reverseProxy
 }
 
-func newDynamicDirector(remote *url.URL) func(*http.Request) {
-	director := func(req *http.Request) {
-		var target = remote
-		targetQuery := target.RawQuery
-		reg := req.Header.Get(config.HeaderDragonflyRegistry)
-		if len(reg) > 0 {
-			regURL, err := url.Parse(reg)
-			if err == nil {
-				logger.Debugf("dynamic host url: %s", reg)
-				target = regURL
-			}
-		}
-		req.URL.Scheme = target.Scheme
-		req.URL.Host = target.Host
-		req.URL.Path, req.URL.RawPath = joinURLPath(target, req.URL)
-		if targetQuery == "" || req.URL.RawQuery == "" {
-			req.URL.RawQuery = targetQuery + req.URL.RawQuery
-		} else {
-			req.URL.RawQuery = targetQuery + "&" + req.URL.RawQuery
-		}
-		if _, ok := req.Header["User-Agent"]; !ok {
-			// explicitly disable User-Agent so it's not set to default value
-			req.Header.Set("User-Agent", "")
-		}
+// setTarget rewrites the url of req to target, same as the director of httputil.NewSingleHostReverseProxy
+func setTarget(req *http.Request, target *url.URL) {
+	targetQuery := target.RawQuery
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path, req.URL.RawPath = joinURLPath(target, req.URL)
+	if targetQuery == "" || req.URL.RawQuery == "" {
+		req.URL.RawQuery = targetQuery + req.URL.RawQuery
+	} else {
+		req.URL.RawQuery = targetQuery + "&" + req.URL.RawQuery
 	}
-	return director
 }
 
 // singleJoiningSlash is from net/http/httputil/reverseproxy.go
@@ -19,15 +19,20 @@ package rpcserver
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 
+	"github.com/go-http-utils/headers"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/internal/dferrors"
@@ -39,6 +44,9 @@ import (
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 )
 
+// streamChunkSize is the max size of content in one DownStreamResult, it is far less than the grpc max message size
+const streamChunkSize = 256 * 1024
+
 type Server interface {
 	clientutil.KeepAlive
 	ServeDownload(listener net.Listener) error
@@ -135,6 +143,10 @@ func (m *server) CheckHealth(context.Context) error {
 func (m *server) Download(ctx context.Context,
 	req *dfdaemongrpc.DownRequest, results chan<- *dfdaemongrpc.DownResult) error {
 	m.Keep()
+	outputs, err := convertOutputs(req)
+	if err != nil {
+		return err
+	}
 	// init peer task request, peer uses different peer id to generate every request
 	peerTask := &peer.FilePeerTaskRequest{
 		PeerTaskRequest: scheduler.PeerTaskRequest{
@@ -142,17 +154,25 @@ func (m *server) Download(ctx context.Context,
 			UrlMeta:  req.UrlMeta,
 			PeerId:   idgen.PeerID(m.peerHost.Ip),
 			PeerHost: m.peerHost,
+			Pattern:  req.Pattern,
 		},
-		Output:            req.Output,
-		Limit:             req.Limit,
-		DisableBackSource: req.DisableBackSource,
-		Pattern:           req.Pattern,
-		Callsystem:        req.Callsystem,
+		Output:                req.Output,
+		Outputs:               outputs,
+		DisableFileAttributes: req.DisableFileAttributes,
+		Pin:                   req.Pin,
+		Priority:              req.Priority,
+		Limit:                 req.Limit,
+		DisableBackSource:     req.DisableBackSource,
+		Pattern:               req.Pattern,
+		Callsystem:            req.Callsystem,
 	}
 	log := logger.With("peer", peerTask.PeerId, "component", "downloadService")
 
 	peerTaskProgress, tiny, err := m.peerTaskManager.StartFilePeerTask(ctx, peerTask)
 	if err != nil {
+		if de, ok := err.(*dferrors.DfError); ok {
+			return de
+		}
 		return dferrors.New(base.Code_UnknownError, fmt.Sprintf("%s", err))
 	}
 	if tiny != nil {
@@ -161,9 +181,10 @@ func (m *server) Download(ctx context.Context,
 			PeerId:          tiny.PeerID,
 			CompletedLength: uint64(len(tiny.Content)),
 			Done:            true,
+			ContentLength:   int64(len(tiny.Content)),
 		}
 		log.Infof("tiny file, wrote to output")
-		if req.Uid != 0 && req.Gid != 0 {
+		if len(outputs) == 0 && req.Uid != 0 && req.Gid != 0 {
 			if err = os.Chown(req.Output, int(req.Uid), int(req.Gid)); err != nil {
 				log.Errorf("change own failed: %s", err)
 				return err
@@ -185,16 +206,22 @@ func (m *server) Download(ctx context.Context,
 				return dferrors.New(p.State.Code, p.State.Msg)
 			}
 			results <- &dfdaemongrpc.DownResult{
-				TaskId:          p.TaskID,
-				PeerId:          p.PeerID,
-				CompletedLength: uint64(p.CompletedLength),
-				Done:            p.PeerTaskDone,
+				TaskId:           p.TaskID,
+				PeerId:           p.PeerID,
+				CompletedLength:  uint64(p.CompletedLength),
+				Done:             p.PeerTaskDone,
+				ContentLength:    p.ContentLength,
+				Reuse:            p.Reuse,
+				PeerLength:       uint64(p.PeerLength),
+				CdnLength:        uint64(p.CDNLength),
+				BackSourceLength: uint64(p.BackSourceLength),
 			}
 			// peer task sets PeerTaskDone to true only once
 			if p.PeerTaskDone {
 				p.DoneCallback()
 				log.Infof("task %s/%s done", p.PeerID, p.TaskID)
-				if req.Uid != 0 && req.Gid != 0 {
+				// the owner of outputs is changed by storage
+				if len(outputs) == 0 && req.Uid != 0 && req.Gid != 0 {
 					log.Infof("change own to uid %d gid %d", req.Uid, req.Gid)
 					if err = os.Chown(req.Output, int(req.Uid), int(req.Gid)); err != nil {
 						log.Errorf("change own failed: %s", err)
@@ -213,3 +240,329 @@ func (m *server) Download(ctx context.Context,
 		}
 	}
 }
+
+func (m *server) DownloadStream(req *dfdaemongrpc.DownRequest, stream dfdaemongrpc.Daemon_DownloadStreamServer) error {
+	m.Keep()
+	ctx := stream.Context()
+	peerID := idgen.PeerID(m.peerHost.Ip)
+	log := logger.With("peer", peerID, "component", "downloadService")
+
+	// the stream peer task is the same one used by proxy, the content is read in order of offset
+	body, attr, err := m.peerTaskManager.StartStreamPeerTask(ctx, &peer.StreamPeerTaskRequest{
+		PeerTaskRequest: scheduler.PeerTaskRequest{
+			Url:      req.Url,
+			UrlMeta:  req.UrlMeta,
+			PeerId:   peerID,
+			PeerHost: m.peerHost,
+			Pattern:  req.Pattern,
+		},
+		Limit:             req.Limit,
+		DisableBackSource: req.DisableBackSource,
+	})
+	if err != nil {
+		log.Errorf("start stream peer task error: %s", err)
+		if de, ok := err.(*dferrors.DfError); ok {
+			return de
+		}
+		return dferrors.New(base.Code_UnknownError, fmt.Sprintf("%s", err))
+	}
+	defer body.Close()
+
+	var contentLength int64 = -1
+	if l, ok := attr[headers.ContentLength]; ok {
+		if i, e := strconv.ParseInt(l, 10, 64); e == nil {
+			contentLength = i
+		}
+	}
+	if err = stream.Send(&dfdaemongrpc.DownStreamResult{
+		TaskId:        attr[config.HeaderDragonflyTask],
+		PeerId:        peerID,
+		ContentLength: contentLength,
+	}); err != nil {
+		log.Errorf("send stream task info error: %s", err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+
+	var (
+		buf     = make([]byte, streamChunkSize)
+		written int64
+	)
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			if e := stream.Send(&dfdaemongrpc.DownStreamResult{Data: buf[:n]}); e != nil {
+				log.Errorf("send content error: %s", e)
+				return dferrors.New(base.Code_ClientError, e.Error())
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			log.Errorf("read content error: %s", err)
+			if errors.Is(err, storage.ErrInvalidDigest) {
+				return dferrors.New(base.Code_ClientDigestMismatch, err.Error())
+			}
+			return dferrors.New(base.Code_ClientError, err.Error())
+		}
+	}
+	log.Infof("stream task %s done, length: %d", attr[config.HeaderDragonflyTask], written)
+	return nil
+}
+
+func (m *server) ListTasks(ctx context.Context, req *dfdaemongrpc.ListTasksRequest) (*dfdaemongrpc.ListTasksResult, error) {
+	m.Keep()
+	result := &dfdaemongrpc.ListTasksResult{}
+	for _, info := range m.storageManager.ListTasks() {
+		result.Tasks = append(result.Tasks, convertTaskInfo(info))
+	}
+	return result, nil
+}
+
+func (m *server) StatTask(ctx context.Context, req *dfdaemongrpc.StatTaskRequest) (*dfdaemongrpc.StatTaskResult, error) {
+	m.Keep()
+	infos := m.storageManager.FindTasks(req.TaskId)
+	if len(infos) == 0 {
+		return nil, dferrors.Newf(base.Code_PeerTaskNotFound, "task %s not found", req.TaskId)
+	}
+	result := &dfdaemongrpc.StatTaskResult{}
+	for _, info := range infos {
+		result.Tasks = append(result.Tasks, convertTaskInfo(info))
+	}
+	return result, nil
+}
+
+func (m *server) DeleteTask(ctx context.Context, req *dfdaemongrpc.DeleteTaskRequest) error {
+	m.Keep()
+	infos := m.storageManager.FindTasks(req.TaskId)
+	if len(infos) == 0 {
+		return dferrors.Newf(base.Code_PeerTaskNotFound, "task %s not found", req.TaskId)
+	}
+	// running peer task is still writing the storage, refuse to delete it
+	for _, info := range infos {
+		if m.peerTaskManager.IsPeerTaskRunning(info.PeerID) {
+			return dferrors.Newf(base.Code_BadRequest, "task %s is running by peer %s", info.TaskID, info.PeerID)
+		}
+	}
+	if err := m.storageManager.DeleteTask(req.TaskId); err != nil {
+		logger.Errorf("delete task %s error: %s", req.TaskId, err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+	logger.Infof("task %s deleted", req.TaskId)
+	return nil
+}
+
+func (m *server) PinTask(ctx context.Context, req *dfdaemongrpc.PinTaskRequest) error {
+	m.Keep()
+	err := m.storageManager.PinTask(storage.PinTaskRequest{
+		TaskID:   req.TaskId,
+		Pinned:   req.Pinned,
+		Priority: req.Priority,
+	})
+	if err == storage.ErrTaskNotFound {
+		return dferrors.Newf(base.Code_PeerTaskNotFound, "task %s not found", req.TaskId)
+	}
+	if err != nil {
+		logger.Errorf("pin task %s error: %s", req.TaskId, err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+	logger.Infof("task %s pinned: %t, priority: %d", req.TaskId, req.Pinned, req.Priority)
+	return nil
+}
+
+func (m *server) ImportTask(ctx context.Context, req *dfdaemongrpc.ImportTaskRequest) error {
+	m.Keep()
+	if !filepath.IsAbs(req.Path) {
+		return dferrors.Newf(base.Code_BadRequest, "path %s is not absolute", req.Path)
+	}
+	meta := storage.PeerTaskMetadata{
+		PeerID: idgen.PeerID(m.peerHost.Ip),
+		TaskID: idgen.TaskID(req.Url, req.UrlMeta),
+	}
+	log := logger.With("peer", meta.PeerID, "task", meta.TaskID, "component", "importService")
+	if reuse := m.storageManager.FindCompletedTask(meta.TaskID); reuse != nil {
+		log.Infof("task is already completed by peer %s, skip importing", reuse.PeerID)
+		return nil
+	}
+
+	// 1. register task storage
+	err := m.storageManager.RegisterTask(ctx, storage.RegisterTaskRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		},
+		URL:           req.Url,
+		URLMeta:       req.UrlMeta,
+		ContentLength: -1,
+		TotalPieces:   -1,
+	})
+	if err != nil {
+		log.Errorf("register task storage error: %s", err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+
+	// 2. import file into task storage
+	if err = m.peerTaskManager.GetPieceManager().ImportFile(ctx, meta, req.Path, req.UrlMeta.GetDigest()); err != nil {
+		log.Errorf("import file %s error: %s", req.Path, err)
+		if er := m.storageManager.UnregisterTask(ctx, storage.CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		}); er != nil {
+			log.Errorf("unregister task storage error: %s", er)
+		}
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+
+	// 3. mark task storage done
+	err = m.storageManager.Store(ctx, &storage.StoreRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		},
+		MetadataOnly: true,
+	})
+	if err != nil {
+		log.Errorf("store task metadata error: %s", err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+
+	// 4. announce task to scheduler, the imported data is kept even when announcing failed
+	if err = m.peerTaskManager.AnnouncePeerTask(ctx, meta, req.Url, req.UrlMeta); err != nil {
+		log.Errorf("announce task error: %s", err)
+		return dferrors.New(base.Code_SchedError, err.Error())
+	}
+	log.Infof("import file %s done", req.Path)
+	return nil
+}
+
+func (m *server) ExportTask(ctx context.Context, req *dfdaemongrpc.ExportTaskRequest) error {
+	m.Keep()
+	if !filepath.IsAbs(req.Output) {
+		return dferrors.Newf(base.Code_BadRequest, "output %s is not absolute", req.Output)
+	}
+	taskID := req.TaskId
+	if taskID == "" {
+		if req.Url == "" {
+			return dferrors.New(base.Code_BadRequest, "task id or url is required")
+		}
+		taskID = idgen.TaskID(req.Url, req.UrlMeta)
+	}
+	log := logger.With("task", taskID, "component", "exportService")
+
+	// export only uses the local storage, never contacts scheduler or source
+	reuse := m.storageManager.FindCompletedTask(taskID)
+	if reuse == nil {
+		infos := m.storageManager.FindTasks(taskID)
+		if len(infos) == 0 {
+			return dferrors.Newf(base.Code_PeerTaskNotFound, "task %s not found", taskID)
+		}
+		info := infos[0]
+		return dferrors.Newf(base.Code_ClientError, "task %s is not completed, pieces: %d/%d",
+			taskID, info.CompletedPieces, info.TotalPieces)
+	}
+	for _, info := range m.storageManager.FindTasks(taskID) {
+		if info.PeerID == reuse.PeerID && info.CompletedPieces < info.TotalPieces {
+			return dferrors.Newf(base.Code_ClientError, "task %s has missing pieces: %d/%d",
+				taskID, info.CompletedPieces, info.TotalPieces)
+		}
+	}
+
+	err := m.storageManager.Store(ctx, &storage.StoreRequest{
+		CommonTaskRequest: storage.CommonTaskRequest{
+			PeerID:      reuse.PeerID,
+			TaskID:      taskID,
+			Destination: req.Output,
+		},
+		StoreOnly:   true,
+		TotalPieces: reuse.TotalPieces,
+		Mode:        convertExportMode(req.Mode),
+	})
+	if err != nil {
+		log.Errorf("export task to %s error: %s", req.Output, err)
+		return dferrors.New(base.Code_ClientError, err.Error())
+	}
+	if req.Uid != 0 && req.Gid != 0 {
+		log.Infof("change own to uid %d gid %d", req.Uid, req.Gid)
+		if err = os.Chown(req.Output, int(req.Uid), int(req.Gid)); err != nil {
+			log.Errorf("change own failed: %s", err)
+			return dferrors.New(base.Code_ClientError, err.Error())
+		}
+	}
+	log.Infof("export task from peer %s to %s done", reuse.PeerID, req.Output)
+	return nil
+}
+
+// convertOutputs converts output and extra outputs of the request to the outputs of storage,
+// it returns nil when the request has only one output without mode, which is stored as before
+func convertOutputs(req *dfdaemongrpc.DownRequest) ([]storage.StoreOutput, error) {
+	if len(req.ExtraOutputs) == 0 && req.Mode == 0 {
+		return nil, nil
+	}
+	output := storage.StoreOutput{
+		Path: req.Output,
+		Perm: os.FileMode(req.Mode),
+	}
+	// the owner of output is kept when both uid and gid are 0, like the output without mode
+	if req.Uid != 0 || req.Gid != 0 {
+		output.Uid, output.Gid = ownerID(&req.Uid), ownerID(&req.Gid)
+	}
+	outputs := []storage.StoreOutput{output}
+	for _, output := range req.ExtraOutputs {
+		outputs = append(outputs, storage.StoreOutput{
+			Path: output.Path,
+			Uid:  ownerID(output.Uid),
+			Gid:  ownerID(output.Gid),
+			Perm: os.FileMode(output.Mode),
+		})
+	}
+	paths := map[string]bool{}
+	for _, output := range outputs {
+		if !filepath.IsAbs(output.Path) {
+			return nil, dferrors.Newf(base.Code_BadRequest, "output %s is not absolute", output.Path)
+		}
+		if paths[filepath.Clean(output.Path)] {
+			return nil, dferrors.Newf(base.Code_BadRequest, "output %s is duplicated", output.Path)
+		}
+		paths[filepath.Clean(output.Path)] = true
+	}
+	return outputs, nil
+}
+
+// ownerID converts the optional uid or gid of output
+func ownerID(id *int64) *int {
+	if id == nil {
+		return nil
+	}
+	i := int(*id)
+	return &i
+}
+
+func convertExportMode(mode dfdaemongrpc.ExportMode) storage.StoreMode {
+	switch mode {
+	case dfdaemongrpc.ExportMode_HARDLINK:
+		return storage.StoreModeHardLink
+	case dfdaemongrpc.ExportMode_REFLINK:
+		return storage.StoreModeReflink
+	case dfdaemongrpc.ExportMode_COPY:
+		return storage.StoreModeCopy
+	default:
+		return storage.StoreModeAuto
+	}
+}
+
+func convertTaskInfo(info *storage.TaskInfo) *dfdaemongrpc.TaskInfo {
+	return &dfdaemongrpc.TaskInfo{
+		TaskId:         info.TaskID,
+		PeerId:         info.PeerID,
+		Url:            info.URL,
+		UrlMeta:        info.URLMeta,
+		ContentLength:  info.ContentLength,
+		TotalPiece:     info.TotalPieces,
+		CompletedPiece: info.CompletedPieces,
+		Done:           info.Done,
+		LastAccessTime: info.LastAccess.UnixNano(),
+		Pinned:         info.Pinned,
+		Priority:       info.Priority,
+	}
+}
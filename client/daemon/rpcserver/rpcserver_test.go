@@ -22,6 +22,8 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,11 +31,16 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/phayes/freeport"
 	testifyassert "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/peer"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/client/daemon/test"
 	mock_peer "d7y.io/dragonfly/v2/client/daemon/test/mock/peer"
 	mock_storage "d7y.io/dragonfly/v2/client/daemon/test/mock/storage"
+	"d7y.io/dragonfly/v2/internal/dferrors"
 	"d7y.io/dragonfly/v2/internal/dfnet"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
@@ -132,6 +139,80 @@ func TestDownloadManager_ServeDownload(t *testing.T) {
 	assert.True(lastResult.Done)
 }
 
+// failedDownloadStreamServer fails to send any result to client
+type failedDownloadStreamServer struct {
+	grpc.ServerStream
+}
+
+func (s *failedDownloadStreamServer) Context() context.Context {
+	return context.Background()
+}
+
+func (s *failedDownloadStreamServer) Send(*dfdaemongrpc.DownStreamResult) error {
+	return io.ErrClosedPipe
+}
+
+func TestDownloadManager_DownloadStream_SendError(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPeerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	mockPeerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+			assert.Equal(float64(1024), req.Limit)
+			assert.True(req.DisableBackSource)
+			return io.NopCloser(strings.NewReader("test data")), map[string]string{}, nil
+		})
+	m := &server{
+		KeepAlive:       clientutil.NewKeepAlive("test"),
+		peerHost:        &scheduler.PeerHost{},
+		peerTaskManager: mockPeerTaskManager,
+	}
+
+	err := m.DownloadStream(&dfdaemongrpc.DownRequest{
+		Url:               "http://localhost/test",
+		Limit:             1024,
+		DisableBackSource: true,
+	}, &failedDownloadStreamServer{})
+	de, ok := err.(*dferrors.DfError)
+	if assert.True(ok, "error should be a DfError") {
+		assert.Equal(base.Code_ClientError, de.Code)
+	}
+}
+
+func Test_convertOutputs(t *testing.T) {
+	assert := testifyassert.New(t)
+	zero, uid := int64(0), int64(1000)
+	outputs, err := convertOutputs(&dfdaemongrpc.DownRequest{
+		Output: "/tmp/output",
+		Mode:   0644,
+		ExtraOutputs: []*dfdaemongrpc.Output{
+			{Path: "/tmp/output-gid", Gid: &zero},
+			{Path: "/tmp/output-uid", Uid: &uid},
+			{Path: "/tmp/output-default"},
+		},
+	})
+	assert.Nil(err)
+	if !assert.Len(outputs, 4) {
+		return
+	}
+	// the owner of main output is kept when both uid and gid are 0
+	assert.Nil(outputs[0].Uid)
+	assert.Nil(outputs[0].Gid)
+	// gid 0 is changed when it is set explicitly
+	assert.Nil(outputs[1].Uid)
+	if assert.NotNil(outputs[1].Gid) {
+		assert.Equal(0, *outputs[1].Gid)
+	}
+	if assert.NotNil(outputs[2].Uid) {
+		assert.Equal(1000, *outputs[2].Uid)
+	}
+	assert.Nil(outputs[2].Gid)
+	assert.Nil(outputs[3].Uid)
+	assert.Nil(outputs[3].Gid)
+}
+
 func TestDownloadManager_ServePeer(t *testing.T) {
 	assert := testifyassert.New(t)
 	ctrl := gomock.NewController(t)
@@ -254,3 +335,70 @@ func TestDownloadManager_ServePeer(t *testing.T) {
 		assert.Equal(tc.responsePieceSize, len(response.PieceInfos))
 	}
 }
+
+func TestDownloadManager_ImportTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dataDir, err := os.MkdirTemp("", "d7y-test-*")
+	assert.Nil(err)
+	defer os.RemoveAll(dataDir)
+	storageManager, err := storage.NewStorageManager(
+		config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Hour,
+			},
+		}, func(request storage.CommonTaskRequest) {})
+	assert.Nil(err)
+	pieceManager, err := peer.NewPieceManager(storageManager, time.Minute)
+	assert.Nil(err)
+
+	var (
+		url       = "http://www.test.com/import"
+		urlMeta   = &base.UrlMeta{Tag: "d7y-test"}
+		announced *storage.PeerTaskMetadata
+	)
+	mockPeerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	mockPeerTaskManager.EXPECT().GetPieceManager().AnyTimes().Return(pieceManager)
+	mockPeerTaskManager.EXPECT().AnnouncePeerTask(gomock.Any(), gomock.Any(), url, urlMeta).Times(1).DoAndReturn(
+		func(ctx context.Context, meta storage.PeerTaskMetadata, url string, urlMeta *base.UrlMeta) error {
+			announced = &meta
+			return nil
+		})
+	m := &server{
+		KeepAlive:       clientutil.NewKeepAlive("test"),
+		peerHost:        &scheduler.PeerHost{Ip: "127.0.0.1"},
+		peerTaskManager: mockPeerTaskManager,
+		storageManager:  storageManager,
+	}
+
+	// relative path is rejected
+	err = m.ImportTask(context.Background(), &dfdaemongrpc.ImportTaskRequest{Url: url, UrlMeta: urlMeta, Path: "go.html"})
+	assert.Equal(base.Code_BadRequest, err.(*dferrors.DfError).Code)
+
+	path, err := filepath.Abs(test.File)
+	assert.Nil(err)
+	err = m.ImportTask(context.Background(), &dfdaemongrpc.ImportTaskRequest{Url: url, UrlMeta: urlMeta, Path: path})
+	assert.Nil(err)
+	if !assert.NotNil(announced) {
+		return
+	}
+	taskID := idgen.TaskID(url, urlMeta)
+	assert.Equal(taskID, announced.TaskID)
+	reuse := storageManager.FindCompletedTask(taskID)
+	if !assert.NotNil(reuse) {
+		return
+	}
+	assert.Equal(announced.PeerID, reuse.PeerID)
+
+	testData, err := os.ReadFile(test.File)
+	assert.Nil(err)
+	assert.Equal(int64(len(testData)), reuse.ContentLength)
+
+	// importing the completed task again is skipped without announcing
+	err = m.ImportTask(context.Background(), &dfdaemongrpc.ImportTaskRequest{Url: url, UrlMeta: urlMeta, Path: path})
+	assert.Nil(err)
+}
@@ -0,0 +1,90 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// cosignVerifier verifies the signatures created by "cosign sign-blob", which are the base64 encoded
+// ecdsa or rsa signatures of the sha256 of the content
+type cosignVerifier struct {
+	keys []crypto.PublicKey
+}
+
+func newCosignVerifier(keys [][]byte) (Verifier, error) {
+	v := &cosignVerifier{}
+	for _, key := range keys {
+		for rest := key; ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse cosign public key")
+			}
+			switch pub.(type) {
+			case *ecdsa.PublicKey, *rsa.PublicKey:
+				v.keys = append(v.keys, pub)
+			default:
+				return nil, errors.Errorf("cosign public key type %T is not supported", pub)
+			}
+		}
+	}
+	if len(v.keys) == 0 {
+		return nil, errors.New("no cosign public key is found")
+	}
+	return v, nil
+}
+
+func (v *cosignVerifier) Verify(content io.Reader, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		// raw signature
+		sig = signature
+	}
+
+	h := sha256.New()
+	if _, err = io.Copy(h, content); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+	for _, key := range v.keys {
+		switch pub := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(pub, digest, sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig) == nil {
+				return nil
+			}
+		}
+	}
+	return errors.Wrap(ErrUntrusted, "cosign: signature is not verified by any public key")
+}
@@ -0,0 +1,59 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck // openpgp is frozen, but it is enough for detached signatures
+)
+
+const gpgArmoredSignaturePrefix = "-----BEGIN PGP SIGNATURE-----"
+
+// gpgVerifier verifies the armored or binary detached gpg signatures
+type gpgVerifier struct {
+	keyring openpgp.EntityList
+}
+
+func newGPGVerifier(keys [][]byte) (Verifier, error) {
+	v := &gpgVerifier{}
+	for _, key := range keys {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+		if err != nil {
+			if entities, err = openpgp.ReadKeyRing(bytes.NewReader(key)); err != nil {
+				return nil, errors.Wrap(err, "read gpg public key")
+			}
+		}
+		v.keyring = append(v.keyring, entities...)
+	}
+	return v, nil
+}
+
+func (v *gpgVerifier) Verify(content io.Reader, signature []byte) error {
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(gpgArmoredSignaturePrefix)) {
+		_, err = openpgp.CheckArmoredDetachedSignature(v.keyring, content, bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(v.keyring, content, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return errors.Wrapf(ErrUntrusted, "gpg: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	minisignKeyIDSize       = 8
+	minisignCommentPrefix   = "untrusted comment:"
+	minisignTrustedPrefix   = "trusted comment: "
+	minisignAlgorithm       = "Ed"
+	minisignHashedAlgorithm = "ED"
+)
+
+// minisignVerifier verifies the prehashed minisign signatures, the legacy signatures of the whole content
+// are not supported because the content may be too large to load in memory
+type minisignVerifier struct {
+	keys map[[minisignKeyIDSize]byte]ed25519.PublicKey
+}
+
+type minisignSignature struct {
+	algorithm      string
+	keyID          [minisignKeyIDSize]byte
+	signature      []byte
+	trustedComment string
+	globalSig      []byte
+}
+
+func newMinisignVerifier(keys [][]byte) (Verifier, error) {
+	v := &minisignVerifier{keys: map[[minisignKeyIDSize]byte]ed25519.PublicKey{}}
+	for _, key := range keys {
+		lines := minisignLines(key)
+		if len(lines) == 0 {
+			return nil, errors.New("empty minisign public key")
+		}
+		data, err := base64.StdEncoding.DecodeString(lines[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "decode minisign public key")
+		}
+		if len(data) != 2+minisignKeyIDSize+ed25519.PublicKeySize || string(data[:2]) != minisignAlgorithm {
+			return nil, errors.New("invalid minisign public key")
+		}
+		var keyID [minisignKeyIDSize]byte
+		copy(keyID[:], data[2:2+minisignKeyIDSize])
+		v.keys[keyID] = data[2+minisignKeyIDSize:]
+	}
+	return v, nil
+}
+
+func (v *minisignVerifier) Verify(content io.Reader, signature []byte) error {
+	sig, err := parseMinisignSignature(signature)
+	if err != nil {
+		return errors.Wrapf(ErrUntrusted, "minisign: %v", err)
+	}
+	key, ok := v.keys[sig.keyID]
+	if !ok {
+		return errors.Wrapf(ErrUntrusted, "minisign: key %X is not trusted", sig.keyID)
+	}
+
+	h, _ := blake2b.New512(nil)
+	if _, err = io.Copy(h, content); err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, h.Sum(nil), sig.signature) {
+		return errors.Wrap(ErrUntrusted, "minisign: invalid signature")
+	}
+	if !ed25519.Verify(key, append(sig.signature, sig.trustedComment...), sig.globalSig) {
+		return errors.Wrap(ErrUntrusted, "minisign: invalid trusted comment")
+	}
+	return nil
+}
+
+// parseMinisignSignature parses the signature file, which contains the signature, trusted comment and global signature
+func parseMinisignSignature(signature []byte) (*minisignSignature, error) {
+	lines := minisignLines(signature)
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], minisignTrustedPrefix) {
+		return nil, errors.New("invalid signature format")
+	}
+	data, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode signature")
+	}
+	if len(data) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return nil, errors.New("invalid signature size")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode global signature")
+	}
+
+	sig := &minisignSignature{
+		algorithm:      string(data[:2]),
+		signature:      data[2+minisignKeyIDSize:],
+		trustedComment: strings.TrimPrefix(lines[1], minisignTrustedPrefix),
+		globalSig:      globalSig,
+	}
+	copy(sig.keyID[:], data[2:2+minisignKeyIDSize])
+	if sig.algorithm != minisignHashedAlgorithm {
+		return nil, errors.Errorf("signature algorithm %q is not supported, sign with prehashed mode", sig.algorithm)
+	}
+	return sig, nil
+}
+
+// minisignLines returns the non-empty lines without untrusted comment
+func minisignLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, minisignCommentPrefix) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
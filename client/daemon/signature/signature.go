@@ -0,0 +1,167 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package signature verifies the detached signatures of the downloaded content with the trusted public keys
+package signature
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+var (
+	// ErrUntrusted is returned when the content is not signed by any trusted public key
+	ErrUntrusted = errors.New("content is not signed by trusted keys")
+)
+
+// Verifier verifies the detached signature of the content with the trusted public keys of one signature type
+type Verifier interface {
+	Verify(content io.Reader, signature []byte) error
+}
+
+// Manager fetches the signature of the content and verifies it with the verifier of the signature type
+type Manager interface {
+	// Verify fetches the signature with the header of the content request and verifies the content,
+	// the credentials in header are only sent when the signature is in the same host as the content
+	Verify(ctx context.Context, contentURL string, sig *base.Signature, header map[string]string, content io.Reader) error
+}
+
+type manager struct {
+	verifiers map[string]Verifier
+	maxSize   int64
+}
+
+var _ Manager = (*manager)(nil)
+
+// NewManager creates a signature manager with the public keys in option
+func NewManager(opt config.SignatureOption) (Manager, error) {
+	keys := map[string][][]byte{}
+	for _, key := range opt.PublicKeys {
+		data, err := os.ReadFile(key.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s public key", key.Type)
+		}
+		keys[key.Type] = append(keys[key.Type], data)
+	}
+
+	m := &manager{
+		verifiers: map[string]Verifier{},
+		maxSize:   opt.MaxSize.ToNumber(),
+	}
+	if m.maxSize <= 0 {
+		m.maxSize = config.DefaultSignatureMaxSize.ToNumber()
+	}
+	for typ, data := range keys {
+		verifier, err := NewVerifier(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		m.verifiers[typ] = verifier
+	}
+	return m, nil
+}
+
+// NewVerifier creates the verifier of the signature type with the public keys
+func NewVerifier(typ string, keys [][]byte) (Verifier, error) {
+	switch typ {
+	case config.SignatureTypeMinisign:
+		return newMinisignVerifier(keys)
+	case config.SignatureTypeGPG:
+		return newGPGVerifier(keys)
+	case config.SignatureTypeCosign:
+		return newCosignVerifier(keys)
+	default:
+		return nil, errors.Errorf("not support signature type: %s", typ)
+	}
+}
+
+func (m *manager) Verify(ctx context.Context, contentURL string, sig *base.Signature, header map[string]string, content io.Reader) error {
+	verifier, ok := m.verifiers[sig.Type]
+	if !ok {
+		return errors.Wrapf(ErrUntrusted, "no %s public key is configured", sig.Type)
+	}
+	signature, err := m.fetch(ctx, sig.Url, signatureHeader(contentURL, sig.Url, header))
+	if err != nil {
+		return errors.Wrapf(err, "fetch signature %s", sig.Url)
+	}
+	return verifier.Verify(content, signature)
+}
+
+// credentialHeaders are the headers not sent to the signature in other host, like the redirects of http client
+var credentialHeaders = []string{headers.Authorization, headers.ProxyAuthorization, headers.Cookie}
+
+// signatureHeader returns the header of the content request to fetch the signature, except the range,
+// the signature url is controlled by the caller, so the credentials are dropped when the host is different
+func signatureHeader(contentURL, signatureURL string, header map[string]string) map[string]string {
+	sameHost := false
+	if cu, err := url.Parse(contentURL); err == nil {
+		if su, err := url.Parse(signatureURL); err == nil {
+			sameHost = cu.Scheme == su.Scheme && strings.EqualFold(cu.Host, su.Host)
+		}
+	}
+	hdr := map[string]string{}
+	for k, v := range header {
+		if strings.EqualFold(k, headers.Range) {
+			continue
+		}
+		if !sameHost && isCredentialHeader(k) {
+			continue
+		}
+		hdr[k] = v
+	}
+	return hdr
+}
+
+func isCredentialHeader(key string) bool {
+	for _, h := range credentialHeaders {
+		if strings.EqualFold(key, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch downloads the signature with the giving header
+func (m *manager) fetch(ctx context.Context, url string, header map[string]string) ([]byte, error) {
+	request, err := source.NewRequestWithContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	response, err := source.Download(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, m.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > m.maxSize {
+		return nil, errors.Errorf("signature is larger than %d bytes", m.maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,164 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+var content = []byte("dragonfly signature test content")
+
+func TestVerifier_Minisign(t *testing.T) {
+	assert := testifyassert.New(t)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(err)
+	keyID := []byte("12345678")
+
+	key := fmt.Sprintf("untrusted comment: minisign public key\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(minisignAlgorithm), keyID...), pub...)))
+	sign := func(data []byte, trustedComment string) []byte {
+		digest := blake2b.Sum512(data)
+		sig := ed25519.Sign(priv, digest[:])
+		globalSig := ed25519.Sign(priv, append(append([]byte{}, sig...), trustedComment...))
+		return []byte(fmt.Sprintf("untrusted comment: signature\n%s\n%s%s\n%s\n",
+			base64.StdEncoding.EncodeToString(append(append([]byte(minisignHashedAlgorithm), keyID...), sig...)),
+			minisignTrustedPrefix, trustedComment,
+			base64.StdEncoding.EncodeToString(globalSig)))
+	}
+
+	verifier, err := NewVerifier(config.SignatureTypeMinisign, [][]byte{[]byte(key)})
+	assert.Nil(err)
+
+	assert.Nil(verifier.Verify(bytes.NewReader(content), sign(content, "timestamp:1")))
+	assert.ErrorIs(verifier.Verify(bytes.NewReader([]byte("tampered")), sign(content, "timestamp:1")), ErrUntrusted)
+
+	// tamper the trusted comment
+	signature := bytes.Replace(sign(content, "timestamp:1"), []byte("timestamp:1"), []byte("timestamp:2"), 1)
+	assert.ErrorIs(verifier.Verify(bytes.NewReader(content), signature), ErrUntrusted)
+
+	// signed by unknown key
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	priv = other
+	assert.ErrorIs(verifier.Verify(bytes.NewReader(content), sign(content, "timestamp:1")), ErrUntrusted)
+}
+
+func TestVerifier_GPG(t *testing.T) {
+	assert := testifyassert.New(t)
+	entity, err := openpgp.NewEntity("dragonfly", "", "dragonfly@example.com", nil)
+	assert.Nil(err)
+
+	key := &bytes.Buffer{}
+	assert.Nil(entity.Serialize(key))
+	verifier, err := NewVerifier(config.SignatureTypeGPG, [][]byte{key.Bytes()})
+	assert.Nil(err)
+
+	binarySig := &bytes.Buffer{}
+	assert.Nil(openpgp.DetachSign(binarySig, entity, bytes.NewReader(content), nil))
+	assert.Nil(verifier.Verify(bytes.NewReader(content), binarySig.Bytes()))
+
+	armoredSig := &bytes.Buffer{}
+	assert.Nil(openpgp.ArmoredDetachSign(armoredSig, entity, bytes.NewReader(content), nil))
+	assert.Nil(verifier.Verify(bytes.NewReader(content), armoredSig.Bytes()))
+
+	assert.ErrorIs(verifier.Verify(bytes.NewReader([]byte("tampered")), armoredSig.Bytes()), ErrUntrusted)
+}
+
+func TestVerifier_Cosign(t *testing.T) {
+	assert := testifyassert.New(t)
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.Nil(err)
+	key := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	verifier, err := NewVerifier(config.SignatureTypeCosign, [][]byte{key})
+	assert.Nil(err)
+
+	digest := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	assert.Nil(err)
+	assert.Nil(verifier.Verify(bytes.NewReader(content), []byte(base64.StdEncoding.EncodeToString(sig))))
+	assert.Nil(verifier.Verify(bytes.NewReader(content), sig))
+	assert.ErrorIs(verifier.Verify(bytes.NewReader([]byte("tampered")), sig), ErrUntrusted)
+
+	_, err = NewVerifier(config.SignatureTypeCosign, [][]byte{[]byte("not a key")})
+	assert.NotNil(err)
+}
+
+func TestNewVerifier_UnknownType(t *testing.T) {
+	_, err := NewVerifier("unknown", nil)
+	testifyassert.NotNil(t, err)
+}
+
+func TestSignatureHeader(t *testing.T) {
+	header := map[string]string{
+		"Authorization": "Bearer token",
+		"Cookie":        "session=1",
+		"Range":         "bytes=0-9",
+		"X-Request-Id":  "1",
+	}
+	tests := []struct {
+		name         string
+		contentURL   string
+		signatureURL string
+		expected     map[string]string
+	}{
+		{
+			name:         "same host",
+			contentURL:   "https://example.com/file",
+			signatureURL: "https://EXAMPLE.com/file.sig",
+			expected: map[string]string{
+				"Authorization": "Bearer token",
+				"Cookie":        "session=1",
+				"X-Request-Id":  "1",
+			},
+		},
+		{
+			name:         "other host",
+			contentURL:   "https://example.com/file",
+			signatureURL: "https://attacker.com/file.sig",
+			expected:     map[string]string{"X-Request-Id": "1"},
+		},
+		{
+			name:         "other scheme",
+			contentURL:   "https://example.com/file",
+			signatureURL: "http://example.com/file.sig",
+			expected:     map[string]string{"X-Request-Id": "1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testifyassert.Equal(t, tc.expected, signatureHeader(tc.contentURL, tc.signatureURL, header))
+		})
+	}
+}
@@ -18,6 +18,7 @@ package storage
 
 import (
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -25,6 +26,12 @@ import (
 const (
 	taskData     = "data"
 	taskMetadata = "metadata"
+	// pieceStoreDir is the directory of content addressable piece store in data path,
+	// it starts with dot, so it is skipped when reloading tasks
+	pieceStoreDir = ".pieces"
+
+	// metadataSaveInterval is the minimal interval of saving metadata of unfinished tasks
+	metadataSaveInterval = time.Second
 
 	defaultFileMode      = os.FileMode(0644)
 	defaultDirectoryMode = os.FileMode(0755)
@@ -0,0 +1,388 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+const pieceTempPrefix = ".piece-"
+
+var ErrContentAddressableNotSupported = errors.New("not supported in content addressable storage")
+
+// pieceStore stores piece data keyed by sha256 of the content, pieces with the same content
+// are stored only once and shared between tasks, every task holds a reference of its pieces
+type pieceStore struct {
+	sync.Mutex
+	dataDir string
+	// key: sha256 of piece content, value: reference count
+	refs map[string]int
+}
+
+func newPieceStore(dataDir string) *pieceStore {
+	return &pieceStore{
+		dataDir: dataDir,
+		refs:    map[string]int{},
+	}
+}
+
+func (p *pieceStore) path(digest string) string {
+	return path.Join(p.dataDir, digest[:2], digest)
+}
+
+// write stores data of reader and holds a reference of it for caller,
+// it returns the written bytes and the digest of the content, empty content is not stored
+func (p *pieceStore) write(r io.Reader) (int64, string, error) {
+	if err := os.MkdirAll(p.dataDir, defaultDirectoryMode); err != nil {
+		return 0, "", err
+	}
+	tmp, err := os.CreateTemp(p.dataDir, pieceTempPrefix)
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || n == 0 {
+		return n, "", err
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	dst := p.path(digest)
+	if err = os.MkdirAll(path.Dir(dst), defaultDirectoryMode); err != nil {
+		return n, "", err
+	}
+	// hold lock between checking the content and referencing it, avoid releasing concurrently
+	p.Lock()
+	defer p.Unlock()
+	if _, err = os.Stat(dst); err == nil {
+		logger.Debugf("piece content %s exists, reuse it", digest)
+	} else if err = os.Rename(tmp.Name(), dst); err != nil {
+		return n, "", err
+	}
+	p.refs[digest]++
+	return n, digest, nil
+}
+
+// acquire holds a reference of the existing content, it is used when reloading tasks
+func (p *pieceStore) acquire(digest string) {
+	p.Lock()
+	defer p.Unlock()
+	p.refs[digest]++
+}
+
+// release drops a reference of the content, the content is removed when no task references it
+func (p *pieceStore) release(digest string) error {
+	p.Lock()
+	defer p.Unlock()
+	p.refs[digest]--
+	if p.refs[digest] > 0 {
+		return nil
+	}
+	delete(p.refs, digest)
+	if err := os.Remove(p.path(digest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	logger.Debugf("piece content %s is not referenced, removed", digest)
+	return nil
+}
+
+func (p *pieceStore) open(digest string) (*os.File, error) {
+	return os.Open(p.path(digest))
+}
+
+// prune removes the contents without references and the temporary files, it is used after reloading tasks
+func (p *pieceStore) prune() {
+	p.Lock()
+	defer p.Unlock()
+	dirs, err := os.ReadDir(p.dataDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("read piece store %s error: %s", p.dataDir, err)
+		}
+		return
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			if strings.HasPrefix(dir.Name(), pieceTempPrefix) {
+				_ = os.Remove(path.Join(p.dataDir, dir.Name()))
+			}
+			continue
+		}
+		subDir := path.Join(p.dataDir, dir.Name())
+		files, err := os.ReadDir(subDir)
+		if err != nil {
+			logger.Warnf("read piece store %s error: %s", subDir, err)
+			continue
+		}
+		for _, file := range files {
+			if p.refs[file.Name()] > 0 {
+				continue
+			}
+			if err := os.Remove(path.Join(subDir, file.Name())); err != nil {
+				logger.Warnf("remove unreferenced piece content %s error: %s", file.Name(), err)
+			} else {
+				logger.Infof("remove unreferenced piece content %s", file.Name())
+			}
+		}
+	}
+}
+
+// contentSegment is a part of piece content
+type contentSegment struct {
+	digest string
+	offset int64
+	length int64
+}
+
+// pieceContentReader reads segments in order, the piece content is opened only when reading it
+type pieceContentReader struct {
+	store    *pieceStore
+	segments []contentSegment
+	current  *os.File
+	reader   io.Reader
+}
+
+func (r *pieceContentReader) Read(p []byte) (int, error) {
+	for {
+		if r.reader == nil {
+			if len(r.segments) == 0 {
+				return 0, io.EOF
+			}
+			seg := r.segments[0]
+			r.segments = r.segments[1:]
+			file, err := r.store.open(seg.digest)
+			if err != nil {
+				return 0, err
+			}
+			if _, err = file.Seek(seg.offset, io.SeekStart); err != nil {
+				file.Close()
+				return 0, err
+			}
+			r.current, r.reader = file, io.LimitReader(file, seg.length)
+		}
+		n, err := r.reader.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current, r.reader = nil, nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *pieceContentReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current, r.reader = nil, nil
+	return err
+}
+
+func (t *localTaskStore) writePieceContent(req *WritePieceRequest) (int64, error) {
+	n, digest, err := t.pieceStore.write(io.LimitReader(req.Reader, req.Range.Length))
+	if err != nil {
+		return 0, err
+	}
+	// when UnknownLength and size is align to piece num
+	if req.UnknownLength && n == 0 {
+		return 0, nil
+	}
+	if n != req.Range.Length {
+		if req.UnknownLength {
+			// when back source, and can not detect content length, we need update real length
+			req.Range.Length = n
+		} else {
+			if digest != "" {
+				_ = t.pieceStore.release(digest)
+			}
+			return n, ErrShortRead
+		}
+	}
+	// when Md5 is empty, try to get md5 from reader, it's useful for back source
+	if req.PieceMetadata.Md5 == "" {
+		if get, ok := req.Reader.(digestutils.DigestReader); ok {
+			req.PieceMetadata.Md5 = get.Digest()
+		} else {
+			t.Warnf("reader is not a DigestReader")
+		}
+	}
+	req.PieceMetadata.Sha256 = digest
+	t.Debugf("wrote %d bytes to piece content %s, piece %d, start %d, length: %d",
+		n, digest, req.Num, req.Range.Start, req.Range.Length)
+	t.Lock()
+	// double check
+	if _, ok := t.Pieces[req.Num]; ok {
+		t.Unlock()
+		if err = t.pieceStore.release(digest); err != nil {
+			t.Warnf("release piece content %s error: %s", digest, err)
+		}
+		return n, nil
+	}
+	t.Pieces[req.Num] = req.PieceMetadata
+	t.Unlock()
+	t.trySaveMetadata()
+	return n, nil
+}
+
+func (t *localTaskStore) readPieceContent(req *ReadPieceRequest) (io.Reader, io.Closer, error) {
+	var rg clientutil.Range
+	// If req.Num is equal to -1, range has a fixed value.
+	if req.Num != -1 {
+		t.RLock()
+		piece, ok := t.persistentMetadata.Pieces[req.Num]
+		t.RUnlock()
+		if !ok {
+			t.Errorf("invalid piece num: %d", req.Num)
+			return nil, nil, ErrPieceNotFound
+		}
+		req.Range = piece.Range
+		rg = piece.Range
+	} else {
+		rg = req.Range
+	}
+	segments, err := t.contentSegments(rg)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := &pieceContentReader{store: t.pieceStore, segments: segments}
+	return reader, reader, nil
+}
+
+func (t *localTaskStore) readAllPieceContents() (io.ReadCloser, error) {
+	t.RLock()
+	contentLength := t.ContentLength
+	t.RUnlock()
+	segments, err := t.contentSegments(clientutil.Range{Start: 0, Length: contentLength})
+	if err != nil {
+		return nil, err
+	}
+	return &pieceContentReader{store: t.pieceStore, segments: segments}, nil
+}
+
+// contentSegments returns the segments of piece contents which cover the range in order
+func (t *localTaskStore) contentSegments(rg clientutil.Range) ([]contentSegment, error) {
+	t.RLock()
+	pieces := make([]PieceMetadata, 0, len(t.Pieces))
+	for _, piece := range t.Pieces {
+		pieces = append(pieces, piece)
+	}
+	t.RUnlock()
+	sort.Slice(pieces, func(i, j int) bool {
+		return pieces[i].Range.Start < pieces[j].Range.Start
+	})
+
+	var (
+		segments []contentSegment
+		start    = rg.Start
+		end      = rg.Start + rg.Length
+	)
+	for _, piece := range pieces {
+		if start >= end {
+			break
+		}
+		pieceEnd := piece.Range.Start + piece.Range.Length
+		if pieceEnd <= start {
+			continue
+		}
+		// there is a gap between pieces
+		if piece.Range.Start > start {
+			break
+		}
+		length := pieceEnd - start
+		if end < pieceEnd {
+			length = end - start
+		}
+		segments = append(segments, contentSegment{
+			digest: piece.Sha256,
+			offset: start - piece.Range.Start,
+			length: length,
+		})
+		start += length
+	}
+	if start < end {
+		t.Errorf("piece content not found at offset %d", start)
+		return nil, ErrPieceNotFound
+	}
+	return segments, nil
+}
+
+func (t *localTaskStore) storePieceContents(dst string, mode StoreMode) error {
+	// the pieces are stored separately, they can only be copied in reflink mode
+	if mode == StoreModeHardLink {
+		return errors.Wrap(ErrContentAddressableNotSupported, "link task data")
+	}
+	reader, err := t.readAllPieceContents()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
+	if err != nil {
+		t.Errorf("open tasks destination file error: %s", err)
+		return err
+	}
+	defer dstFile.Close()
+	n, err := io.Copy(dstFile, reader)
+	t.Debugf("copied piece contents %d bytes to %s", n, dst)
+	return err
+}
+
+// releasePieceContents drops the references of all pieces in task
+func (t *localTaskStore) releasePieceContents() error {
+	t.Lock()
+	defer t.Unlock()
+	var errs []string
+	for num, piece := range t.Pieces {
+		if piece.Sha256 == "" {
+			continue
+		}
+		if err := t.pieceStore.release(piece.Sha256); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		piece.Sha256 = ""
+		t.Pieces[num] = piece
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("release piece contents error: %q", strings.Join(errs, "; "))
+	}
+	t.Infof("released piece contents")
+	return nil
+}
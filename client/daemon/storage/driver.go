@@ -0,0 +1,162 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/internal/dfplugin"
+)
+
+// TaskStore is the TaskStorageDriver of one peer task, it is created by TaskStorageDriverBuilder
+// and managed by storage manager, including reloading and gc
+type TaskStore interface {
+	TaskStorageDriver
+	Reclaimer
+
+	// Info returns the summary of the task store
+	Info() *TaskInfo
+
+	// Touch updates the last access time of the task store
+	Touch()
+
+	// IsReclaimMarked indicates whether the task store is marked to be reclaimed
+	IsReclaimMarked() bool
+
+	// Pin updates the pin and priority of the task store, pinned task store is never reclaimed by gc
+	Pin(pinned bool, priority int32) error
+}
+
+// TaskStorageDriverOption is the option for creating or reloading a task store
+type TaskStorageDriverOption struct {
+	RegisterTaskRequest
+
+	// StoreStrategy is the strategy which the builder is registered with
+	StoreStrategy config.StoreStrategy
+
+	// DataDir is the work directory of the peer task, eg: <data path>/<task id>/<peer id>.
+	// The driver must persist a json metadata file named "metadata" in it, which contains
+	// a "storeStrategy" field, storage manager uses it to find the driver when reloading.
+	DataDir string
+
+	// Reload indicates the task store should be restored from DataDir after daemon restarted,
+	// only TaskID and PeerID in RegisterTaskRequest are set when reloading
+	Reload bool
+
+	StorageOption *config.StorageOption
+	GCCallback    GCCallback
+
+	// dataPathStat and pieceStore are shared by local task stores
+	dataPathStat *syscall.Stat_t
+	pieceStore   *pieceStore
+}
+
+// TaskStorageDriverBuilder creates a task store with the giving option
+type TaskStorageDriverBuilder func(opt *TaskStorageDriverOption) (TaskStore, error)
+
+const (
+	// driverPluginMetaKeyStrategy indicates the store strategy of a storage driver plugin
+	driverPluginMetaKeyStrategy = "strategy"
+)
+
+var (
+	driversMutex sync.RWMutex
+	drivers      = map[config.StoreStrategy]TaskStorageDriverBuilder{}
+)
+
+// RegisterDriver registers a task storage driver builder with the store strategy,
+// the builder registered later overrides the former one with the same strategy
+func RegisterDriver(strategy config.StoreStrategy, builder TaskStorageDriverBuilder) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+	drivers[strategy] = builder
+}
+
+// GetDriver returns the task storage driver builder registered with the store strategy
+func GetDriver(strategy config.StoreStrategy) (TaskStorageDriverBuilder, bool) {
+	driversMutex.RLock()
+	defer driversMutex.RUnlock()
+	builder, ok := drivers[strategy]
+	return builder, ok
+}
+
+// LoadDriverPlugin loads the task storage driver plugin named d7y-storage-plugin-<strategy>.so in dir,
+// the plugin must return a func(*TaskStorageDriverOption) (TaskStore, error) and
+// set metadata "strategy" to the store strategy in DragonflyPluginInit
+func LoadDriverPlugin(dir string, strategy config.StoreStrategy) (TaskStorageDriverBuilder, error) {
+	logger.Debugf("try to load storage driver plugin: %s", strategy)
+	client, meta, err := dfplugin.Load(dir, dfplugin.PluginTypeStorage, string(strategy), map[string]string{})
+	if err != nil {
+		logger.Errorf("load storage driver plugin error: %s", err)
+		return nil, err
+	}
+
+	if meta[driverPluginMetaKeyStrategy] != string(strategy) {
+		logger.Errorf("load storage driver plugin error: store strategy not match")
+		return nil, errors.New("store strategy not match")
+	}
+
+	// same as dfplugin, the symbol is not the named type TaskStorageDriverBuilder
+	builder, ok := client.(func(opt *TaskStorageDriverOption) (TaskStore, error))
+	if !ok {
+		logger.Errorf("invalid plugin, not a TaskStorageDriverBuilder")
+		return nil, errors.New("invalid plugin, not a TaskStorageDriverBuilder")
+	}
+
+	logger.Debugf("loaded storage driver plugin %s", strategy)
+	return builder, nil
+}
+
+// loadDriver returns the registered driver builder of the strategy,
+// when not found, tries to load it from plugin directory and registers it
+func loadDriver(pluginDir string, strategy config.StoreStrategy) (TaskStorageDriverBuilder, error) {
+	if builder, ok := GetDriver(strategy); ok {
+		return builder, nil
+	}
+	builder, err := LoadDriverPlugin(pluginDir, strategy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "not support store strategy: %s", strategy)
+	}
+	RegisterDriver(strategy, builder)
+	return builder, nil
+}
+
+// readStoreStrategy reads the store strategy in the metadata file of a task store
+func readStoreStrategy(metadataFilePath string) (config.StoreStrategy, error) {
+	data, err := os.ReadFile(metadataFilePath)
+	if err != nil {
+		return "", err
+	}
+	var meta struct {
+		StoreStrategy string `json:"storeStrategy"`
+	}
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return "", err
+	}
+	// same as NewStorageManager, empty store strategy stands for the simple strategy
+	if meta.StoreStrategy == "" {
+		return config.SimpleLocalTaskStoreStrategy, nil
+	}
+	return config.StoreStrategy(meta.StoreStrategy), nil
+}
@@ -19,17 +19,24 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/atomic"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/util/digestutils"
 )
 
@@ -46,23 +53,168 @@ type localTaskStore struct {
 
 	expireTime    time.Duration
 	lastAccess    atomic.Int64
+	accessCount   atomic.Int64
+	lastSave      atomic.Int64
 	reclaimMarked atomic.Bool
 	gcCallback    func(CommonTaskRequest)
 
 	// when digest not match, invalid will be set
 	invalid atomic.Bool
+
+	// pieceStore is set when using content addressable storage, piece data is stored in it
+	pieceStore *pieceStore
 }
 
 var _ TaskStorageDriver = (*localTaskStore)(nil)
 var _ Reclaimer = (*localTaskStore)(nil)
+var _ TaskStore = (*localTaskStore)(nil)
+
+func init() {
+	for _, strategy := range []config.StoreStrategy{
+		config.SimpleLocalTaskStoreStrategy,
+		config.AdvanceLocalTaskStoreStrategy,
+		config.ContentAddressableLocalTaskStoreStrategy,
+	} {
+		RegisterDriver(strategy, newLocalTaskStore)
+	}
+}
 
-func (t *localTaskStore) touch() {
+func newLocalTaskStore(opt *TaskStorageDriverOption) (TaskStore, error) {
+	if opt.Reload {
+		return reloadLocalTaskStore(opt)
+	}
+	req := opt.RegisterTaskRequest
+	t := &localTaskStore{
+		persistentMetadata: persistentMetadata{
+			StoreStrategy: string(opt.StoreStrategy),
+			TaskID:        req.TaskID,
+			URL:           req.URL,
+			URLMeta:       req.URLMeta,
+			TaskMeta:      map[string]string{},
+			ContentLength: req.ContentLength,
+			TotalPieces:   req.TotalPieces,
+			PieceMd5Sign:  req.PieceMd5Sign,
+			PeerID:        req.PeerID,
+			Pieces:        map[int32]PieceMetadata{},
+			Pinned:        req.Pinned,
+			Priority:      req.Priority,
+		},
+		gcCallback:       opt.GCCallback,
+		dataDir:          opt.DataDir,
+		metadataFilePath: path.Join(opt.DataDir, taskMetadata),
+		expireTime:       opt.StorageOption.TaskExpireTime.Duration,
+
+		SugaredLoggerOnWith: logger.With("task", req.TaskID, "peer", req.PeerID, "component", "localTaskStore"),
+	}
+	if err := os.MkdirAll(t.dataDir, defaultDirectoryMode); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	t.Touch()
+	metadata, err := os.OpenFile(t.metadataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
+	if err != nil {
+		return nil, err
+	}
+	t.metadataFile = metadata
+
+	// fallback to simple strategy for proxy
+	if req.Destination == "" && t.StoreStrategy == string(config.AdvanceLocalTaskStoreStrategy) {
+		t.StoreStrategy = string(config.SimpleLocalTaskStoreStrategy)
+	}
+	data := path.Join(t.dataDir, taskData)
+	switch t.StoreStrategy {
+	case string(config.ContentAddressableLocalTaskStoreStrategy):
+		// piece data is stored in piece store, no task data file
+		t.pieceStore = opt.pieceStore
+	case string(config.AdvanceLocalTaskStoreStrategy):
+		dir, file := path.Split(req.Destination)
+		dirStat, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		t.DataFilePath = path.Join(dir, fmt.Sprintf(".%s.dfget.cache.%s", file, req.PeerID))
+		f, err := os.OpenFile(t.DataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+
+		stat := dirStat.Sys().(*syscall.Stat_t)
+		// same dev, can hard link
+		if stat.Dev == opt.dataPathStat.Dev {
+			logger.Debugf("same device, try to hard link")
+			if err := os.Link(t.DataFilePath, data); err != nil {
+				logger.Warnf("hard link failed for same device: %s, fallback to symbol link", err)
+				// fallback to symbol link
+				if err := os.Symlink(t.DataFilePath, data); err != nil {
+					logger.Errorf("symbol link failed: %s", err)
+					return nil, err
+				}
+			}
+		} else {
+			logger.Debugf("different devices, try to symbol link")
+			// make symbol link for reload error gc
+			if err := os.Symlink(t.DataFilePath, data); err != nil {
+				logger.Errorf("symbol link failed: %s", err)
+				return nil, err
+			}
+		}
+	default:
+		// simple strategy, also used by the drivers registered with other strategies which wrap local task store
+		t.DataFilePath = data
+		f, err := os.OpenFile(t.DataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+	}
+	return t, nil
+}
+
+func reloadLocalTaskStore(opt *TaskStorageDriverOption) (TaskStore, error) {
+	t := &localTaskStore{
+		dataDir:             opt.DataDir,
+		metadataFilePath:    path.Join(opt.DataDir, taskMetadata),
+		expireTime:          opt.StorageOption.TaskExpireTime.Duration,
+		gcCallback:          opt.GCCallback,
+		SugaredLoggerOnWith: logger.With("task", opt.TaskID, "peer", opt.PeerID, "component", opt.StoreStrategy),
+	}
+	t.Touch()
+
+	var err error
+	// open with write mode, the unfinished task will save metadata after resumed
+	if t.metadataFile, err = os.OpenFile(t.metadataFilePath, os.O_RDWR, defaultFileMode); err != nil {
+		return nil, err
+	}
+	bytes, err := io.ReadAll(t.metadataFile)
+	if err != nil {
+		t.metadataFile.Close()
+		return nil, err
+	}
+	if err = json.Unmarshal(bytes, &t.persistentMetadata); err != nil {
+		t.metadataFile.Close()
+		return nil, err
+	}
+	logger.Debugf("load task %s/%s from disk, metadata %s, last access: %s, expire time: %s",
+		t.persistentMetadata.TaskID, t.persistentMetadata.PeerID, t.metadataFilePath, t.lastAccess, t.expireTime)
+	if t.StoreStrategy == string(config.ContentAddressableLocalTaskStoreStrategy) {
+		t.pieceStore = opt.pieceStore
+		for _, piece := range t.Pieces {
+			if piece.Sha256 != "" {
+				t.pieceStore.acquire(piece.Sha256)
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *localTaskStore) Touch() {
 	access := time.Now().UnixNano()
 	t.lastAccess.Store(access)
 }
 
 func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest) (int64, error) {
-	t.touch()
+	t.Touch()
 
 	// piece already exists
 	t.RLock()
@@ -80,6 +232,10 @@ func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest)
 	}
 	t.RUnlock()
 
+	if t.pieceStore != nil {
+		return t.writePieceContent(req)
+	}
+
 	file, err := os.OpenFile(t.DataFilePath, os.O_RDWR, defaultFileMode)
 	if err != nil {
 		return 0, err
@@ -140,17 +296,19 @@ func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest)
 	t.Debugf("wrote %d bytes to file %s, piece %d, start %d, length: %d",
 		n, t.DataFilePath, req.Num, req.Range.Start, req.Range.Length)
 	t.Lock()
-	defer t.Unlock()
 	// double check
 	if _, ok := t.Pieces[req.Num]; ok {
+		t.Unlock()
 		return n, nil
 	}
 	t.Pieces[req.Num] = req.PieceMetadata
+	t.Unlock()
+	t.trySaveMetadata()
 	return n, nil
 }
 
 func (t *localTaskStore) UpdateTask(ctx context.Context, req *UpdateTaskRequest) error {
-	t.touch()
+	t.Touch()
 	t.Lock()
 	defer t.Unlock()
 	t.persistentMetadata.ContentLength = req.ContentLength
@@ -162,6 +320,9 @@ func (t *localTaskStore) UpdateTask(ctx context.Context, req *UpdateTaskRequest)
 		t.PieceMd5Sign = req.PieceMd5Sign
 		t.Debugf("update piece md5 sign: %s", t.PieceMd5Sign)
 	}
+	if req.FileAttributes != nil {
+		t.FileAttributes = req.FileAttributes
+	}
 	if req.GenPieceDigest {
 		var pieceDigests []string
 		for i := int32(0); i < t.TotalPieces; i++ {
@@ -176,6 +337,18 @@ func (t *localTaskStore) UpdateTask(ctx context.Context, req *UpdateTaskRequest)
 }
 
 func (t *localTaskStore) ValidateDigest(*PeerTaskMetadata) error {
+	if err := t.validatePieceMd5Sign(); err != nil {
+		return err
+	}
+	if err := t.validateContentDigest(); err != nil {
+		t.Errorf("invalid content digest: %s", err)
+		t.invalid.Store(true)
+		return err
+	}
+	return nil
+}
+
+func (t *localTaskStore) validatePieceMd5Sign() error {
 	t.Lock()
 	defer t.Unlock()
 	if t.persistentMetadata.PieceMd5Sign == "" {
@@ -202,10 +375,66 @@ func (t *localTaskStore) ValidateDigest(*PeerTaskMetadata) error {
 	return nil
 }
 
+// validateContentDigest checks the whole content with the digest in url meta, like sha512:xxx
+func (t *localTaskStore) validateContentDigest() error {
+	if t.URLMeta == nil || t.URLMeta.Digest == "" {
+		return nil
+	}
+	parsed := digestutils.Parse(t.URLMeta.Digest)
+	if len(parsed) != 2 {
+		return errors.Wrapf(ErrInvalidDigest, "digest %q", t.URLMeta.Digest)
+	}
+	h := digestutils.CreateHash(parsed[0])
+	if h == nil {
+		return errors.Wrapf(ErrInvalidDigest, "digest algorithm %q is not supported", parsed[0])
+	}
+
+	var (
+		content io.ReadCloser
+		err     error
+	)
+	if t.pieceStore != nil {
+		content, err = t.readAllPieceContents()
+	} else {
+		content, err = os.Open(t.DataFilePath)
+	}
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if _, err = io.Copy(h, content); err != nil {
+		return err
+	}
+	if actual := digestutils.ToHashString(h); !strings.EqualFold(actual, parsed[1]) {
+		return errors.Wrapf(ErrInvalidDigest, "%s digest not match, desired: %s, actual: %s", parsed[0], parsed[1], actual)
+	}
+	return nil
+}
+
 func (t *localTaskStore) IsInvalid(*PeerTaskMetadata) (bool, error) {
 	return t.invalid.Load(), nil
 }
 
+func (t *localTaskStore) IsVerified(*PeerTaskMetadata) (bool, error) {
+	t.RLock()
+	defer t.RUnlock()
+	return t.isVerified(), nil
+}
+
+// isVerified must be called with lock
+func (t *localTaskStore) isVerified() bool {
+	return t.URLMeta.GetSignature() == nil || t.Verified
+}
+
+func (t *localTaskStore) MarkVerified(*PeerTaskMetadata) error {
+	t.Lock()
+	t.Verified = true
+	t.Unlock()
+	t.Infof("task signature verified")
+	return t.saveMetadata()
+}
+
 // ReadPiece get a LimitReadCloser from task data with seeked, caller should read bytes and close it.
 func (t *localTaskStore) ReadPiece(ctx context.Context, req *ReadPieceRequest) (io.Reader, io.Closer, error) {
 	if t.invalid.Load() {
@@ -213,7 +442,11 @@ func (t *localTaskStore) ReadPiece(ctx context.Context, req *ReadPieceRequest) (
 		return nil, nil, ErrInvalidDigest
 	}
 
-	t.touch()
+	t.Touch()
+	t.accessCount.Inc()
+	if t.pieceStore != nil {
+		return t.readPieceContent(req)
+	}
 	file, err := os.Open(t.DataFilePath)
 	if err != nil {
 		return nil, nil, err
@@ -248,7 +481,11 @@ func (t *localTaskStore) ReadAllPieces(ctx context.Context, req *PeerTaskMetadat
 		return nil, ErrInvalidDigest
 	}
 
-	t.touch()
+	t.Touch()
+	t.accessCount.Inc()
+	if t.pieceStore != nil {
+		return t.readAllPieceContents()
+	}
 	file, err := os.Open(t.DataFilePath)
 	if err != nil {
 		return nil, err
@@ -265,7 +502,8 @@ func (t *localTaskStore) ReadAllPieces(ctx context.Context, req *PeerTaskMetadat
 func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 	// Store is be called in callback.Done, mark local task store done, for fast search
 	t.Done = true
-	t.touch()
+	t.Touch()
+	t.accessCount.Inc()
 	if req.TotalPieces > 0 {
 		t.Lock()
 		t.TotalPieces = req.TotalPieces
@@ -281,20 +519,101 @@ func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 	if req.MetadataOnly {
 		return nil
 	}
+	if len(req.Outputs) > 0 {
+		return t.storeOutputs(req)
+	}
 	_, err := os.Stat(req.Destination)
 	if err == nil {
 		// remove exist file
 		t.Infof("destination file %q exists, purge it first", req.Destination)
 		os.Remove(req.Destination)
 	}
+	// the file attributes are only applied to copies, the hard link shares the inode with the task data
+	mode, attr := req.Mode, t.fileAttributes(req)
+	if attr != nil {
+		mode = t.unlinkedMode(mode, req.Destination)
+	}
+	if err = t.storeTo(req.Destination, mode); err != nil {
+		return err
+	}
+	if err = clientutil.ApplyFileAttributes(req.Destination, attr, false); err != nil {
+		t.Warnf("apply file attributes error: %s", err)
+	}
+	return nil
+}
+
+// fileAttributes returns the file attributes of the resource which should be applied to the outputs
+func (t *localTaskStore) fileAttributes(req *StoreRequest) *source.FileAttributes {
+	if req.DisableFileAttributes {
+		return nil
+	}
+	t.RLock()
+	defer t.RUnlock()
+	return t.FileAttributes
+}
+
+// storeTo stores the task data to dst with the store mode
+func (t *localTaskStore) storeTo(dst string, mode StoreMode) error {
+	if t.pieceStore != nil {
+		return t.storePieceContents(dst, mode)
+	}
+	switch mode {
+	case StoreModeHardLink:
+		return t.linkTo(dst)
+	case StoreModeReflink:
+		// reflink is only supported by some file systems, fall back to copy like auto mode
+		if err := t.reflinkTo(dst); err == nil {
+			return nil
+		}
+		return t.copyTo(dst)
+	case StoreModeCopy:
+		return t.copyTo(dst)
+	}
 	// 1. try to link
-	err = os.Link(t.DataFilePath, req.Destination)
-	if err == nil {
-		t.Infof("task data link to file %q success", req.Destination)
+	if err := t.linkTo(dst); err == nil {
 		return nil
 	}
-	t.Warnf("task data link to file %q error: %s", req.Destination, err)
 	// 2. link failed, copy it
+	return t.copyTo(dst)
+}
+
+func (t *localTaskStore) linkTo(dst string) error {
+	err := os.Link(t.DataFilePath, dst)
+	if err != nil {
+		t.Warnf("task data link to file %q error: %s", dst, err)
+		return err
+	}
+	t.Infof("task data link to file %q success", dst)
+	return nil
+}
+
+// reflinkFile is reflink, it is replaced in tests
+var reflinkFile = reflink
+
+func (t *localTaskStore) reflinkTo(dst string) error {
+	file, err := os.Open(t.DataFilePath)
+	if err != nil {
+		t.Debugf("open tasks data error: %s", err)
+		return err
+	}
+	defer file.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
+	if err != nil {
+		t.Errorf("open tasks destination file error: %s", err)
+		return err
+	}
+	defer dstFile.Close()
+	if err = reflinkFile(dstFile, file); err != nil {
+		t.Warnf("task data reflink to file %q error: %s", dst, err)
+		os.Remove(dst)
+		return err
+	}
+	t.Infof("task data reflink to file %q success", dst)
+	return nil
+}
+
+func (t *localTaskStore) copyTo(dst string) error {
 	file, err := os.Open(t.DataFilePath)
 	if err != nil {
 		t.Debugf("open tasks data error: %s", err)
@@ -307,7 +626,7 @@ func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 		t.Debugf("task seek file error: %s", err)
 		return err
 	}
-	dstFile, err := os.OpenFile(req.Destination, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
 	if err != nil {
 		t.Errorf("open tasks destination file error: %s", err)
 		return err
@@ -316,7 +635,7 @@ func (t *localTaskStore) Store(ctx context.Context, req *StoreRequest) error {
 	// copy_file_range is valid in linux
 	// https://go-review.googlesource.com/c/go/+/229101/
 	n, err := io.Copy(dstFile, file)
-	t.Debugf("copied tasks data %d bytes to %s", n, req.Destination)
+	t.Debugf("copied tasks data %d bytes to %s", n, dst)
 	return err
 }
 
@@ -328,13 +647,18 @@ func (t *localTaskStore) GetPieces(ctx context.Context, req *base.PieceTaskReque
 
 	t.RLock()
 	defer t.RUnlock()
-	t.touch()
+	if !t.isVerified() {
+		t.Warnf("signature is not verified, refuse to get pieces")
+		return nil, ErrTaskNotVerified
+	}
+	t.Touch()
 	piecePacket := &base.PiecePacket{
-		TaskId:        req.TaskId,
-		DstPid:        t.PeerID,
-		TotalPiece:    t.TotalPieces,
-		ContentLength: t.ContentLength,
-		PieceMd5Sign:  t.PieceMd5Sign,
+		TaskId:         req.TaskId,
+		DstPid:         t.PeerID,
+		TotalPiece:     t.TotalPieces,
+		ContentLength:  t.ContentLength,
+		PieceMd5Sign:   t.PieceMd5Sign,
+		FileAttributes: clientutil.FileAttributesToProto(t.FileAttributes),
 	}
 	if t.TotalPieces > -1 && int32(req.StartNum) >= t.TotalPieces {
 		t.Warnf("invalid start num: %d", req.StartNum)
@@ -354,7 +678,89 @@ func (t *localTaskStore) GetPieces(ctx context.Context, req *base.PieceTaskReque
 	return piecePacket, nil
 }
 
+// partial returns the downloaded pieces of the unfinished task, it returns nil when no piece can be resumed
+func (t *localTaskStore) partial() *PartialPeerTask {
+	t.RLock()
+	defer t.RUnlock()
+	if t.Done || len(t.Pieces) == 0 || (t.TotalPieces > 0 && int32(len(t.Pieces)) >= t.TotalPieces) {
+		return nil
+	}
+	p := &PartialPeerTask{
+		PeerTaskMetadata: PeerTaskMetadata{
+			PeerID: t.PeerID,
+			TaskID: t.TaskID,
+		},
+		ContentLength: t.ContentLength,
+		TotalPieces:   t.TotalPieces,
+		PieceMd5Sign:  t.PieceMd5Sign,
+	}
+	for _, piece := range t.Pieces {
+		p.Pieces = append(p.Pieces, &base.PieceInfo{
+			PieceNum:    piece.Num,
+			RangeStart:  uint64(piece.Range.Start),
+			RangeSize:   uint32(piece.Range.Length),
+			PieceMd5:    piece.Md5,
+			PieceOffset: piece.Offset,
+			PieceStyle:  piece.Style,
+		})
+	}
+	sort.Slice(p.Pieces, func(i, j int) bool {
+		return p.Pieces[i].PieceNum < p.Pieces[j].PieceNum
+	})
+	return p
+}
+
+func (t *localTaskStore) Info() *TaskInfo {
+	t.RLock()
+	defer t.RUnlock()
+	var completedLength int64
+	for _, piece := range t.Pieces {
+		completedLength += piece.Range.Length
+	}
+	return &TaskInfo{
+		PeerTaskMetadata: PeerTaskMetadata{
+			PeerID: t.PeerID,
+			TaskID: t.TaskID,
+		},
+		URL:             t.URL,
+		URLMeta:         t.URLMeta,
+		ContentLength:   t.ContentLength,
+		TotalPieces:     t.TotalPieces,
+		CompletedPieces: int32(len(t.Pieces)),
+		CompletedLength: completedLength,
+		Done:            t.Done,
+		LastAccess:      time.Unix(0, t.lastAccess.Load()),
+		AccessCount:     t.accessCount.Load(),
+		Pinned:          t.Pinned,
+		Priority:        t.Priority,
+	}
+}
+
+func (t *localTaskStore) Pin(pinned bool, priority int32) error {
+	t.Lock()
+	t.Pinned = pinned
+	t.Priority = priority
+	t.Unlock()
+	t.Infof("task pinned: %t, priority: %d", pinned, priority)
+	return t.saveMetadata()
+}
+
+func (t *localTaskStore) IsReclaimMarked() bool {
+	return t.reclaimMarked.Load()
+}
+
 func (t *localTaskStore) CanReclaim() bool {
+	// the data of invalid task store is corrupted, reclaim it even if it is pinned
+	if t.invalid.Load() {
+		t.Infof("reclaim check, task is invalid, reclaim: true")
+		return true
+	}
+	t.RLock()
+	pinned := t.Pinned
+	t.RUnlock()
+	if pinned {
+		return false
+	}
 	access := time.Unix(0, t.lastAccess.Load())
 	reclaim := access.Add(t.expireTime).Before(time.Now())
 	t.Debugf("reclaim check, last access: %v, reclaim: %v", access, reclaim)
@@ -411,6 +817,10 @@ func (t *localTaskStore) Reclaim() error {
 }
 
 func (t *localTaskStore) reclaimData() error {
+	// content addressable storage has no task data file, just release piece contents
+	if t.pieceStore != nil {
+		return t.releasePieceContents()
+	}
 	// remove data
 	data := path.Join(t.dataDir, taskData)
 	stat, err := os.Lstat(data)
@@ -470,6 +880,21 @@ func (t *localTaskStore) saveMetadata() error {
 	_, err = t.metadataFile.Write(data)
 	if err != nil {
 		t.Errorf("save metadata error: %s", err)
+		return err
+	}
+	// metadata may be shorter than the last saved one
+	return t.metadataFile.Truncate(int64(len(data)))
+}
+
+// trySaveMetadata saves metadata of the unfinished task at most once per metadataSaveInterval,
+// then the downloaded pieces can be resumed after daemon restarted
+func (t *localTaskStore) trySaveMetadata() {
+	now := time.Now().UnixNano()
+	last := t.lastSave.Load()
+	if now-last < int64(metadataSaveInterval) || !t.lastSave.CAS(last, now) {
+		return
+	}
+	if err := t.saveMetadata(); err != nil {
+		t.Warnf("save unfinished task metadata error: %s", err)
 	}
-	return err
 }
@@ -26,10 +26,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	testifyassert "github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
@@ -37,6 +40,7 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
 	_ "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
+	"d7y.io/dragonfly/v2/pkg/source"
 )
 
 func TestMain(m *testing.M) {
@@ -211,6 +215,209 @@ func TestLocalTaskStore_StoreTaskData_Simple(t *testing.T) {
 	assert.Equal(testData, bs, "data must match")
 }
 
+func TestLocalTaskStore_StoreTaskData_Modes(t *testing.T) {
+	assert := testifyassert.New(t)
+	src := path.Join(test.DataDir, taskData)
+	dst := path.Join(test.DataDir, taskData+".export")
+	testData := []byte("test data")
+	err := os.WriteFile(src, testData, defaultFileMode)
+	assert.Nil(err, "prepare test data")
+	defer os.Remove(src)
+
+	ts := localTaskStore{
+		SugaredLoggerOnWith: logger.With("test", "localTaskStore"),
+		persistentMetadata: persistentMetadata{
+			TaskID:       "test",
+			DataFilePath: src,
+		},
+		dataDir: test.DataDir,
+	}
+	ts.lastAccess.Store(time.Now().UnixNano())
+
+	for _, tc := range []struct {
+		name     string
+		mode     StoreMode
+		sameFile bool
+	}{
+		{name: "auto", mode: StoreModeAuto, sameFile: true},
+		{name: "hardlink", mode: StoreModeHardLink, sameFile: true},
+		{name: "copy", mode: StoreModeCopy, sameFile: false},
+		{name: "reflink", mode: StoreModeReflink, sameFile: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			defer os.Remove(dst)
+			err := ts.Store(context.Background(), &StoreRequest{
+				CommonTaskRequest: CommonTaskRequest{
+					TaskID:      ts.TaskID,
+					Destination: dst,
+				},
+				StoreOnly: true,
+				Mode:      tc.mode,
+			})
+			assert.Nil(err, "store test data")
+			bs, err := os.ReadFile(dst)
+			assert.Nil(err, "read output test data")
+			assert.Equal(testData, bs, "data must match")
+
+			srcStat, _ := os.Stat(src)
+			dstStat, _ := os.Stat(dst)
+			assert.Equal(tc.sameFile, os.SameFile(srcStat, dstStat))
+		})
+	}
+}
+
+func TestLocalTaskStore_StoreTaskData_ReflinkFallback(t *testing.T) {
+	assert := testifyassert.New(t)
+	src := path.Join(test.DataDir, taskData)
+	dst := path.Join(test.DataDir, taskData+".reflink")
+	testData := []byte("test data")
+	err := os.WriteFile(src, testData, defaultFileMode)
+	assert.Nil(err, "prepare test data")
+	defer os.Remove(src)
+	defer os.Remove(dst)
+
+	// the file system does not support reflink
+	var reflinked bool
+	reflinkFile = func(dst, src *os.File) error {
+		reflinked = true
+		return errors.New("reflink is not supported")
+	}
+	defer func() { reflinkFile = reflink }()
+
+	ts := localTaskStore{
+		SugaredLoggerOnWith: logger.With("test", "localTaskStore"),
+		persistentMetadata: persistentMetadata{
+			TaskID:       "test",
+			DataFilePath: src,
+		},
+		dataDir: test.DataDir,
+	}
+	ts.lastAccess.Store(time.Now().UnixNano())
+	err = ts.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			TaskID:      ts.TaskID,
+			Destination: dst,
+		},
+		StoreOnly: true,
+		Mode:      StoreModeReflink,
+	})
+	assert.Nil(err, "store test data")
+	assert.True(reflinked, "reflink is tried first")
+	bs, err := os.ReadFile(dst)
+	assert.Nil(err, "read output test data")
+	assert.Equal(testData, bs, "data must match")
+}
+
+func TestLocalTaskStore_StoreTaskData_OutputAttributes(t *testing.T) {
+	src := path.Join(test.DataDir, taskData)
+	testData := []byte("test data")
+	if err := os.WriteFile(src, testData, defaultFileMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src)
+
+	ts := localTaskStore{
+		SugaredLoggerOnWith: logger.With("test", "localTaskStore"),
+		persistentMetadata: persistentMetadata{
+			TaskID:       "test",
+			DataFilePath: src,
+		},
+		dataDir: test.DataDir,
+	}
+	ts.lastAccess.Store(time.Now().UnixNano())
+	srcStat, _ := os.Stat(src)
+	srcSys := srcStat.Sys().(*syscall.Stat_t)
+	uid, otherGid := int(srcSys.Uid), int(srcSys.Gid)+1
+
+	for _, tc := range []struct {
+		name     string
+		output   StoreOutput
+		sameFile bool
+	}{
+		{name: "default", output: StoreOutput{}, sameFile: true},
+		{name: "same permission and owner", output: StoreOutput{Perm: defaultFileMode, Uid: &uid}, sameFile: true},
+		{name: "other permission", output: StoreOutput{Perm: 0600}, sameFile: false},
+		{name: "other group", output: StoreOutput{Gid: &otherGid}, sameFile: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.output.Gid != nil && os.Getuid() != 0 {
+				t.Skip("change group requires root")
+			}
+			assert := testifyassert.New(t)
+			dst := path.Join(test.DataDir, taskData+".output")
+			defer os.Remove(dst)
+			tc.output.Path = dst
+			err := ts.Store(context.Background(), &StoreRequest{
+				CommonTaskRequest: CommonTaskRequest{
+					TaskID: ts.TaskID,
+				},
+				StoreOnly: true,
+				Outputs:   []StoreOutput{tc.output},
+			})
+			assert.Nil(err, "store test data")
+
+			dstStat, _ := os.Stat(dst)
+			assert.Equal(tc.sameFile, os.SameFile(srcStat, dstStat), "output with other owner or permission is not hard linked")
+			// the task data is never changed by outputs
+			stat, _ := os.Stat(src)
+			assert.Equal(srcStat.Mode(), stat.Mode())
+			assert.Equal(srcSys.Gid, stat.Sys().(*syscall.Stat_t).Gid)
+		})
+	}
+}
+
+func TestLocalTaskStore_StoreTaskData_FileAttributes(t *testing.T) {
+	assert := testifyassert.New(t)
+	src := path.Join(test.DataDir, taskData)
+	dst := path.Join(test.DataDir, taskData+".output")
+	if err := os.WriteFile(src, []byte("test data"), defaultFileMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src)
+	defer os.Remove(dst)
+
+	lastModified := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	ts := localTaskStore{
+		SugaredLoggerOnWith: logger.With("test", "localTaskStore"),
+		persistentMetadata: persistentMetadata{
+			TaskID:       "test",
+			DataFilePath: src,
+			FileAttributes: &source.FileAttributes{
+				Mode:         0777,
+				LastModified: lastModified,
+			},
+		},
+		dataDir: test.DataDir,
+	}
+	ts.lastAccess.Store(time.Now().UnixNano())
+	srcStat, _ := os.Stat(src)
+
+	err := ts.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			TaskID:      ts.TaskID,
+			Destination: dst,
+		},
+		StoreOnly: true,
+		Mode:      StoreModeHardLink,
+	})
+	assert.Nil(err, "store test data")
+
+	// the file attributes are applied to the copy, the task data is never changed
+	dstStat, _ := os.Stat(dst)
+	assert.False(os.SameFile(srcStat, dstStat))
+	assert.Equal(os.FileMode(0777), dstStat.Mode().Perm())
+	assert.Equal(lastModified, dstStat.ModTime().UnixNano()/int64(time.Millisecond))
+	stat, _ := os.Stat(src)
+	assert.Equal(srcStat.Mode(), stat.Mode())
+	assert.Equal(srcStat.ModTime(), stat.ModTime())
+
+	// the file attributes are sent to other peers with pieces
+	piecePacket, err := ts.GetPieces(context.Background(), &base.PieceTaskRequest{TaskId: ts.TaskID})
+	assert.Nil(err)
+	assert.Equal(&base.FileAttributes{Mode: 0777, LastModified: lastModified}, piecePacket.FileAttributes)
+}
+
 func TestLocalTaskStore_ReloadPersistentTask_Simple(t *testing.T) {
 
 }
@@ -350,6 +557,193 @@ func TestLocalTaskStore_ReloadPersistentTask_Advance(t *testing.T) {
 
 }
 
+func TestStorageManager_ListAndDeleteTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		taskID = "task-d4bb1c273a9889fea14abd4651994fe8"
+		peers  = []string{"peer-1", "peer-2"}
+	)
+	for _, peerID := range peers {
+		err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+			URL:           "http://example.com/test",
+			URLMeta:       &base.UrlMeta{Tag: "test"},
+			ContentLength: 1024,
+			TotalPieces:   2,
+		})
+		assert.Nil(err, "register task")
+	}
+
+	infos := sm.ListTasks()
+	assert.Len(infos, 2)
+	for i, info := range infos {
+		assert.Equal(taskID, info.TaskID)
+		assert.Equal(peers[i], info.PeerID)
+		assert.Equal("http://example.com/test", info.URL)
+		assert.Equal("test", info.URLMeta.Tag)
+		assert.Equal(int64(1024), info.ContentLength)
+		assert.Equal(int32(2), info.TotalPieces)
+		assert.False(info.Done)
+	}
+	assert.Len(sm.FindTasks(taskID), 2)
+	assert.Len(sm.FindTasks("unknown"), 0)
+
+	assert.Nil(sm.DeleteTask(taskID), "delete task")
+	assert.Len(sm.ListTasks(), 0)
+	assert.Len(sm.FindTasks(taskID), 0)
+	_, err = os.Stat(path.Join(dataDir, taskID, peers[0]))
+	assert.True(os.IsNotExist(err), "task data should be removed")
+	assert.Equal(ErrTaskNotFound, sm.DeleteTask(taskID))
+}
+
+func TestStorageManager_ContentAddressable(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	opt := &config.StorageOption{
+		DataPath: dataDir,
+		TaskExpireTime: clientutil.Duration{
+			Duration: time.Minute,
+		},
+	}
+	sm, err := NewStorageManager(config.ContentAddressableLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		testData  = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+		pieceSize = 16
+		peerID    = "peer-d4bb1c273a9889fea14abd4651994fe8"
+		taskIDs   = []string{"task-1", "task-2"}
+	)
+	for _, taskID := range taskIDs {
+		err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+			ContentLength: int64(len(testData)),
+		})
+		assert.Nil(err, "register task")
+		var num int32
+		for start := 0; start < len(testData); start += pieceSize {
+			end := start + pieceSize
+			if end > len(testData) {
+				end = len(testData)
+			}
+			_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+				PeerTaskMetadata: PeerTaskMetadata{
+					PeerID: peerID,
+					TaskID: taskID,
+				},
+				PieceMetadata: PieceMetadata{
+					Num: num,
+					Range: clientutil.Range{
+						Start:  int64(start),
+						Length: int64(end - start),
+					},
+				},
+				Reader: bytes.NewBuffer(testData[start:end]),
+			})
+			assert.Nil(err, "write piece")
+			num++
+		}
+		err = sm.Store(context.Background(), &StoreRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+			MetadataOnly: true,
+			TotalPieces:  num,
+		})
+		assert.Nil(err, "store task metadata")
+	}
+
+	countPieceContents := func() int {
+		var count int
+		_ = filepath.Walk(path.Join(dataDir, pieceStoreDir), func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				count++
+			}
+			return nil
+		})
+		return count
+	}
+	// identical pieces are stored only once
+	assert.Equal(3, countPieceContents())
+
+	rc, err := sm.ReadAllPieces(context.Background(), &PeerTaskMetadata{PeerID: peerID, TaskID: taskIDs[1]})
+	assert.Nil(err, "read all pieces")
+	data, err := io.ReadAll(rc)
+	assert.Nil(err, "read all pieces")
+	rc.Close()
+	assert.Equal(testData, data)
+
+	// read range across pieces
+	r, c, err := sm.ReadPiece(context.Background(), &ReadPieceRequest{
+		PeerTaskMetadata: PeerTaskMetadata{PeerID: peerID, TaskID: taskIDs[0]},
+		PieceMetadata: PieceMetadata{
+			Num:   -1,
+			Range: clientutil.Range{Start: 10, Length: 20},
+		},
+	})
+	assert.Nil(err, "read range")
+	data, err = io.ReadAll(r)
+	assert.Nil(err, "read range")
+	c.Close()
+	assert.Equal(testData[10:30], data)
+
+	dst := path.Join(dataDir, "output")
+	err = sm.Store(context.Background(), &StoreRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID:      peerID,
+			TaskID:      taskIDs[0],
+			Destination: dst,
+		},
+		StoreOnly: true,
+	})
+	assert.Nil(err, "store task data")
+	data, err = os.ReadFile(dst)
+	assert.Nil(err, "read output")
+	assert.Equal(testData, data)
+
+	// reload from disk, references are restored
+	sm, err = NewStorageManager(config.ContentAddressableLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(sm.ListTasks(), 2)
+	assert.Equal(3, countPieceContents())
+
+	// shared pieces are kept until the last task is deleted
+	assert.Nil(sm.DeleteTask(taskIDs[0]), "delete task")
+	assert.Equal(3, countPieceContents())
+	assert.Nil(sm.DeleteTask(taskIDs[1]), "delete task")
+	assert.Equal(0, countPieceContents())
+}
+
 func calcFileMd5(filePath string) (string, error) {
 	var md5String string
 	file, err := os.Open(filePath)
@@ -366,3 +760,396 @@ func calcFileMd5(filePath string) (string, error) {
 	md5String = hex.EncodeToString(hashInBytes)
 	return md5String, nil
 }
+
+func TestStorageManager_FindPartialTask(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	opt := &config.StorageOption{
+		DataPath: dataDir,
+		TaskExpireTime: clientutil.Duration{
+			Duration: time.Minute,
+		},
+	}
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		testData = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+		peerID   = "peer-d4bb1c273a9889fea14abd4651994fe8"
+		taskID   = "task-d4bb1c273a9889fea14abd4651994fe8"
+	)
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: peerID,
+			TaskID: taskID,
+		},
+		ContentLength: int64(len(testData)),
+		TotalPieces:   2,
+	})
+	assert.Nil(err, "register task")
+	assert.Nil(sm.FindPartialTask(taskID), "no piece downloaded")
+
+	// only the first piece is downloaded before the daemon exits
+	_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: PeerTaskMetadata{
+			PeerID: peerID,
+			TaskID: taskID,
+		},
+		PieceMetadata: PieceMetadata{
+			Num: 0,
+			Range: clientutil.Range{
+				Start:  0,
+				Length: 16,
+			},
+		},
+		Reader: bytes.NewBuffer(testData[:16]),
+	})
+	assert.Nil(err, "write piece")
+
+	partial := sm.FindPartialTask(taskID)
+	if assert.NotNil(partial, "find partial task") {
+		assert.Equal(peerID, partial.PeerID)
+		assert.Equal(int32(2), partial.TotalPieces)
+		assert.Equal(1, len(partial.Pieces))
+		assert.Equal(uint32(16), partial.Pieces[0].RangeSize)
+	}
+
+	// the downloaded pieces are still found after reloading
+	sm, err = NewStorageManager(config.SimpleLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial = sm.FindPartialTask(taskID)
+	if assert.NotNil(partial, "find partial task after reload") {
+		assert.Equal(peerID, partial.PeerID)
+		assert.Equal(int64(len(testData)), partial.ContentLength)
+		assert.Equal(1, len(partial.Pieces))
+	}
+}
+
+func TestStorageManager_RegisterDriver(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	opt := &config.StorageOption{
+		DataPath: dataDir,
+		TaskExpireTime: clientutil.Duration{
+			Duration: time.Minute,
+		},
+	}
+	_, err = NewStorageManager("unknown", opt, func(request CommonTaskRequest) {}, WithPluginDir(dataDir))
+	assert.NotNil(err, "not registered and no plugin")
+
+	var (
+		strategy = config.StoreStrategy("io.d7y.storage.v2.test")
+		created  int
+		reloaded int
+	)
+	RegisterDriver(strategy, func(opt *TaskStorageDriverOption) (TaskStore, error) {
+		if opt.Reload {
+			reloaded++
+		} else {
+			created++
+		}
+		return newLocalTaskStore(opt)
+	})
+	sm, err := NewStorageManager(strategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		testData = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+		peerID   = "peer-d4bb1c273a9889fea14abd4651994fe8"
+		taskID   = "task-d4bb1c273a9889fea14abd4651994fe8"
+	)
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: peerID,
+			TaskID: taskID,
+		},
+		ContentLength: int64(len(testData)),
+		TotalPieces:   1,
+	})
+	assert.Nil(err, "register task")
+	_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: PeerTaskMetadata{
+			PeerID: peerID,
+			TaskID: taskID,
+		},
+		PieceMetadata: PieceMetadata{
+			Num: 0,
+			Range: clientutil.Range{
+				Start:  0,
+				Length: int64(len(testData)),
+			},
+		},
+		Reader: bytes.NewBuffer(testData),
+	})
+	assert.Nil(err, "write piece")
+	assert.Equal(1, created)
+
+	// task store is reloaded by the driver which created it, even if the store strategy is changed
+	sm, err = NewStorageManager(config.SimpleLocalTaskStoreStrategy, opt, func(request CommonTaskRequest) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(1, reloaded)
+	assert.Equal(1, len(sm.FindTasks(taskID)))
+}
+
+func TestStorageManager_PinAndQuota(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+			DiskQuota: 3 * 1024,
+			GCPolicy:  config.LRUGCPolicy,
+		}, func(request CommonTaskRequest) {
+		}, WithGCInterval(time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	register := func(taskID string, pin bool) error {
+		return sm.RegisterTask(context.Background(), RegisterTaskRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: "peer-" + taskID,
+				TaskID: taskID,
+			},
+			URL:           "http://example.com/" + taskID,
+			ContentLength: 1024,
+			TotalPieces:   1,
+			Pinned:        pin,
+		})
+	}
+	reclaimMarked := func(taskID string) bool {
+		t, ok := sm.(*storageManager).LoadTask(PeerTaskMetadata{PeerID: "peer-" + taskID, TaskID: taskID})
+		return ok && t.(TaskStore).IsReclaimMarked()
+	}
+
+	assert.Nil(register("task-1", true), "register pinned task")
+	assert.Nil(register("task-2", false), "register task")
+	assert.Nil(register("task-3", false), "register task")
+	assert.True(sm.FindTasks("task-1")[0].Pinned)
+
+	// task-3 has lower priority than task-2, it is reclaimed first
+	assert.Nil(sm.PinTask(PinTaskRequest{TaskID: "task-2", Priority: 1}))
+	assert.Equal(ErrTaskNotFound, sm.PinTask(PinTaskRequest{TaskID: "unknown"}))
+
+	assert.Nil(register("task-4", true), "register task exceeding quota")
+	assert.False(reclaimMarked("task-1"), "pinned task should not be reclaimed")
+	assert.False(reclaimMarked("task-2"), "task with higher priority should not be reclaimed")
+	assert.True(reclaimMarked("task-3"), "task should be marked reclaimed")
+	// the marked task may be still in use, it is reclaimed in next gc loop
+	assert.Len(sm.FindTasks("task-3"), 1)
+	for i := 0; i < 2; i++ {
+		_, err = sm.(*storageManager).TryGC()
+		assert.Nil(err)
+	}
+	assert.Len(sm.FindTasks("task-3"), 0, "task should be reclaimed")
+
+	assert.Nil(sm.PinTask(PinTaskRequest{TaskID: "task-2", Pinned: true}))
+	err = register("task-5", false)
+	assert.ErrorIs(err, ErrDiskQuotaExceeded, "all tasks are pinned")
+	assert.Len(sm.ListTasks(), 3)
+
+	// the content length is unknown when registered, the quota is checked when writing pieces
+	assert.Nil(sm.PinTask(PinTaskRequest{TaskID: "task-2"}))
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: "peer-task-6",
+			TaskID: "task-6",
+		},
+		ContentLength: -1,
+		TotalPieces:   -1,
+	})
+	assert.Nil(err, "register task with unknown length")
+	writePiece := func(num int32) error {
+		_, err := sm.WritePiece(context.Background(), &WritePieceRequest{
+			PeerTaskMetadata: PeerTaskMetadata{PeerID: "peer-task-6", TaskID: "task-6"},
+			PieceMetadata: PieceMetadata{
+				Num:   num,
+				Range: clientutil.Range{Start: int64(num) * 1024, Length: 1024},
+			},
+			Reader: bytes.NewBuffer(make([]byte, 1024)),
+		})
+		return err
+	}
+	assert.Nil(writePiece(0), "write piece exceeding quota")
+	assert.True(reclaimMarked("task-2"), "task should be marked reclaimed")
+	assert.ErrorIs(writePiece(1), ErrDiskQuotaExceeded, "all other tasks are pinned")
+
+	// no task is marked when the candidates can not cover the exceeded bytes
+	assert.Nil(sm.PinTask(PinTaskRequest{TaskID: "task-4"}))
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: "peer-task-7",
+			TaskID: "task-7",
+		},
+		ContentLength: 4 * 1024,
+		TotalPieces:   4,
+	})
+	assert.ErrorIs(err, ErrDiskQuotaExceeded, "candidates are not enough")
+	assert.False(reclaimMarked("task-4"), "task should not be marked when refused")
+	assert.False(reclaimMarked("task-6"), "task should not be marked when refused")
+}
+
+func TestStorageManager_ScrubTasks(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		testData = []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+		peerID   = "peer-d4bb1c273a9889fea14abd4651994fe8"
+		taskID   = "task-d4bb1c273a9889fea14abd4651994fe8"
+		meta     = PeerTaskMetadata{PeerID: peerID, TaskID: taskID}
+	)
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: peerID,
+			TaskID: taskID,
+		},
+		ContentLength: int64(len(testData)),
+		TotalPieces:   2,
+	})
+	assert.Nil(err, "register task")
+	for i, rg := range []clientutil.Range{{Start: 0, Length: 16}, {Start: 16, Length: 20}} {
+		data := testData[rg.Start : rg.Start+rg.Length]
+		digest := md5.Sum(data)
+		_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+			PeerTaskMetadata: meta,
+			PieceMetadata: PieceMetadata{
+				Num:   int32(i),
+				Md5:   hex.EncodeToString(digest[:]),
+				Range: rg,
+			},
+			Reader: bytes.NewBuffer(data),
+		})
+		assert.Nil(err, "write piece")
+	}
+	err = sm.UpdateTask(context.Background(), &UpdateTaskRequest{
+		PeerTaskMetadata: meta,
+		ContentLength:    int64(len(testData)),
+		TotalPieces:      2,
+		GenPieceDigest:   true,
+	})
+	assert.Nil(err, "update task")
+
+	limiter := rate.NewLimiter(rate.Limit(1024), 16)
+	ts, _ := sm.(*storageManager).tasks.Load(meta)
+	lt := ts.(*localTaskStore)
+
+	// unfinished task is not scrubbed
+	corrupted, err := sm.ScrubTasks(context.Background(), limiter)
+	assert.Nil(err)
+	assert.Equal(0, corrupted)
+
+	lt.Done = true
+	corrupted, err = sm.ScrubTasks(context.Background(), limiter)
+	assert.Nil(err)
+	assert.Equal(0, corrupted)
+	invalid, _ := sm.IsInvalid(&meta)
+	assert.False(invalid)
+
+	// flip one byte in the second piece
+	file, err := os.OpenFile(lt.DataFilePath, os.O_WRONLY, 0)
+	assert.Nil(err, "open data file")
+	_, err = file.WriteAt([]byte("Z"), 20)
+	assert.Nil(err, "corrupt data file")
+	file.Close()
+
+	corrupted, err = sm.ScrubTasks(context.Background(), limiter)
+	assert.Nil(err)
+	assert.Equal(1, corrupted)
+	invalid, _ = sm.IsInvalid(&meta)
+	assert.True(invalid, "corrupted task should be invalid")
+	assert.True(lt.CanReclaim(), "corrupted task should be reclaimed")
+	_, err = sm.ReadAllPieces(context.Background(), &meta)
+	assert.Equal(ErrInvalidDigest, err)
+}
+
+func TestLocalTaskStore_SignatureVerified(t *testing.T) {
+	assert := testifyassert.New(t)
+	dataDir, err := os.MkdirTemp("", "dragonfly-storage-test-")
+	assert.Nil(err, "create data dir")
+	defer os.RemoveAll(dataDir)
+
+	sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: dataDir,
+			TaskExpireTime: clientutil.Duration{
+				Duration: time.Minute,
+			},
+		}, func(request CommonTaskRequest) {
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := PeerTaskMetadata{PeerID: "peer-signed", TaskID: "task-signed"}
+	err = sm.RegisterTask(context.Background(), RegisterTaskRequest{
+		CommonTaskRequest: CommonTaskRequest{
+			PeerID: meta.PeerID,
+			TaskID: meta.TaskID,
+		},
+		URLMeta: &base.UrlMeta{
+			Signature: &base.Signature{Type: config.SignatureTypeMinisign, Url: "http://localhost/test.minisig"},
+		},
+		ContentLength: 10,
+		TotalPieces:   1,
+	})
+	assert.Nil(err, "register task")
+	_, err = sm.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: meta,
+		PieceMetadata: PieceMetadata{
+			Num:   0,
+			Range: clientutil.Range{Start: 0, Length: 10},
+		},
+		Reader: bytes.NewBuffer([]byte("0123456789")),
+	})
+	assert.Nil(err, "write piece")
+
+	request := &base.PieceTaskRequest{TaskId: meta.TaskID, DstPid: meta.PeerID, Limit: 1}
+	verified, err := sm.IsVerified(&meta)
+	assert.Nil(err)
+	assert.False(verified)
+	_, err = sm.GetPieces(context.Background(), request)
+	assert.ErrorIs(err, ErrTaskNotVerified, "unverified pieces should not be shared")
+
+	assert.Nil(sm.MarkVerified(&meta))
+	verified, err = sm.IsVerified(&meta)
+	assert.Nil(err)
+	assert.True(verified)
+	packet, err := sm.GetPieces(context.Background(), request)
+	assert.Nil(err)
+	assert.Len(packet.PieceInfos, 1)
+}
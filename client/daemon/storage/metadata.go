@@ -18,14 +18,19 @@ package storage
 
 import (
 	"io"
+	"os"
+	"time"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/source"
 )
 
 type persistentMetadata struct {
 	StoreStrategy string                  `json:"storeStrategy"`
 	TaskID        string                  `json:"taskID"`
+	URL           string                  `json:"url"`
+	URLMeta       *base.UrlMeta           `json:"urlMeta"`
 	TaskMeta      map[string]string       `json:"taskMeta"`
 	ContentLength int64                   `json:"contentLength"`
 	TotalPieces   int32                   `json:"totalPieces"`
@@ -34,6 +39,14 @@ type persistentMetadata struct {
 	PieceMd5Sign  string                  `json:"pieceMd5Sign"`
 	DataFilePath  string                  `json:"dataFilePath"`
 	Done          bool                    `json:"done"`
+	// Pinned task is never reclaimed by gc
+	Pinned bool `json:"pinned,omitempty"`
+	// Priority is compared before gc policy, task with lower priority is reclaimed first
+	Priority int32 `json:"priority,omitempty"`
+	// FileAttributes are the attributes of the resource when downloaded from source, applied to the outputs
+	FileAttributes *source.FileAttributes `json:"fileAttributes,omitempty"`
+	// Verified indicates the signature in URLMeta is verified, the task with signature is shared after verified
+	Verified bool `json:"verified,omitempty"`
 }
 
 type PeerTaskMetadata struct {
@@ -47,6 +60,8 @@ type PieceMetadata struct {
 	Offset uint64           `json:"offset,omitempty"`
 	Range  clientutil.Range `json:"range,omitempty"`
 	Style  base.PieceStyle  `json:"style,omitempty"`
+	// Sha256 is the key of piece content in content addressable storage
+	Sha256 string `json:"sha256,omitempty"`
 }
 
 type CommonTaskRequest struct {
@@ -57,9 +72,14 @@ type CommonTaskRequest struct {
 
 type RegisterTaskRequest struct {
 	CommonTaskRequest
+	URL           string
+	URLMeta       *base.UrlMeta
 	ContentLength int64
 	TotalPieces   int32
 	PieceMd5Sign  string
+	// Pinned and Priority are the gc policy of the task in local storage
+	Pinned   bool
+	Priority int32
 }
 
 type WritePieceRequest struct {
@@ -69,11 +89,39 @@ type WritePieceRequest struct {
 	Reader        io.Reader
 }
 
+// StoreMode stands how task data is stored to the destination
+type StoreMode int
+
+const (
+	// StoreModeAuto tries hard link first, falls back to copy
+	StoreModeAuto StoreMode = iota
+	StoreModeHardLink
+	// StoreModeReflink clones data with copy on write, falls back to copy when the file system
+	// does not support it
+	StoreModeReflink
+	StoreModeCopy
+)
+
 type StoreRequest struct {
 	CommonTaskRequest
 	MetadataOnly bool
 	StoreOnly    bool
 	TotalPieces  int32
+	Mode         StoreMode
+	// Outputs are materialized atomically instead of Destination when not empty
+	Outputs []StoreOutput
+	// DisableFileAttributes does not apply the file attributes of the resource to the outputs
+	DisableFileAttributes bool
+}
+
+// StoreOutput is one destination of the task data with its own owner and permission
+type StoreOutput struct {
+	Path string
+	// Uid and Gid change the owner of the output when they are set
+	Uid *int
+	Gid *int
+	// Perm changes the permission of the output when it is not 0
+	Perm os.FileMode
 }
 
 type ReadPieceRequest struct {
@@ -88,6 +136,45 @@ type UpdateTaskRequest struct {
 	PieceMd5Sign  string
 	// GenPieceDigest is used when back source
 	GenPieceDigest bool
+	// FileAttributes are the attributes of the resource, only known when back source
+	FileAttributes *source.FileAttributes
 }
 
 type ReusePeerTask = UpdateTaskRequest
+
+// PartialPeerTask is an unfinished peer task in local storage, it can be resumed with the downloaded pieces
+type PartialPeerTask struct {
+	PeerTaskMetadata
+	ContentLength int64
+	TotalPieces   int32
+	PieceMd5Sign  string
+	// Pieces are the downloaded pieces sorted by piece number
+	Pieces []*base.PieceInfo
+}
+
+// TaskInfo is a snapshot of one local peer task store
+type TaskInfo struct {
+	PeerTaskMetadata
+	URL             string
+	URLMeta         *base.UrlMeta
+	ContentLength   int64
+	TotalPieces     int32
+	CompletedPieces int32
+	// CompletedLength is the total length of the completed pieces
+	CompletedLength int64
+	Done            bool
+	LastAccess      time.Time
+	// AccessCount is the count of reading the task data since the task store is loaded
+	AccessCount int64
+	Pinned      bool
+	Priority    int32
+}
+
+type PinTaskRequest struct {
+	TaskID   string
+	Pinned   bool
+	Priority int32
+	// Merge only pins the task, and only updates the priority when it is not zero,
+	// it is used by downloads which never unpin the task
+	Merge bool
+}
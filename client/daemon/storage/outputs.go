@@ -0,0 +1,218 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+// storeOutputs materializes the task data to all outputs, the first group of outputs is stored with the store mode,
+// the other groups are copied, because the outputs linked to one inode share the owner and permission.
+// The first group is never hard linked to the task data when its owner, permission or file attributes are changed,
+// otherwise the task data in local storage is changed too
+func (t *localTaskStore) storeOutputs(req *StoreRequest) error {
+	mode, attr := req.Mode, t.fileAttributes(req)
+	if len(req.Outputs) > 0 && (attr != nil || t.outputChangesData(req.Outputs[0])) {
+		mode = t.unlinkedMode(mode, req.Outputs[0].Path)
+	}
+	err := materializeOutputs(req.Outputs, func(dst string, first bool) error {
+		if first {
+			return t.storeTo(dst, mode)
+		}
+		return t.storeTo(dst, StoreModeCopy)
+	}, attr)
+	if err != nil {
+		t.Errorf("store task data to %d outputs error: %s", len(req.Outputs), err)
+		return err
+	}
+	t.Infof("store task data to %d outputs success", len(req.Outputs))
+	return nil
+}
+
+// unlinkedMode returns the store mode which never hard links the task data to dst, it is used when dst is changed
+// after stored, reflink falls back to copy when the file system does not support it
+func (t *localTaskStore) unlinkedMode(mode StoreMode, dst string) StoreMode {
+	if mode != StoreModeAuto && mode != StoreModeHardLink {
+		return mode
+	}
+	t.Infof("attributes of %s are changed, reflink or copy it instead of hard link", dst)
+	return StoreModeReflink
+}
+
+// outputChangesData returns whether applying the owner and permission of the output changes the task data file
+func (t *localTaskStore) outputChangesData(output StoreOutput) bool {
+	info, err := os.Stat(t.DataFilePath)
+	if err != nil {
+		return true
+	}
+	if output.Perm != 0 && output.Perm != info.Mode().Perm() {
+		return true
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return output.Uid != nil || output.Gid != nil
+	}
+	return (output.Uid != nil && uint32(*output.Uid) != stat.Uid) || (output.Gid != nil && uint32(*output.Gid) != stat.Gid)
+}
+
+// StoreContentToOutputs materializes the content to all outputs, it is used by the content not kept in local storage
+func StoreContentToOutputs(content []byte, outputs []StoreOutput) error {
+	return materializeOutputs(outputs, func(dst string, _ bool) error {
+		return copyToFile(bytes.NewReader(content), dst)
+	}, nil)
+}
+
+// materializeOutputs materializes all outputs to temporary files beside them, then renames the temporary files
+// to the outputs, so no output is touched when any of them fails. The outputs with the same owner and permission
+// are hard links of one file, or copies of it when hard link is not supported, eg: across file systems.
+// The file attributes of the resource are applied to every output, except the mode of the output with permission.
+func materializeOutputs(outputs []StoreOutput, store func(dst string, first bool) error, attr *source.FileAttributes) error {
+	type pending struct {
+		tmp    string
+		output StoreOutput
+	}
+	var (
+		pendings []pending
+		renamed  int
+	)
+	defer func() {
+		for _, p := range pendings[renamed:] {
+			os.Remove(p.tmp)
+		}
+	}()
+
+	for i, group := range groupOutputs(outputs) {
+		var leader string
+		for j, output := range group {
+			tmp := tempOutputPath(output.Path)
+			pendings = append(pendings, pending{tmp: tmp, output: output})
+			if j == 0 {
+				if err := store(tmp, i == 0); err != nil {
+					return errors.Wrapf(err, "store output %s", output.Path)
+				}
+				leader = tmp
+			} else if err := os.Link(leader, tmp); err != nil {
+				logger.Debugf("link output %s error: %s, copy it", output.Path, err)
+				if err = copyFile(leader, tmp); err != nil {
+					return errors.Wrapf(err, "copy output %s", output.Path)
+				}
+			}
+			if err := clientutil.ApplyFileAttributes(tmp, attr, output.Perm != 0); err != nil {
+				logger.Warnf("apply file attributes to output %s error: %s", output.Path, err)
+			}
+			if err := applyOutputAttributes(tmp, output); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range pendings {
+		if err := os.Rename(p.tmp, p.output.Path); err != nil {
+			return errors.Wrapf(err, "rename output %s", p.output.Path)
+		}
+		renamed++
+	}
+	return nil
+}
+
+// groupOutputs groups the outputs by owner and permission, in order of the first output of every group
+func groupOutputs(outputs []StoreOutput) [][]StoreOutput {
+	type attributes struct {
+		uid, gid int
+		perm     os.FileMode
+	}
+	var (
+		groups [][]StoreOutput
+		index  = map[attributes]int{}
+	)
+	for _, output := range outputs {
+		attr := attributes{-1, -1, output.Perm}
+		if output.Uid != nil {
+			attr.uid = *output.Uid
+		}
+		if output.Gid != nil {
+			attr.gid = *output.Gid
+		}
+		i, ok := index[attr]
+		if !ok {
+			i = len(groups)
+			index[attr] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], output)
+	}
+	return groups
+}
+
+func tempOutputPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d.tmp", filepath.Base(path), time.Now().UnixNano()))
+}
+
+func applyOutputAttributes(path string, output StoreOutput) error {
+	if output.Perm != 0 {
+		if err := os.Chmod(path, output.Perm); err != nil {
+			return errors.Wrapf(err, "change mode of output %s", output.Path)
+		}
+	}
+	if output.Uid != nil || output.Gid != nil {
+		// -1 keeps the owner or group unchanged
+		uid, gid := -1, -1
+		if output.Uid != nil {
+			uid = *output.Uid
+		}
+		if output.Gid != nil {
+			gid = *output.Gid
+		}
+		if err := os.Chown(path, uid, gid); err != nil {
+			return errors.Wrapf(err, "change owner of output %s", output.Path)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return copyToFile(file, dst)
+}
+
+func copyToFile(r io.Reader, dst string) error {
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dstFile, r); err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
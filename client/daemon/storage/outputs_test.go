@@ -0,0 +1,65 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestStoreContentToOutputs(t *testing.T) {
+	assert := testifyassert.New(t)
+	dir := t.TempDir()
+	content := []byte("dragonfly outputs test content")
+
+	outputs := []StoreOutput{
+		{Path: filepath.Join(dir, "a")},
+		{Path: filepath.Join(dir, "b"), Perm: 0600},
+		{Path: filepath.Join(dir, "c")},
+	}
+	// exist output is replaced
+	assert.Nil(os.WriteFile(outputs[2].Path, []byte("old"), 0644))
+	assert.Nil(StoreContentToOutputs(content, outputs))
+
+	stats := make([]os.FileInfo, len(outputs))
+	for i, output := range outputs {
+		data, err := os.ReadFile(output.Path)
+		assert.Nil(err)
+		assert.Equal(content, data)
+		stats[i], err = os.Stat(output.Path)
+		assert.Nil(err)
+	}
+	// outputs with the same owner and permission are hard links
+	assert.True(os.SameFile(stats[0], stats[2]))
+	assert.False(os.SameFile(stats[0], stats[1]))
+	assert.Equal(os.FileMode(0600), stats[1].Mode().Perm())
+
+	// no output is touched when any of them fails
+	failed := []StoreOutput{
+		{Path: filepath.Join(dir, "d")},
+		{Path: filepath.Join(dir, "not-exist", "e")},
+	}
+	assert.NotNil(StoreContentToOutputs(content, failed))
+	_, err := os.Stat(failed[0].Path)
+	assert.True(os.IsNotExist(err))
+	entries, err := os.ReadDir(dir)
+	assert.Nil(err)
+	assert.Len(entries, 3, "temporary files are removed")
+}
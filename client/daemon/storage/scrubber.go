@@ -0,0 +1,200 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+// scrubBufferSize is the max bytes read from task data before waiting for the rate limiter
+const scrubBufferSize = 64 * 1024
+
+// ScrubTasks re-verifies the piece data of all done task stores, the corrupted task stores are
+// marked invalid, then they are refused to be read and reclaimed in next gc loop.
+// It returns the count of the corrupted task stores.
+func (s *storageManager) ScrubTasks(ctx context.Context, limiter *rate.Limiter) (int, error) {
+	var tasks []*localTaskStore
+	s.tasks.Range(func(key, val interface{}) bool {
+		// only local task stores can be scrubbed, the driver plugins should verify their data by themselves
+		if t, ok := val.(*localTaskStore); ok {
+			tasks = append(tasks, t)
+		}
+		return true
+	})
+
+	var corrupted int
+	for _, t := range tasks {
+		if err := t.scrub(ctx, limiter); err != nil {
+			if ctx.Err() != nil {
+				return corrupted, ctx.Err()
+			}
+			if errors.Is(err, ErrInvalidDigest) {
+				corrupted++
+				continue
+			}
+			t.Warnf("scrub task error: %s", err)
+		}
+	}
+	logger.Infof("scrubbed %d task(s), corrupted %d task(s)", len(tasks), corrupted)
+	return corrupted, nil
+}
+
+// scrub re-calculates the md5 of all pieces and the piece md5 sign of the done task store,
+// when any of them not match, the task store is marked invalid and ErrInvalidDigest is returned
+func (t *localTaskStore) scrub(ctx context.Context, limiter *rate.Limiter) error {
+	t.RLock()
+	if !t.Done || t.invalid.Load() || t.IsReclaimMarked() {
+		t.RUnlock()
+		return nil
+	}
+	pieces := make([]PieceMetadata, 0, len(t.Pieces))
+	for _, piece := range t.Pieces {
+		pieces = append(pieces, piece)
+	}
+	totalPieces, pieceMd5Sign := t.TotalPieces, t.PieceMd5Sign
+	t.RUnlock()
+	sort.Slice(pieces, func(i, j int) bool {
+		return pieces[i].Num < pieces[j].Num
+	})
+
+	var file *os.File
+	if t.pieceStore == nil {
+		var err error
+		if file, err = os.Open(t.DataFilePath); err != nil {
+			if os.IsNotExist(err) {
+				return t.markCorrupted(errors.Wrap(ErrInvalidDigest, "data file not found"))
+			}
+			return err
+		}
+		defer file.Close()
+	}
+
+	var (
+		corruptedPieces int
+		pieceDigests    []string
+	)
+	for _, piece := range pieces {
+		pieceDigests = append(pieceDigests, piece.Md5)
+		// the piece content in content addressable storage is also verified by sha256
+		if piece.Md5 == "" && piece.Sha256 == "" {
+			continue
+		}
+		md5Digest, sha256Digest, err := t.scrubPiece(ctx, file, piece, limiter)
+		if err != nil {
+			return err
+		}
+		metrics.StorageScrubPieceCount.Add(1)
+		if piece.Md5 != "" && md5Digest != piece.Md5 {
+			t.Errorf("scrub piece %d, invalid md5, desired: %s, actual: %s", piece.Num, piece.Md5, md5Digest)
+			corruptedPieces++
+		} else if piece.Sha256 != "" && sha256Digest != piece.Sha256 {
+			t.Errorf("scrub piece %d, invalid sha256, desired: %s, actual: %s", piece.Num, piece.Sha256, sha256Digest)
+			corruptedPieces++
+		}
+	}
+	if corruptedPieces > 0 {
+		metrics.StorageScrubCorruptedPieceCount.Add(float64(corruptedPieces))
+		return t.markCorrupted(errors.Wrapf(ErrInvalidDigest, "%d piece(s) corrupted", corruptedPieces))
+	}
+
+	// the md5 of pieces are also verified by the piece md5 sign, avoid corrupted metadata
+	if pieceMd5Sign != "" && int32(len(pieces)) == totalPieces {
+		if digest := digestutils.Sha256(pieceDigests...); digest != pieceMd5Sign {
+			return t.markCorrupted(errors.Wrapf(ErrInvalidDigest, "invalid piece md5 sign, desired: %s, actual: %s", pieceMd5Sign, digest))
+		}
+	}
+	return nil
+}
+
+// scrubPiece reads the piece data with the limiter and returns the md5 and sha256 of it,
+// when the piece data can not be read, empty digests are returned
+func (t *localTaskStore) scrubPiece(ctx context.Context, file *os.File, piece PieceMetadata, limiter *rate.Limiter) (string, string, error) {
+	var r io.Reader
+	if file != nil {
+		r = io.NewSectionReader(file, piece.Range.Start, piece.Range.Length)
+	} else {
+		reader, closer, err := t.readPieceContent(&ReadPieceRequest{PieceMetadata: piece})
+		if err != nil {
+			t.Errorf("scrub piece %d, read piece content error: %s", piece.Num, err)
+			return "", "", nil
+		}
+		defer closer.Close()
+		r = reader
+	}
+
+	size := scrubBufferSize
+	if limiter != nil && limiter.Limit() != rate.Inf && limiter.Burst() > 0 && limiter.Burst() < size {
+		size = limiter.Burst()
+	}
+	var (
+		buf        = make([]byte, size)
+		md5Hash    = md5.New()
+		sha256Hash hash.Hash
+		read       int64
+	)
+	if piece.Sha256 != "" {
+		sha256Hash = sha256.New()
+	}
+	for read < piece.Range.Length {
+		n := int64(len(buf))
+		if remain := piece.Range.Length - read; remain < n {
+			n = remain
+		}
+		if limiter != nil {
+			if err := limiter.WaitN(ctx, int(n)); err != nil {
+				return "", "", err
+			}
+		}
+		// the data file may be truncated
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			t.Errorf("scrub piece %d, read data error: %s", piece.Num, err)
+			return "", "", nil
+		}
+		md5Hash.Write(buf[:n])
+		if sha256Hash != nil {
+			sha256Hash.Write(buf[:n])
+		}
+		read += n
+		metrics.StorageScrubBytesCount.Add(float64(n))
+	}
+	var sha256Digest string
+	if sha256Hash != nil {
+		sha256Digest = hex.EncodeToString(sha256Hash.Sum(nil))
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), sha256Digest, nil
+}
+
+func (t *localTaskStore) markCorrupted(err error) error {
+	t.Errorf("task data corrupted, mark invalid: %s", err)
+	t.invalid.Store(true)
+	metrics.StorageScrubCorruptedTaskCount.Add(1)
+	return err
+}
@@ -18,7 +18,6 @@ package storage
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -35,6 +34,7 @@ import (
 	"github.com/shirou/gopsutil/v3/disk"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
@@ -64,6 +64,13 @@ type TaskStorageDriver interface {
 	ValidateDigest(req *PeerTaskMetadata) error
 
 	IsInvalid(req *PeerTaskMetadata) (bool, error)
+
+	// IsVerified returns false when the task requests signature verification and it is not verified yet,
+	// the pieces of unverified task are not shared with other peers
+	IsVerified(req *PeerTaskMetadata) (bool, error)
+
+	// MarkVerified marks the signature of the task verified
+	MarkVerified(req *PeerTaskMetadata) error
 }
 
 // Reclaimer stands storage reclaimer
@@ -84,18 +91,34 @@ type Manager interface {
 	clientutil.KeepAlive
 	// RegisterTask registers a task in storage driver
 	RegisterTask(ctx context.Context, req RegisterTaskRequest) error
+	// UnregisterTask reclaims the task store registered by RegisterTask
+	UnregisterTask(ctx context.Context, req CommonTaskRequest) error
 	// FindCompletedTask try to find a completed task for fast path
 	FindCompletedTask(taskID string) *ReusePeerTask
+	// FindPartialTask try to find an unfinished task with downloaded pieces for resuming
+	FindPartialTask(taskID string) *PartialPeerTask
+	// ListTasks returns all task stores in storage
+	ListTasks() []*TaskInfo
+	// FindTasks returns all task stores of the task
+	FindTasks(taskID string) []*TaskInfo
+	// DeleteTask reclaims all task stores of the task
+	DeleteTask(taskID string) error
+	// PinTask updates the pin and priority of all task stores of the task
+	PinTask(req PinTaskRequest) error
+	// ScrubTasks re-verifies the data of all done task stores and marks the corrupted ones invalid
+	ScrubTasks(ctx context.Context, limiter *rate.Limiter) (int, error)
 	// CleanUp cleans all storage data
 	CleanUp()
 }
 
 var (
-	ErrTaskNotFound     = errors.New("task not found")
-	ErrPieceNotFound    = errors.New("piece not found")
-	ErrPieceCountNotSet = errors.New("total piece count not set")
-	ErrDigestNotSet     = errors.New("piece digest not set")
-	ErrInvalidDigest    = errors.New("invalid digest")
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrTaskNotVerified   = errors.New("task signature is not verified")
+	ErrPieceNotFound     = errors.New("piece not found")
+	ErrPieceCountNotSet  = errors.New("total piece count not set")
+	ErrDigestNotSet      = errors.New("piece digest not set")
+	ErrInvalidDigest     = errors.New("invalid digest")
+	ErrDiskQuotaExceeded = errors.New("disk quota exceeded")
 )
 
 const (
@@ -113,13 +136,32 @@ type storageManager struct {
 	clientutil.KeepAlive
 	storeStrategy      config.StoreStrategy
 	storeOption        *config.StorageOption
+	driverBuilder      TaskStorageDriverBuilder
+	pluginDir          string
 	tasks              sync.Map
 	markedReclaimTasks []PeerTaskMetadata
 	dataPathStat       *syscall.Stat_t
 	gcCallback         func(CommonTaskRequest)
 	gcInterval         time.Duration
 	indexRWMutex       sync.RWMutex
-	indexTask2PeerTask map[string][]*localTaskStore // key: task id, value: slice of localTaskStore
+	indexTask2PeerTask map[string][]TaskStore // key: task id, value: slice of TaskStore
+	pieceStore         *pieceStore
+
+	// diskUsages are the disk usages of the tasks not marked reclaimed, usedBytes is the sum of them.
+	// They are only maintained when disk quota is set, and resynchronized with the tasks in every gc loop
+	diskUsageLock sync.Mutex
+	diskUsages    map[PeerTaskMetadata]*diskUsage
+	usedBytes     int64
+}
+
+// diskUsage is the disk usage of a task accounted by disk quota
+type diskUsage struct {
+	contentLength   int64
+	completedLength int64
+}
+
+func (u *diskUsage) bytes() int64 {
+	return taskUsage(u.contentLength, u.completedLength)
 }
 
 var _ gc.GC = (*storageManager)(nil)
@@ -145,12 +187,8 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 	if err != nil {
 		return nil, err
 	}
-	switch storeStrategy {
-	case config.SimpleLocalTaskStoreStrategy, config.AdvanceLocalTaskStoreStrategy:
-	case config.StoreStrategy(""):
+	if storeStrategy == "" {
 		storeStrategy = config.SimpleLocalTaskStoreStrategy
-	default:
-		return nil, fmt.Errorf("not support store strategy: %s", storeStrategy)
 	}
 
 	s := &storageManager{
@@ -160,7 +198,8 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 		dataPathStat:       stat.Sys().(*syscall.Stat_t),
 		gcCallback:         gcCallback,
 		gcInterval:         time.Minute,
-		indexTask2PeerTask: map[string][]*localTaskStore{},
+		indexTask2PeerTask: map[string][]TaskStore{},
+		diskUsages:         map[PeerTaskMetadata]*diskUsage{},
 	}
 
 	for _, o := range moreOpts {
@@ -168,10 +207,15 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 			return nil, err
 		}
 	}
+	if s.driverBuilder, err = loadDriver(s.pluginDir, storeStrategy); err != nil {
+		return nil, err
+	}
+	s.pieceStore = newPieceStore(path.Join(s.storeOption.DataPath, pieceStoreDir))
 
 	if err := s.ReloadPersistentTask(gcCallback); err != nil {
 		logger.Warnf("reload tasks error: %s", err)
 	}
+	s.syncDiskUsages()
 
 	gc.Register(GCName, s)
 	return s, nil
@@ -184,6 +228,14 @@ func WithStorageOption(opt *config.StorageOption) func(*storageManager) error {
 	}
 }
 
+// WithPluginDir sets the directory for loading storage driver plugins
+func WithPluginDir(pluginDir string) func(*storageManager) error {
+	return func(manager *storageManager) error {
+		manager.pluginDir = pluginDir
+		return nil
+	}
+}
+
 func WithGCInterval(gcInterval time.Duration) func(*storageManager) error {
 	return func(manager *storageManager) error {
 		manager.gcInterval = gcInterval
@@ -223,7 +275,19 @@ func (s *storageManager) WritePiece(ctx context.Context, req *WritePieceRequest)
 	if !ok {
 		return 0, ErrTaskNotFound
 	}
-	return t.(TaskStorageDriver).WritePiece(ctx, req)
+	// the content length is unknown when most tasks are registered, check quota when the task grows
+	if err := s.growDiskUsage(t.(TaskStore), req.PeerTaskMetadata, func(u *diskUsage) {
+		u.completedLength += req.Range.Length
+	}); err != nil {
+		return 0, err
+	}
+	n, err := t.(TaskStorageDriver).WritePiece(ctx, req)
+	if err != nil {
+		_ = s.growDiskUsage(t.(TaskStore), req.PeerTaskMetadata, func(u *diskUsage) {
+			u.completedLength -= req.Range.Length
+		})
+	}
+	return n, err
 }
 
 func (s *storageManager) ReadPiece(ctx context.Context, req *ReadPieceRequest) (io.Reader, io.Closer, error) {
@@ -295,102 +359,46 @@ func (s *storageManager) UpdateTask(ctx context.Context, req *UpdateTaskRequest)
 	if !ok {
 		return ErrTaskNotFound
 	}
+	if err := s.growDiskUsage(t.(TaskStore), req.PeerTaskMetadata, func(u *diskUsage) {
+		u.contentLength = req.ContentLength
+	}); err != nil {
+		return err
+	}
 	return t.(TaskStorageDriver).UpdateTask(ctx, req)
 }
 
 func (s *storageManager) CreateTask(req RegisterTaskRequest) error {
 	s.Keep()
-	logger.Debugf("init local task storage, peer id: %s, task id: %s", req.PeerID, req.TaskID)
-
-	dataDir := path.Join(s.storeOption.DataPath, req.TaskID, req.PeerID)
-	t := &localTaskStore{
-		persistentMetadata: persistentMetadata{
-			StoreStrategy: string(s.storeStrategy),
-			TaskID:        req.TaskID,
-			TaskMeta:      map[string]string{},
-			ContentLength: req.ContentLength,
-			TotalPieces:   req.TotalPieces,
-			PieceMd5Sign:  req.PieceMd5Sign,
-			PeerID:        req.PeerID,
-			Pieces:        map[int32]PieceMetadata{},
-		},
-		gcCallback:       s.gcCallback,
-		dataDir:          dataDir,
-		metadataFilePath: path.Join(dataDir, taskMetadata),
-		expireTime:       s.storeOption.TaskExpireTime.Duration,
-
-		SugaredLoggerOnWith: logger.With("task", req.TaskID, "peer", req.PeerID, "component", "localTaskStore"),
+	logger.Debugf("init task storage, peer id: %s, task id: %s", req.PeerID, req.TaskID)
+	meta := PeerTaskMetadata{
+		PeerID: req.PeerID,
+		TaskID: req.TaskID,
 	}
-	if err := os.MkdirAll(t.dataDir, defaultDirectoryMode); err != nil && !os.IsExist(err) {
+	if err := s.reserveDiskUsage(meta, req.ContentLength); err != nil {
 		return err
 	}
-	t.touch()
-	metadata, err := os.OpenFile(t.metadataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
+
+	t, err := s.driverBuilder(&TaskStorageDriverOption{
+		RegisterTaskRequest: req,
+		StoreStrategy:       s.storeStrategy,
+		DataDir:             path.Join(s.storeOption.DataPath, req.TaskID, req.PeerID),
+		StorageOption:       s.storeOption,
+		GCCallback:          s.gcCallback,
+		dataPathStat:        s.dataPathStat,
+		pieceStore:          s.pieceStore,
+	})
 	if err != nil {
+		s.releaseDiskUsage(meta)
 		return err
 	}
-	t.metadataFile = metadata
-
-	// fallback to simple strategy for proxy
-	if req.Destination == "" {
-		t.StoreStrategy = string(config.SimpleLocalTaskStoreStrategy)
-	}
-	data := path.Join(dataDir, taskData)
-	switch t.StoreStrategy {
-	case string(config.SimpleLocalTaskStoreStrategy):
-		t.DataFilePath = data
-		f, err := os.OpenFile(t.DataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
-		if err != nil {
-			return err
-		}
-		f.Close()
-	case string(config.AdvanceLocalTaskStoreStrategy):
-		dir, file := path.Split(req.Destination)
-		dirStat, err := os.Stat(dir)
-		if err != nil {
-			return err
-		}
-
-		t.DataFilePath = path.Join(dir, fmt.Sprintf(".%s.dfget.cache.%s", file, req.PeerID))
-		f, err := os.OpenFile(t.DataFilePath, os.O_CREATE|os.O_RDWR, defaultFileMode)
-		if err != nil {
-			return err
-		}
-		f.Close()
-
-		stat := dirStat.Sys().(*syscall.Stat_t)
-		// same dev, can hard link
-		if stat.Dev == s.dataPathStat.Dev {
-			logger.Debugf("same device, try to hard link")
-			if err := os.Link(t.DataFilePath, data); err != nil {
-				logger.Warnf("hard link failed for same device: %s, fallback to symbol link", err)
-				// fallback to symbol link
-				if err := os.Symlink(t.DataFilePath, data); err != nil {
-					logger.Errorf("symbol link failed: %s", err)
-					return err
-				}
-			}
-		} else {
-			logger.Debugf("different devices, try to symbol link")
-			// make symbol link for reload error gc
-			if err := os.Symlink(t.DataFilePath, data); err != nil {
-				logger.Errorf("symbol link failed: %s", err)
-				return err
-			}
-		}
-	}
-	s.tasks.Store(
-		PeerTaskMetadata{
-			PeerID: req.PeerID,
-			TaskID: req.TaskID,
-		}, t)
+	s.tasks.Store(meta, t)
 
 	s.indexRWMutex.Lock()
 	if ts, ok := s.indexTask2PeerTask[req.TaskID]; ok {
 		ts = append(ts, t)
 		s.indexTask2PeerTask[req.TaskID] = ts
 	} else {
-		s.indexTask2PeerTask[req.TaskID] = []*localTaskStore{t}
+		s.indexTask2PeerTask[req.TaskID] = []TaskStore{t}
 	}
 	s.indexRWMutex.Unlock()
 	return nil
@@ -404,31 +412,146 @@ func (s *storageManager) FindCompletedTask(taskID string) *ReusePeerTask {
 		return nil
 	}
 	for _, t := range ts {
-		if t.invalid.Load() {
+		info := t.Info()
+		if invalid, _ := t.IsInvalid(&info.PeerTaskMetadata); invalid {
 			continue
 		}
 		// touch it before marking reclaim
-		t.touch()
+		t.Touch()
 		// already marked, skip
-		if t.reclaimMarked.Load() {
+		if t.IsReclaimMarked() {
 			continue
 		}
 
-		if !t.Done {
+		if !info.Done {
 			continue
 		}
 		return &ReusePeerTask{
 			PeerTaskMetadata: PeerTaskMetadata{
-				PeerID: t.PeerID,
+				PeerID: info.PeerID,
 				TaskID: taskID,
 			},
-			ContentLength: t.ContentLength,
-			TotalPieces:   int32(t.TotalPieces),
+			ContentLength: info.ContentLength,
+			TotalPieces:   info.TotalPieces,
+		}
+	}
+	return nil
+}
+
+func (s *storageManager) FindPartialTask(taskID string) *PartialPeerTask {
+	s.indexRWMutex.RLock()
+	defer s.indexRWMutex.RUnlock()
+	for _, t := range s.indexTask2PeerTask[taskID] {
+		// only local task stores support resuming
+		lt, ok := t.(*localTaskStore)
+		if !ok || lt.invalid.Load() || lt.IsReclaimMarked() {
+			continue
+		}
+		if p := lt.partial(); p != nil {
+			lt.Touch()
+			return p
 		}
 	}
 	return nil
 }
 
+func (s *storageManager) ListTasks() []*TaskInfo {
+	var infos []*TaskInfo
+	s.tasks.Range(func(key, task interface{}) bool {
+		infos = append(infos, task.(TaskStore).Info())
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].TaskID != infos[j].TaskID {
+			return infos[i].TaskID < infos[j].TaskID
+		}
+		return infos[i].PeerID < infos[j].PeerID
+	})
+	return infos
+}
+
+func (s *storageManager) FindTasks(taskID string) []*TaskInfo {
+	s.indexRWMutex.RLock()
+	defer s.indexRWMutex.RUnlock()
+	var infos []*TaskInfo
+	for _, t := range s.indexTask2PeerTask[taskID] {
+		infos = append(infos, t.Info())
+	}
+	return infos
+}
+
+func (s *storageManager) DeleteTask(taskID string) error {
+	s.indexRWMutex.RLock()
+	ts := make([]TaskStore, len(s.indexTask2PeerTask[taskID]))
+	copy(ts, s.indexTask2PeerTask[taskID])
+	s.indexRWMutex.RUnlock()
+	if len(ts) == 0 {
+		return ErrTaskNotFound
+	}
+
+	var errs []string
+	for _, t := range ts {
+		if err := s.deleteTaskStore(t); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("delete task %s error: %q", taskID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *storageManager) PinTask(req PinTaskRequest) error {
+	s.indexRWMutex.RLock()
+	ts := make([]TaskStore, len(s.indexTask2PeerTask[req.TaskID]))
+	copy(ts, s.indexTask2PeerTask[req.TaskID])
+	s.indexRWMutex.RUnlock()
+	if len(ts) == 0 {
+		return ErrTaskNotFound
+	}
+
+	for _, t := range ts {
+		pinned, priority := req.Pinned, req.Priority
+		if req.Merge {
+			info := t.Info()
+			pinned = pinned || info.Pinned
+			if priority == 0 {
+				priority = info.Priority
+			}
+		}
+		if err := t.Pin(pinned, priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storageManager) UnregisterTask(ctx context.Context, req CommonTaskRequest) error {
+	t, ok := s.tasks.Load(PeerTaskMetadata{
+		PeerID: req.PeerID,
+		TaskID: req.TaskID,
+	})
+	if !ok {
+		return ErrTaskNotFound
+	}
+	return s.deleteTaskStore(t.(TaskStore))
+}
+
+// deleteTaskStore removes the task store from index and reclaims it immediately
+func (s *storageManager) deleteTaskStore(t TaskStore) error {
+	meta := t.Info().PeerTaskMetadata
+	s.tasks.Delete(meta)
+	s.cleanIndex(meta.TaskID, meta.PeerID)
+	s.releaseDiskUsage(meta)
+	t.MarkReclaim()
+	if err := t.Reclaim(); err != nil {
+		logger.Errorf("delete task %s/%s error: %s", meta.TaskID, meta.PeerID, err)
+		return err
+	}
+	logger.Infof("task %s/%s deleted", meta.TaskID, meta.PeerID)
+	return nil
+}
+
 func (s *storageManager) cleanIndex(taskID, peerID string) {
 	s.indexRWMutex.Lock()
 	defer s.indexRWMutex.Unlock()
@@ -436,10 +559,10 @@ func (s *storageManager) cleanIndex(taskID, peerID string) {
 	if !ok {
 		return
 	}
-	var remain []*localTaskStore
+	var remain []TaskStore
 	// FIXME switch instead copy
 	for _, t := range ts {
-		if t.PeerID == peerID {
+		if t.Info().PeerID == peerID {
 			logger.Debugf("clean index for %s/%s", taskID, peerID)
 			continue
 		}
@@ -472,6 +595,30 @@ func (s *storageManager) IsInvalid(req *PeerTaskMetadata) (bool, error) {
 	return t.(TaskStorageDriver).IsInvalid(req)
 }
 
+func (s *storageManager) IsVerified(req *PeerTaskMetadata) (bool, error) {
+	t, ok := s.LoadTask(
+		PeerTaskMetadata{
+			TaskID: req.TaskID,
+			PeerID: req.PeerID,
+		})
+	if !ok {
+		return false, ErrTaskNotFound
+	}
+	return t.(TaskStorageDriver).IsVerified(req)
+}
+
+func (s *storageManager) MarkVerified(req *PeerTaskMetadata) error {
+	t, ok := s.LoadTask(
+		PeerTaskMetadata{
+			TaskID: req.TaskID,
+			PeerID: req.PeerID,
+		})
+	if !ok {
+		return ErrTaskNotFound
+	}
+	return t.(TaskStorageDriver).MarkVerified(req)
+}
+
 func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 	dirs, err := os.ReadDir(s.storeOption.DataPath)
 	if os.IsNotExist(err) {
@@ -486,6 +633,10 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 	)
 	for _, dir := range dirs {
 		taskID := dir.Name()
+		// skip dot files or directories, eg: piece store
+		if strings.HasPrefix(taskID, ".") {
+			continue
+		}
 		taskDir := path.Join(s.storeOption.DataPath, taskID)
 		peerDirs, err := os.ReadDir(taskDir)
 		if err != nil {
@@ -493,10 +644,6 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 		}
 		// remove empty task dir
 		if len(peerDirs) == 0 {
-			// skip dot files or directories
-			if strings.HasPrefix(taskDir, ".") {
-				continue
-			}
 			if err := os.Remove(taskDir); err != nil {
 				logger.Errorf("remove empty task dir %s failed: %s", taskDir, err)
 			} else {
@@ -507,40 +654,14 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 		for _, peerDir := range peerDirs {
 			peerID := peerDir.Name()
 			dataDir := path.Join(s.storeOption.DataPath, taskID, peerID)
-			t := &localTaskStore{
-				dataDir:             dataDir,
-				metadataFilePath:    path.Join(dataDir, taskMetadata),
-				expireTime:          s.storeOption.TaskExpireTime.Duration,
-				gcCallback:          gcCallback,
-				SugaredLoggerOnWith: logger.With("task", taskID, "peer", peerID, "component", s.storeStrategy),
-			}
-			t.touch()
-
-			if t.metadataFile, err = os.Open(t.metadataFilePath); err != nil {
+			t, err := s.reloadTaskStore(taskID, peerID, dataDir, gcCallback)
+			if err != nil {
 				loadErrs = append(loadErrs, err)
 				loadErrDirs = append(loadErrDirs, dataDir)
-				logger.With("action", "reload", "stage", "read metadata", "taskID", taskID, "peerID", peerID).
-					Warnf("open task metadata error: %s", err)
+				logger.With("action", "reload", "taskID", taskID, "peerID", peerID).
+					Warnf("load task from disk error: %s", err)
 				continue
 			}
-			bytes, err0 := io.ReadAll(t.metadataFile)
-			if err0 != nil {
-				loadErrs = append(loadErrs, err0)
-				loadErrDirs = append(loadErrDirs, dataDir)
-				logger.With("action", "reload", "stage", "read metadata", "taskID", taskID, "peerID", peerID).
-					Warnf("load task from disk error: %s", err0)
-				continue
-			}
-
-			if err0 = json.Unmarshal(bytes, &t.persistentMetadata); err0 != nil {
-				loadErrs = append(loadErrs, err0)
-				loadErrDirs = append(loadErrDirs, dataDir)
-				logger.With("action", "reload", "stage", "parse metadata", "taskID", taskID, "peerID", peerID).
-					Warnf("load task from disk error: %s", err0)
-				continue
-			}
-			logger.Debugf("load task %s/%s from disk, metadata %s, last access: %s, expire time: %s",
-				t.persistentMetadata.TaskID, t.persistentMetadata.PeerID, t.metadataFilePath, t.lastAccess, t.expireTime)
 			s.tasks.Store(PeerTaskMetadata{
 				PeerID: peerID,
 				TaskID: taskID,
@@ -551,7 +672,7 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 				ts = append(ts, t)
 				s.indexTask2PeerTask[taskID] = ts
 			} else {
-				s.indexTask2PeerTask[taskID] = []*localTaskStore{t}
+				s.indexTask2PeerTask[taskID] = []TaskStore{t}
 			}
 		}
 	}
@@ -589,6 +710,8 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 		}
 		logger.Warnf("remove load error directory %s ok", dir)
 	}
+	// all references are loaded, remove the piece contents which are not referenced by any task
+	s.pieceStore.prune()
 	if len(loadErrs) > 0 {
 		var sb strings.Builder
 		for _, err := range loadErrs {
@@ -599,16 +722,47 @@ func (s *storageManager) ReloadPersistentTask(gcCallback GCCallback) error {
 	return nil
 }
 
+// reloadTaskStore restores the task store in dataDir with the driver of the store strategy in metadata
+func (s *storageManager) reloadTaskStore(taskID, peerID, dataDir string, gcCallback GCCallback) (TaskStore, error) {
+	strategy, err := readStoreStrategy(path.Join(dataDir, taskMetadata))
+	if err != nil {
+		return nil, err
+	}
+	builder, err := loadDriver(s.pluginDir, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return builder(&TaskStorageDriverOption{
+		RegisterTaskRequest: RegisterTaskRequest{
+			CommonTaskRequest: CommonTaskRequest{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+		},
+		StoreStrategy: strategy,
+		DataDir:       dataDir,
+		Reload:        true,
+		StorageOption: s.storeOption,
+		GCCallback:    gcCallback,
+		dataPathStat:  s.dataPathStat,
+		pieceStore:    s.pieceStore,
+	})
+}
+
 func (s *storageManager) TryGC() (bool, error) {
 	var markedTasks []PeerTaskMetadata
 	var totalNotMarkedSize int64
 	s.tasks.Range(func(key, task interface{}) bool {
-		if task.(*localTaskStore).CanReclaim() {
-			task.(*localTaskStore).MarkReclaim()
+		if task.(TaskStore).IsReclaimMarked() {
+			// the task is marked by disk quota or last gc loop, reclaim it in next gc loop
+			markedTasks = append(markedTasks, key.(PeerTaskMetadata))
+		} else if task.(TaskStore).CanReclaim() {
+			task.(TaskStore).MarkReclaim()
 			markedTasks = append(markedTasks, key.(PeerTaskMetadata))
 		} else {
 			// just calculate not reclaimed task
-			totalNotMarkedSize += task.(*localTaskStore).ContentLength
+			info := task.(TaskStore).Info()
+			totalNotMarkedSize += taskUsage(info.ContentLength, info.CompletedLength)
 			logger.Debugf("task %s/%s not reach gc time",
 				key.(PeerTaskMetadata).TaskID, key.(PeerTaskMetadata).PeerID)
 		}
@@ -618,41 +772,29 @@ func (s *storageManager) TryGC() (bool, error) {
 	quotaBytesExceed := totalNotMarkedSize - int64(s.storeOption.DiskGCThreshold)
 	quotaExceed := s.storeOption.DiskGCThreshold > 0 && quotaBytesExceed > 0
 	usageExceed, usageBytesExceed := s.diskUsageExceed()
+	hardQuotaBytesExceed := totalNotMarkedSize - int64(s.storeOption.DiskQuota)
+	hardQuotaExceed := s.storeOption.DiskQuota > 0 && hardQuotaBytesExceed > 0
 
-	if quotaExceed || usageExceed {
+	if quotaExceed || usageExceed || hardQuotaExceed {
 		var bytesExceed int64
-		if quotaBytesExceed > usageBytesExceed {
+		if quotaExceed {
 			bytesExceed = quotaBytesExceed
-		} else {
+		}
+		if usageBytesExceed > bytesExceed {
 			bytesExceed = usageBytesExceed
 		}
-		logger.Infof("quota threshold reached, start gc oldest task, size: %d bytes", bytesExceed)
-		var tasks []*localTaskStore
-		s.tasks.Range(func(key, val interface{}) bool {
-			// skip reclaimed task
-			task := val.(*localTaskStore)
-			if task.reclaimMarked.Load() {
-				return true
-			}
-			// task is not done, and is active in s.gcInterval
-			// next gc loop will check it again
-			if !task.Done && time.Now().Sub(time.Unix(0, task.lastAccess.Load())) < s.gcInterval {
-				return true
-			}
-			tasks = append(tasks, task)
-			return true
-		})
-		// sort by access time
-		sort.SliceStable(tasks, func(i, j int) bool {
-			return tasks[i].lastAccess.Load() < tasks[j].lastAccess.Load()
-		})
-		for _, task := range tasks {
+		if hardQuotaExceed && hardQuotaBytesExceed > bytesExceed {
+			bytesExceed = hardQuotaBytesExceed
+		}
+		logger.Infof("quota threshold reached, start gc tasks by %s policy, size: %d bytes", s.gcPolicy(), bytesExceed)
+		for _, candidate := range s.reclaimCandidates() {
+			task, info := candidate.task, candidate.info
 			task.MarkReclaim()
-			markedTasks = append(markedTasks, PeerTaskMetadata{task.PeerID, task.TaskID})
+			markedTasks = append(markedTasks, info.PeerTaskMetadata)
 			logger.Infof("quota threshold reached, mark task %s/%s reclaimed, last access: %s, size: %s",
-				task.TaskID, task.PeerID, time.Unix(0, task.lastAccess.Load()).Format(time.RFC3339Nano),
-				units.BytesSize(float64(task.ContentLength)))
-			bytesExceed -= task.ContentLength
+				info.TaskID, info.PeerID, info.LastAccess.Format(time.RFC3339Nano),
+				units.BytesSize(float64(info.ContentLength)))
+			bytesExceed -= info.ContentLength
 			if bytesExceed <= 0 {
 				break
 			}
@@ -667,13 +809,13 @@ func (s *storageManager) TryGC() (bool, error) {
 		if !ok {
 			continue
 		}
-		task := t.(*localTaskStore)
+		task := t.(TaskStore)
 		_, span := tracer.Start(context.Background(), config.SpanPeerGC)
-		span.SetAttributes(config.AttributePeerID.String(task.PeerID))
-		span.SetAttributes(config.AttributeTaskID.String(task.TaskID))
+		span.SetAttributes(config.AttributePeerID.String(key.PeerID))
+		span.SetAttributes(config.AttributeTaskID.String(key.TaskID))
 
 		s.tasks.Delete(key)
-		s.cleanIndex(task.TaskID, task.PeerID)
+		s.cleanIndex(key.TaskID, key.PeerID)
 		if err := task.Reclaim(); err != nil {
 			// FIXME: retry later or push to queue
 			logger.Errorf("gc task %s/%s error: %s", key.TaskID, key.PeerID, err)
@@ -693,9 +835,224 @@ func (s *storageManager) TryGC() (bool, error) {
 	}
 	logger.Infof("marked %d task(s), reclaimed %d task(s)", len(markedTasks), len(s.markedReclaimTasks))
 	s.markedReclaimTasks = markedTasks
+	s.syncDiskUsages()
 	return true, nil
 }
 
+// reclaimCandidate is a task store which can be reclaimed when reaching gc threshold or disk quota
+type reclaimCandidate struct {
+	task TaskStore
+	info *TaskInfo
+}
+
+// reclaimCandidates returns the task stores which can be reclaimed, sorted by priority, then by gc policy
+func (s *storageManager) reclaimCandidates() []reclaimCandidate {
+	var (
+		candidates []reclaimCandidate
+		now        = time.Now()
+	)
+	s.tasks.Range(func(key, val interface{}) bool {
+		// skip reclaimed task
+		task := val.(TaskStore)
+		if task.IsReclaimMarked() {
+			return true
+		}
+		info := task.Info()
+		// pinned task is never reclaimed by gc
+		if info.Pinned {
+			return true
+		}
+		// task is not done, and is active in s.gcInterval
+		// next gc loop will check it again
+		if !info.Done && now.Sub(info.LastAccess) < s.gcInterval {
+			return true
+		}
+		candidates = append(candidates, reclaimCandidate{task: task, info: info})
+		return true
+	})
+
+	policy := s.gcPolicy()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].info, candidates[j].info
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		switch policy {
+		case config.LFUGCPolicy:
+			if a.AccessCount != b.AccessCount {
+				return a.AccessCount < b.AccessCount
+			}
+		case config.SizeGCPolicy:
+			if wa, wb := sizeWeight(a, now), sizeWeight(b, now); wa != wb {
+				return wa > wb
+			}
+		}
+		// fallback to lru
+		return a.LastAccess.Before(b.LastAccess)
+	})
+	return candidates
+}
+
+// sizeWeight weights the content length by idle time, the large and idle tasks are reclaimed first
+func sizeWeight(info *TaskInfo, now time.Time) float64 {
+	return float64(info.ContentLength) * now.Sub(info.LastAccess).Seconds()
+}
+
+func (s *storageManager) gcPolicy() config.GCPolicy {
+	if s.storeOption.GCPolicy == "" {
+		return config.LRUGCPolicy
+	}
+	return s.storeOption.GCPolicy
+}
+
+// taskUsage returns the disk usage of a task, it is the completed length when the content length is unknown
+func taskUsage(contentLength, completedLength int64) int64 {
+	if contentLength > completedLength {
+		return contentLength
+	}
+	return completedLength
+}
+
+// syncDiskUsages recomputes the disk usages of the tasks not marked reclaimed, it walks the pieces of all tasks,
+// so it is only called when the manager is created and in gc loop, the usages are updated incrementally otherwise
+func (s *storageManager) syncDiskUsages() {
+	if s.storeOption.DiskQuota <= 0 {
+		return
+	}
+	var (
+		usages = map[PeerTaskMetadata]*diskUsage{}
+		used   int64
+	)
+	s.tasks.Range(func(key, val interface{}) bool {
+		task := val.(TaskStore)
+		if task.IsReclaimMarked() {
+			return true
+		}
+		info := task.Info()
+		u := &diskUsage{contentLength: info.ContentLength, completedLength: info.CompletedLength}
+		usages[key.(PeerTaskMetadata)] = u
+		used += u.bytes()
+		return true
+	})
+	s.diskUsageLock.Lock()
+	defer s.diskUsageLock.Unlock()
+	s.diskUsages, s.usedBytes = usages, used
+}
+
+// reserveDiskUsage accounts the usage of a new task, it returns ErrDiskQuotaExceeded when no enough tasks can be marked
+func (s *storageManager) reserveDiskUsage(meta PeerTaskMetadata, contentLength int64) error {
+	if s.storeOption.DiskQuota <= 0 {
+		return nil
+	}
+	s.diskUsageLock.Lock()
+	defer s.diskUsageLock.Unlock()
+	u := &diskUsage{contentLength: contentLength}
+	if err := s.ensureDiskQuota(u.bytes(), meta); err != nil {
+		return err
+	}
+	s.diskUsages[meta] = u
+	s.usedBytes += u.bytes()
+	return nil
+}
+
+// growDiskUsage applies grow to the usage of an existing task, it returns ErrDiskQuotaExceeded and keeps the usage
+// unchanged when the usage grows and no enough tasks can be marked
+func (s *storageManager) growDiskUsage(t TaskStore, meta PeerTaskMetadata, grow func(u *diskUsage)) error {
+	if s.storeOption.DiskQuota <= 0 {
+		return nil
+	}
+	s.diskUsageLock.Lock()
+	defer s.diskUsageLock.Unlock()
+	u, ok := s.diskUsages[meta]
+	if !ok {
+		// the task is marked reclaimed, or it is created after last sync
+		if t.IsReclaimMarked() {
+			return nil
+		}
+		info := t.Info()
+		u = &diskUsage{contentLength: info.ContentLength, completedLength: info.CompletedLength}
+		s.diskUsages[meta] = u
+		s.usedBytes += u.bytes()
+	}
+	next := *u
+	grow(&next)
+	size := next.bytes() - u.bytes()
+	if size > 0 {
+		if err := s.ensureDiskQuota(size, meta); err != nil {
+			return err
+		}
+	}
+	*u = next
+	s.usedBytes += size
+	return nil
+}
+
+// releaseDiskUsage removes the usage of a task which is marked reclaimed or deleted
+func (s *storageManager) releaseDiskUsage(meta PeerTaskMetadata) {
+	if s.storeOption.DiskQuota <= 0 {
+		return
+	}
+	s.diskUsageLock.Lock()
+	defer s.diskUsageLock.Unlock()
+	s.releaseDiskUsageLocked(meta)
+}
+
+func (s *storageManager) releaseDiskUsageLocked(meta PeerTaskMetadata) {
+	if u, ok := s.diskUsages[meta]; ok {
+		s.usedBytes -= u.bytes()
+		delete(s.diskUsages, meta)
+	}
+}
+
+// ensureDiskQuota marks tasks reclaimed by gc policy when the new usage will exceed disk quota, diskUsageLock must be held.
+// The tasks are marked only when they are enough to cover the exceeded bytes, otherwise none is marked and
+// ErrDiskQuotaExceeded is returned. The growing task itself is never marked.
+// The quota is soft until next gc loop: the marked tasks are not counted any more, but they are kept on disk
+// and reclaimed in next gc loop, so the readers and uploads of them are not broken immediately
+func (s *storageManager) ensureDiskQuota(size int64, self PeerTaskMetadata) error {
+	exceed := s.usedBytes + size - int64(s.storeOption.DiskQuota)
+	if exceed <= 0 {
+		return nil
+	}
+
+	var (
+		marked []reclaimCandidate
+		remain = exceed
+	)
+	for _, candidate := range s.reclaimCandidates() {
+		if candidate.info.PeerTaskMetadata == self {
+			continue
+		}
+		marked = append(marked, candidate)
+		remain -= s.accountedUsage(candidate.info)
+		if remain <= 0 {
+			break
+		}
+	}
+	if remain > 0 {
+		return errors.Wrapf(ErrDiskQuotaExceeded, "need %d bytes more", remain)
+	}
+
+	logger.Infof("disk quota reached, gc %d tasks by %s policy, size: %d bytes", len(marked), s.gcPolicy(), exceed)
+	for _, candidate := range marked {
+		info := candidate.info
+		candidate.task.MarkReclaim()
+		logger.Infof("disk quota reached, mark task %s/%s reclaimed, last access: %s, size: %s",
+			info.TaskID, info.PeerID, info.LastAccess.Format(time.RFC3339Nano),
+			units.BytesSize(float64(s.accountedUsage(info))))
+		s.releaseDiskUsageLocked(info.PeerTaskMetadata)
+	}
+	return nil
+}
+
+// accountedUsage returns the usage of task counted in usedBytes, diskUsageLock must be held
+func (s *storageManager) accountedUsage(info *TaskInfo) int64 {
+	if u, ok := s.diskUsages[info.PeerTaskMetadata]; ok {
+		return u.bytes()
+	}
+	return 0
+}
+
 func (s *storageManager) CleanUp() {
 	_, _ = s.forceGC()
 }
@@ -705,8 +1062,9 @@ func (s *storageManager) forceGC() (bool, error) {
 		meta := key.(PeerTaskMetadata)
 		s.tasks.Delete(meta)
 		s.cleanIndex(meta.TaskID, meta.PeerID)
-		task.(*localTaskStore).MarkReclaim()
-		err := task.(*localTaskStore).Reclaim()
+		s.releaseDiskUsage(meta)
+		task.(TaskStore).MarkReclaim()
+		err := task.(TaskStore).Reclaim()
 		if err != nil {
 			logger.Errorf("gc task store %s error: %s", key, err)
 		}
@@ -10,7 +10,9 @@ import (
 	reflect "reflect"
 	time "time"
 
+	clientutil "d7y.io/dragonfly/v2/client/clientutil"
 	peer "d7y.io/dragonfly/v2/client/daemon/peer"
+	storage "d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	base "d7y.io/dragonfly/v2/pkg/rpc/base"
 	scheduler "d7y.io/dragonfly/v2/pkg/rpc/scheduler"
@@ -40,6 +42,48 @@ func (m *MockTaskManager) EXPECT() *MockTaskManagerMockRecorder {
 	return m.recorder
 }
 
+// AnnouncePeerTask mocks base method.
+func (m *MockTaskManager) AnnouncePeerTask(ctx context.Context, meta storage.PeerTaskMetadata, url string, urlMeta *base.UrlMeta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnnouncePeerTask", ctx, meta, url, urlMeta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnnouncePeerTask indicates an expected call of AnnouncePeerTask.
+func (mr *MockTaskManagerMockRecorder) AnnouncePeerTask(ctx, meta, url, urlMeta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnnouncePeerTask", reflect.TypeOf((*MockTaskManager)(nil).AnnouncePeerTask), ctx, meta, url, urlMeta)
+}
+
+// FindCompletedTask mocks base method.
+func (m *MockTaskManager) FindCompletedTask(taskID string) *storage.ReusePeerTask {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCompletedTask", taskID)
+	ret0, _ := ret[0].(*storage.ReusePeerTask)
+	return ret0
+}
+
+// FindCompletedTask indicates an expected call of FindCompletedTask.
+func (mr *MockTaskManagerMockRecorder) FindCompletedTask(taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCompletedTask", reflect.TypeOf((*MockTaskManager)(nil).FindCompletedTask), taskID)
+}
+
+// GetPieceManager mocks base method.
+func (m *MockTaskManager) GetPieceManager() peer.PieceManager {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPieceManager")
+	ret0, _ := ret[0].(peer.PieceManager)
+	return ret0
+}
+
+// GetPieceManager indicates an expected call of GetPieceManager.
+func (mr *MockTaskManagerMockRecorder) GetPieceManager() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPieceManager", reflect.TypeOf((*MockTaskManager)(nil).GetPieceManager))
+}
+
 // IsPeerTaskRunning mocks base method.
 func (m *MockTaskManager) IsPeerTaskRunning(pid string) bool {
 	m.ctrl.T.Helper()
@@ -54,6 +98,21 @@ func (mr *MockTaskManagerMockRecorder) IsPeerTaskRunning(pid interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPeerTaskRunning", reflect.TypeOf((*MockTaskManager)(nil).IsPeerTaskRunning), pid)
 }
 
+// ReadCompletedTaskRange mocks base method.
+func (m *MockTaskManager) ReadCompletedTaskRange(ctx context.Context, meta storage.PeerTaskMetadata, rg clientutil.Range) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadCompletedTaskRange", ctx, meta, rg)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadCompletedTaskRange indicates an expected call of ReadCompletedTaskRange.
+func (mr *MockTaskManagerMockRecorder) ReadCompletedTaskRange(ctx, meta, rg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadCompletedTaskRange", reflect.TypeOf((*MockTaskManager)(nil).ReadCompletedTaskRange), ctx, meta, rg)
+}
+
 // StartFilePeerTask mocks base method.
 func (m *MockTaskManager) StartFilePeerTask(ctx context.Context, req *peer.FilePeerTaskRequest) (chan *peer.FilePeerTaskProgress, *peer.TinyData, error) {
 	m.ctrl.T.Helper()
@@ -71,7 +130,7 @@ func (mr *MockTaskManagerMockRecorder) StartFilePeerTask(ctx, req interface{}) *
 }
 
 // StartStreamPeerTask mocks base method.
-func (m *MockTaskManager) StartStreamPeerTask(ctx context.Context, req *scheduler.PeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+func (m *MockTaskManager) StartStreamPeerTask(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "StartStreamPeerTask", ctx, req)
 	ret0, _ := ret[0].(io.ReadCloser)
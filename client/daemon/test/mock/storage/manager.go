@@ -13,6 +13,7 @@ import (
 	storage "d7y.io/dragonfly/v2/client/daemon/storage"
 	base "d7y.io/dragonfly/v2/pkg/rpc/base"
 	gomock "github.com/golang/mock/gomock"
+	rate "golang.org/x/time/rate"
 )
 
 // MockTaskStorageDriver is a mock of TaskStorageDriver interface.
@@ -68,6 +69,35 @@ func (mr *MockTaskStorageDriverMockRecorder) IsInvalid(req interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsInvalid", reflect.TypeOf((*MockTaskStorageDriver)(nil).IsInvalid), req)
 }
 
+// IsVerified mocks base method.
+func (m *MockTaskStorageDriver) IsVerified(req *storage.PeerTaskMetadata) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsVerified", req)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsVerified indicates an expected call of IsVerified.
+func (mr *MockTaskStorageDriverMockRecorder) IsVerified(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsVerified", reflect.TypeOf((*MockTaskStorageDriver)(nil).IsVerified), req)
+}
+
+// MarkVerified mocks base method.
+func (m *MockTaskStorageDriver) MarkVerified(req *storage.PeerTaskMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkVerified", req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkVerified indicates an expected call of MarkVerified.
+func (mr *MockTaskStorageDriverMockRecorder) MarkVerified(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkVerified", reflect.TypeOf((*MockTaskStorageDriver)(nil).MarkVerified), req)
+}
+
 // ReadAllPieces mocks base method.
 func (m *MockTaskStorageDriver) ReadAllPieces(ctx context.Context, req *storage.PeerTaskMetadata) (io.ReadCloser, error) {
 	m.ctrl.T.Helper()
@@ -268,6 +298,20 @@ func (mr *MockManagerMockRecorder) CleanUp() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanUp", reflect.TypeOf((*MockManager)(nil).CleanUp))
 }
 
+// DeleteTask mocks base method.
+func (m *MockManager) DeleteTask(taskID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTask", taskID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTask indicates an expected call of DeleteTask.
+func (mr *MockManagerMockRecorder) DeleteTask(taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTask", reflect.TypeOf((*MockManager)(nil).DeleteTask), taskID)
+}
+
 // FindCompletedTask mocks base method.
 func (m *MockManager) FindCompletedTask(taskID string) *storage.ReusePeerTask {
 	m.ctrl.T.Helper()
@@ -282,6 +326,34 @@ func (mr *MockManagerMockRecorder) FindCompletedTask(taskID interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCompletedTask", reflect.TypeOf((*MockManager)(nil).FindCompletedTask), taskID)
 }
 
+// FindPartialTask mocks base method.
+func (m *MockManager) FindPartialTask(taskID string) *storage.PartialPeerTask {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPartialTask", taskID)
+	ret0, _ := ret[0].(*storage.PartialPeerTask)
+	return ret0
+}
+
+// FindPartialTask indicates an expected call of FindPartialTask.
+func (mr *MockManagerMockRecorder) FindPartialTask(taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPartialTask", reflect.TypeOf((*MockManager)(nil).FindPartialTask), taskID)
+}
+
+// FindTasks mocks base method.
+func (m *MockManager) FindTasks(taskID string) []*storage.TaskInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTasks", taskID)
+	ret0, _ := ret[0].([]*storage.TaskInfo)
+	return ret0
+}
+
+// FindTasks indicates an expected call of FindTasks.
+func (mr *MockManagerMockRecorder) FindTasks(taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTasks", reflect.TypeOf((*MockManager)(nil).FindTasks), taskID)
+}
+
 // GetPieces mocks base method.
 func (m *MockManager) GetPieces(ctx context.Context, req *base.PieceTaskRequest) (*base.PiecePacket, error) {
 	m.ctrl.T.Helper()
@@ -312,6 +384,21 @@ func (mr *MockManagerMockRecorder) IsInvalid(req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsInvalid", reflect.TypeOf((*MockManager)(nil).IsInvalid), req)
 }
 
+// IsVerified mocks base method.
+func (m *MockManager) IsVerified(req *storage.PeerTaskMetadata) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsVerified", req)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsVerified indicates an expected call of IsVerified.
+func (mr *MockManagerMockRecorder) IsVerified(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsVerified", reflect.TypeOf((*MockManager)(nil).IsVerified), req)
+}
+
 // Keep mocks base method.
 func (m *MockManager) Keep() {
 	m.ctrl.T.Helper()
@@ -324,6 +411,48 @@ func (mr *MockManagerMockRecorder) Keep() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keep", reflect.TypeOf((*MockManager)(nil).Keep))
 }
 
+// ListTasks mocks base method.
+func (m *MockManager) ListTasks() []*storage.TaskInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTasks")
+	ret0, _ := ret[0].([]*storage.TaskInfo)
+	return ret0
+}
+
+// ListTasks indicates an expected call of ListTasks.
+func (mr *MockManagerMockRecorder) ListTasks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasks", reflect.TypeOf((*MockManager)(nil).ListTasks))
+}
+
+// MarkVerified mocks base method.
+func (m *MockManager) MarkVerified(req *storage.PeerTaskMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkVerified", req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkVerified indicates an expected call of MarkVerified.
+func (mr *MockManagerMockRecorder) MarkVerified(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkVerified", reflect.TypeOf((*MockManager)(nil).MarkVerified), req)
+}
+
+// PinTask mocks base method.
+func (m *MockManager) PinTask(req storage.PinTaskRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinTask", req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinTask indicates an expected call of PinTask.
+func (mr *MockManagerMockRecorder) PinTask(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinTask", reflect.TypeOf((*MockManager)(nil).PinTask), req)
+}
+
 // ReadAllPieces mocks base method.
 func (m *MockManager) ReadAllPieces(ctx context.Context, req *storage.PeerTaskMetadata) (io.ReadCloser, error) {
 	m.ctrl.T.Helper()
@@ -369,6 +498,21 @@ func (mr *MockManagerMockRecorder) RegisterTask(ctx, req interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterTask", reflect.TypeOf((*MockManager)(nil).RegisterTask), ctx, req)
 }
 
+// ScrubTasks mocks base method.
+func (m *MockManager) ScrubTasks(ctx context.Context, limiter *rate.Limiter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScrubTasks", ctx, limiter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScrubTasks indicates an expected call of ScrubTasks.
+func (mr *MockManagerMockRecorder) ScrubTasks(ctx, limiter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScrubTasks", reflect.TypeOf((*MockManager)(nil).ScrubTasks), ctx, limiter)
+}
+
 // Store mocks base method.
 func (m *MockManager) Store(ctx context.Context, req *storage.StoreRequest) error {
 	m.ctrl.T.Helper()
@@ -383,6 +527,20 @@ func (mr *MockManagerMockRecorder) Store(ctx, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockManager)(nil).Store), ctx, req)
 }
 
+// UnregisterTask mocks base method.
+func (m *MockManager) UnregisterTask(ctx context.Context, req storage.CommonTaskRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnregisterTask", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnregisterTask indicates an expected call of UnregisterTask.
+func (mr *MockManagerMockRecorder) UnregisterTask(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnregisterTask", reflect.TypeOf((*MockManager)(nil).UnregisterTask), ctx, req)
+}
+
 // UpdateTask mocks base method.
 func (m *MockManager) UpdateTask(ctx context.Context, req *storage.UpdateTaskRequest) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,189 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+// rangeContentType is used in parts of multipart/byteranges response, the origin content type is unknown
+const rangeContentType = "application/octet-stream"
+
+// downloadRangeFromCompletedTask serves the ranges of request from a completed full task in local storage,
+// ok is false when there is no completed full task, the ranges should be downloaded as a new task.
+// meta must not contain the range, it is used for generating the task id of the full task.
+func (rt *transport) downloadRangeFromCompletedTask(req *http.Request, url string, peerID string,
+	meta *base.UrlMeta, rg string) (resp *http.Response, ok bool) {
+	taskID := idgen.TaskID(url, meta)
+	reuse := rt.peerTaskManager.FindCompletedTask(taskID)
+	if reuse == nil || reuse.ContentLength < 0 {
+		return nil, false
+	}
+	log := logger.With("peer", peerID, "task", taskID, "component", "transport")
+	log.Infof("serve range %q from completed peer task: %s, size: %d", rg, reuse.PeerID, reuse.ContentLength)
+
+	size := reuse.ContentLength
+	hdr := http.Header{}
+	hdr.Set(config.HeaderDragonflyTask, taskID)
+	hdr.Set(config.HeaderDragonflyPeer, peerID)
+	hdr.Set(headers.AcceptRanges, "bytes")
+
+	ranges, err := clientutil.ParseRange(rg, size)
+	if err != nil {
+		log.Warnf("parse range %q error: %s", rg, err)
+		hdr.Set(headers.ContentRange, fmt.Sprintf("bytes */%d", size))
+		metrics.PeerTaskReuseCount.Add(1)
+		return newResponse(req, http.StatusRequestedRangeNotSatisfiable, hdr, http.NoBody, 0), true
+	}
+	// same as net/http, ignore the ranges when the total size of ranges is larger than content
+	if sumRangesSize(ranges) > size {
+		ranges = nil
+	}
+
+	switch len(ranges) {
+	case 0:
+		body, err := rt.peerTaskManager.ReadCompletedTaskRange(req.Context(), reuse.PeerTaskMetadata,
+			clientutil.Range{Start: 0, Length: size})
+		if err != nil {
+			log.Errorf("read completed task error: %s", err)
+			return nil, false
+		}
+		resp = newResponse(req, http.StatusOK, hdr, body, size)
+	case 1:
+		body, err := rt.peerTaskManager.ReadCompletedTaskRange(req.Context(), reuse.PeerTaskMetadata, ranges[0])
+		if err != nil {
+			log.Errorf("read completed task range error: %s", err)
+			return nil, false
+		}
+		hdr.Set(headers.ContentRange, clientutil.GetContentRange(ranges[0].Start, ranges[0].Start+ranges[0].Length-1, size))
+		resp = newResponse(req, http.StatusPartialContent, hdr, body, ranges[0].Length)
+	default:
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		hdr.Set(headers.ContentType, "multipart/byteranges; boundary="+mw.Boundary())
+		go func() {
+			for _, r := range ranges {
+				part, err := mw.CreatePart(rangeMIMEHeader(r, size))
+				if err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				body, err := rt.peerTaskManager.ReadCompletedTaskRange(req.Context(), reuse.PeerTaskMetadata, r)
+				if err != nil {
+					log.Errorf("read completed task range error: %s", err)
+					pw.CloseWithError(err)
+					return
+				}
+				_, err = io.Copy(part, body)
+				body.Close()
+				if err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			}
+			mw.Close()
+			pw.Close()
+		}()
+		resp = newResponse(req, http.StatusPartialContent, hdr, pr, rangesMIMESize(ranges, size))
+	}
+	metrics.PeerTaskReuseCount.Add(1)
+	return resp, true
+}
+
+// rangeTaskContentRange returns the Content-Range of a range task, the range task only contains the bytes
+// of the range, so the total size is unknown. ok is false when the range is not a single range with start.
+func rangeTaskContentRange(rg string, contentLength int64) (string, bool) {
+	const b = "bytes="
+	if contentLength <= 0 || !strings.HasPrefix(rg, b) || strings.Contains(rg, ",") {
+		return "", false
+	}
+	spec := textproto.TrimString(rg[len(b):])
+	i := strings.Index(spec, "-")
+	// suffix range like "bytes=-100", the start is unknown
+	if i <= 0 {
+		return "", false
+	}
+	start, err := strconv.ParseInt(textproto.TrimString(spec[:i]), 10, 64)
+	if err != nil || start < 0 {
+		return "", false
+	}
+	return clientutil.GetContentRange(start, start+contentLength-1, -1), true
+}
+
+func newResponse(req *http.Request, code int, hdr http.Header, body io.ReadCloser, contentLength int64) *http.Response {
+	return &http.Response{
+		StatusCode:    code,
+		Body:          body,
+		Header:        hdr,
+		ContentLength: contentLength,
+
+		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
+	}
+}
+
+func sumRangesSize(ranges []clientutil.Range) (size int64) {
+	for _, r := range ranges {
+		size += r.Length
+	}
+	return
+}
+
+func rangeMIMEHeader(r clientutil.Range, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		headers.ContentRange: {clientutil.GetContentRange(r.Start, r.Start+r.Length-1, size)},
+		headers.ContentType:  {rangeContentType},
+	}
+}
+
+// countingWriter counts how many bytes have been written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// rangesMIMESize returns the number of bytes it takes to encode the provided ranges as a multipart response,
+// copy from net/http/fs.go
+func rangesMIMESize(ranges []clientutil.Range, size int64) (encSize int64) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	for _, r := range ranges {
+		_, _ = mw.CreatePart(rangeMIMEHeader(r, size))
+		encSize += r.Length
+	}
+	_ = mw.Close()
+	encSize += int64(w)
+	return
+}
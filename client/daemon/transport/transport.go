@@ -17,16 +17,22 @@
 package transport
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-http-utils/headers"
+	"github.com/golang/groupcache/lru"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
@@ -40,9 +46,14 @@ import (
 
 var _ *logger.SugaredLoggerOnWith // pin this package for no log code generation
 
+// maxContentLengths is the max count of the content lengths of p2p downloads kept for max p2p size of rules
+const maxContentLengths = 1000
+
 var (
 	// layerReg the regex to determine if it is an image download
 	layerReg = regexp.MustCompile("^.+/blobs/sha256.*$")
+	// blobDigestReg the regex to get the digest of an image blob
+	blobDigestReg = regexp.MustCompile("^.+/blobs/(sha256:[a-f0-9]{64})$")
 )
 
 // transport implements RoundTripper for dragonfly.
@@ -52,8 +63,9 @@ type transport struct {
 	// baseRoundTripper is an implementation of RoundTripper that supports HTTP
 	baseRoundTripper http.RoundTripper
 
-	// shouldUseDragonfly is used to determine to download resources with or without dragonfly
-	shouldUseDragonfly func(req *http.Request) bool
+	// condition is used to determine to download resources with or without dragonfly,
+	// it returns the matched proxy rule whose policies are applied to the request
+	condition func(req *http.Request) (*config.Proxy, bool)
 
 	// peerTaskManager is the peer task manager
 	peerTaskManager peer.TaskManager
@@ -69,6 +81,14 @@ type transport struct {
 
 	// dumpHTTPContent indicates to dump http request header and response header
 	dumpHTTPContent bool
+
+	// contentAddressedBlobs indicates to download image blobs as tasks keyed by digest
+	contentAddressedBlobs bool
+
+	// contentLengths are the content lengths of p2p downloads keyed by task id, they are used to check
+	// the max p2p size of rules without HEAD requests
+	contentLengths     *lru.Cache
+	contentLengthsLock sync.Mutex
 }
 
 // Option is functional config for transport.
@@ -101,7 +121,18 @@ func WithTLS(cfg *tls.Config) Option {
 // WithCondition configures how to decide whether to use dragonfly or not.
 func WithCondition(c func(r *http.Request) bool) Option {
 	return func(rt *transport) *transport {
-		rt.shouldUseDragonfly = c
+		rt.condition = func(r *http.Request) (*config.Proxy, bool) {
+			return nil, c(r)
+		}
+		return rt
+	}
+}
+
+// WithRuleCondition configures how to decide whether to use dragonfly or not with proxy rules,
+// the filter, tag, rate limit, max p2p size and stripped headers of the matched rule are applied
+func WithRuleCondition(c func(r *http.Request) (*config.Proxy, bool)) Option {
+	return func(rt *transport) *transport {
+		rt.condition = c
 		return rt
 	}
 }
@@ -129,12 +160,22 @@ func WithDumpHTTPContent(b bool) Option {
 	}
 }
 
+// WithContentAddressedBlobs downloads image blobs as tasks keyed by the digest in url,
+// the same blob from different repositories or registries shares one task
+func WithContentAddressedBlobs(b bool) Option {
+	return func(rt *transport) *transport {
+		rt.contentAddressedBlobs = b
+		return rt
+	}
+}
+
 // New constructs a new instance of a RoundTripper with additional options.
 func New(options ...Option) (http.RoundTripper, error) {
 	rt := &transport{
-		baseRoundTripper:   defaultHTTPTransport(nil),
-		shouldUseDragonfly: NeedUseDragonfly,
+		baseRoundTripper: defaultHTTPTransport(nil),
+		contentLengths:   lru.New(maxContentLengths),
 	}
+	WithCondition(NeedUseDragonfly)(rt)
 
 	for _, opt := range options {
 		opt(rt)
@@ -146,13 +187,22 @@ func New(options ...Option) (http.RoundTripper, error) {
 // RoundTrip only process first redirect at present
 // TODO: fix resource release
 func (rt *transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	if rt.shouldUseDragonfly(req) {
+	rule, useDragonfly := rt.condition(req)
+	if useDragonfly && rule != nil && rule.MaxP2PSize > 0 {
+		if length := rt.contentLength(req, rule); length > rule.MaxP2PSize {
+			logger.Debugf("content length %d of %s exceeds max p2p size %d, round trip directly",
+				length, req.URL.String(), rule.MaxP2PSize)
+			useDragonfly = false
+		}
+	}
+
+	if useDragonfly {
 		// delete the Accept-Encoding header to avoid returning the same cached
 		// result for different requests
 		req.Header.Del("Accept-Encoding")
 		logger.Debugf("round trip with dragonfly: %s", req.URL.String())
 		metrics.ProxyRequestViaDragonflyCount.Add(1)
-		resp, err = rt.download(req)
+		resp, err = rt.download(req, rule)
 	} else {
 		logger.Debugf("round trip directly, method: %s, url: %s", req.Method, req.URL.String())
 		req.Host = req.URL.Host
@@ -168,51 +218,183 @@ func (rt *transport) RoundTrip(req *http.Request) (resp *http.Response, err erro
 			Errorf("round trip error: %s", err)
 	}
 	rt.processDumpHTTPContent(req, resp)
+	if err == nil && rule != nil {
+		if limiter := rule.Limiter(); limiter != nil {
+			resp.Body = &limitedReadCloser{ReadCloser: resp.Body, ctx: req.Context(), limiter: limiter}
+		}
+	}
 	return resp, err
 }
 
-// NeedUseDragonfly is the default value for shouldUseDragonfly, which downloads all
+// contentLength returns the content length of the resource, it is -1 when unknown. The length of the completed
+// task or the response of last p2p download is used first, the HEAD request is sent to the source only when
+// neither is available, it carries the same headers as the task
+func (rt *transport) contentLength(req *http.Request, rule *config.Proxy) int64 {
+	url := req.URL.String()
+	meta, err := rt.urlMeta(req.Clone(req.Context()), rule)
+	if err != nil {
+		return -1
+	}
+	taskID := idgen.TaskID(url, meta)
+	if reuse := rt.peerTaskManager.FindCompletedTask(taskID); reuse != nil && reuse.ContentLength >= 0 {
+		return reuse.ContentLength
+	}
+	rt.contentLengthsLock.Lock()
+	length, ok := rt.contentLengths.Get(taskID)
+	rt.contentLengthsLock.Unlock()
+	if ok {
+		return length.(int64)
+	}
+
+	head, err := http.NewRequestWithContext(req.Context(), http.MethodHead, url, nil)
+	if err != nil {
+		return -1
+	}
+	head.Header = httputils.MapToHeader(meta.Header)
+	head.Header.Del(headers.Range)
+	head.Header.Del(headers.IfRange)
+	resp, err := rt.baseRoundTripper.RoundTrip(head)
+	if err != nil {
+		logger.Debugf("head %s error: %s", url, err)
+		return -1
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// storeContentLength keeps the content length of the p2p download of task
+func (rt *transport) storeContentLength(taskID string, length int64) {
+	rt.contentLengthsLock.Lock()
+	defer rt.contentLengthsLock.Unlock()
+	rt.contentLengths.Add(taskID, length)
+}
+
+// limitedReadCloser limits the read bytes per second with limiter, the burst of limiter is the max read size
+type limitedReadCloser struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if e := r.limiter.WaitN(r.ctx, n); e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+// CloseIdleConnections closes the idle connections of the base round tripper
+func (rt *transport) CloseIdleConnections() {
+	if c, ok := rt.baseRoundTripper.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// NeedUseDragonfly is the default condition of transport, which downloads all
 // images layers with dragonfly.
 func NeedUseDragonfly(req *http.Request) bool {
 	return req.Method == http.MethodGet && layerReg.MatchString(req.URL.Path)
 }
 
-// download uses dragonfly to download.
-func (rt *transport) download(req *http.Request) (*http.Response, error) {
-	url := req.URL.String()
-	peerID := idgen.PeerID(rt.peerHost.Ip)
-	log := logger.With("peer", peerID, "component", "transport")
-	log.Infof("start download with url: %s", url)
-
+// urlMeta returns the url meta of the task of req, the dragonfly and hop-by-hop headers are removed from req
+func (rt *transport) urlMeta(req *http.Request, rule *config.Proxy) (*base.UrlMeta, error) {
 	// Init meta value
 	meta := &base.UrlMeta{Header: map[string]string{}}
 
-	// Set meta range's value
-	if rg := req.Header.Get("Range"); len(rg) > 0 {
-		meta.Digest = ""
-		meta.Range = rg
+	// Pick header's parameters, the filter and tag of rule take precedence over the default ones
+	defaultFilter, defaultBiz := rt.defaultFilter, rt.defaultBiz
+	if rule != nil && rule.Filter != "" {
+		defaultFilter = rule.Filter
+	}
+	if rule != nil && rule.Tag != "" {
+		defaultBiz = rule.Tag
+	}
+	filter := httputils.PickHeader(req.Header, config.HeaderDragonflyFilter, defaultFilter)
+	tag := httputils.PickHeader(req.Header, config.HeaderDragonflyBiz, defaultBiz)
+	if sigURL := httputils.PickHeader(req.Header, config.HeaderDragonflySignatureURL, ""); sigURL != "" {
+		meta.Signature = &base.Signature{
+			Type: httputils.PickHeader(req.Header, config.HeaderDragonflySignatureType, ""),
+			Url:  sigURL,
+		}
+		if err := meta.Signature.Validate(); err != nil {
+			return nil, err
+		}
 	}
-
-	// Pick header's parameters
-	filter := httputils.PickHeader(req.Header, config.HeaderDragonflyFilter, rt.defaultFilter)
-	tag := httputils.PickHeader(req.Header, config.HeaderDragonflyBiz, rt.defaultBiz)
 
 	// Delete hop-by-hop headers
 	delHopHeaders(req.Header)
 
 	meta.Header = httputils.HeaderToMap(req.Header)
+	if rule != nil {
+		for _, h := range rule.StripHeaders {
+			delete(meta.Header, http.CanonicalHeaderKey(h))
+		}
+	}
 	meta.Tag = tag
 	meta.Filter = filter
+	if rt.contentAddressedBlobs {
+		if match := blobDigestReg.FindStringSubmatch(req.URL.Path); match != nil {
+			meta.Digest = match[1]
+			meta.ContentAddressed = true
+		}
+	}
+	return meta, nil
+}
+
+// download uses dragonfly to download, the policies of rule are applied when it is not nil.
+func (rt *transport) download(req *http.Request, rule *config.Proxy) (*http.Response, error) {
+	url := req.URL.String()
+	peerID := idgen.PeerID(rt.peerHost.Ip)
+	log := logger.With("peer", peerID, "component", "transport")
+	log.Infof("start download with url: %s", url)
+
+	meta, err := rt.urlMeta(req, rule)
+	if err != nil {
+		return nil, err
+	}
 
+	rg := req.Header.Get(headers.Range)
+	if len(rg) > 0 && meta.Signature != nil {
+		// the signature is signed for the whole content, the range of it can not be verified
+		return nil, errors.New("range request is not supported with signature verification")
+	}
+	if len(rg) > 0 {
+		// try to serve the range from completed full task, the validator of If-Range is unknown, skip it
+		if req.Header.Get(headers.IfRange) == "" {
+			if resp, ok := rt.downloadRangeFromCompletedTask(req, url, peerID, meta, rg); ok {
+				return resp, nil
+			}
+		}
+		// Set meta range's value, fallback to download the range as a new task
+		meta.Digest = ""
+		meta.Range = rg
+	}
+
+	var pattern string
+	if rule != nil {
+		pattern = rule.Pattern
+	}
 	body, attr, err := rt.peerTaskManager.StartStreamPeerTask(
 		req.Context(),
-		&scheduler.PeerTaskRequest{
-			Url:         url,
-			UrlMeta:     meta,
-			PeerId:      peerID,
-			PeerHost:    rt.peerHost,
-			HostLoad:    nil,
-			IsMigrating: false,
+		&peer.StreamPeerTaskRequest{
+			PeerTaskRequest: scheduler.PeerTaskRequest{
+				Url:         url,
+				UrlMeta:     meta,
+				PeerId:      peerID,
+				PeerHost:    rt.peerHost,
+				HostLoad:    nil,
+				IsMigrating: false,
+				Pattern:     pattern,
+			},
 		},
 	)
 	if err != nil {
@@ -234,18 +416,18 @@ func (rt *transport) download(req *http.Request) (*http.Response, error) {
 			contentLength = i
 		}
 	}
+	if rule != nil && rule.MaxP2PSize > 0 && len(rg) == 0 && contentLength >= 0 {
+		rt.storeContentLength(idgen.TaskID(url, meta), contentLength)
+	}
 
-	resp := &http.Response{
-		StatusCode:    http.StatusOK,
-		Body:          body,
-		Header:        hdr,
-		ContentLength: contentLength,
-
-		Proto:      req.Proto,
-		ProtoMajor: req.ProtoMajor,
-		ProtoMinor: req.ProtoMinor,
+	code := http.StatusOK
+	if len(rg) > 0 {
+		if contentRange, ok := rangeTaskContentRange(rg, contentLength); ok {
+			code = http.StatusPartialContent
+			hdr.Set(headers.ContentRange, contentRange)
+		}
 	}
-	return resp, nil
+	return newResponse(req, code, hdr, body, contentLength), nil
 }
 
 func (rt *transport) processDumpHTTPContent(req *http.Request, resp *http.Response) {
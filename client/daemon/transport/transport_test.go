@@ -19,16 +19,27 @@ package transport
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/go-http-utils/headers"
 	"github.com/golang/mock/gomock"
 	testifyassert "github.com/stretchr/testify/assert"
 
+	"d7y.io/dragonfly/v2/client/clientutil"
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/peer"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
 	mock_peer "d7y.io/dragonfly/v2/client/daemon/test/mock/peer"
+	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler"
 )
 
@@ -45,7 +56,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 	var url = "http://x/y"
 	peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
 	peerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
-		func(ctx context.Context, req *scheduler.PeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+		func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
 			assert.Equal(req.Url, url)
 			return io.NopCloser(bytes.NewBuffer(testData)), nil, nil
 		},
@@ -71,3 +82,282 @@ func TestTransport_RoundTrip(t *testing.T) {
 	}
 	assert.Equal(testData, output)
 }
+
+func TestTransport_RoundTripContentAddressedBlob(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	var (
+		digest  = "sha256:" + strings.Repeat("a", 64)
+		taskIDs []string
+	)
+	peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	peerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+			assert.Equal(digest, req.UrlMeta.Digest)
+			assert.True(req.UrlMeta.ContentAddressed)
+			taskIDs = append(taskIDs, idgen.TaskID(req.Url, req.UrlMeta))
+			return io.NopCloser(bytes.NewBufferString("layer")), nil, nil
+		},
+	).Times(2)
+	rt, _ := New(
+		WithPeerHost(&scheduler.PeerHost{}),
+		WithPeerTaskManager(peerTaskManager),
+		WithContentAddressedBlobs(true))
+
+	for _, url := range []string{
+		"https://index.docker.io/v2/library/alpine/blobs/" + digest,
+		"https://ghcr.io/v2/dragonflyoss/alpine/blobs/" + digest,
+	} {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		resp, err := rt.RoundTrip(req)
+		assert.Nil(err)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+	}
+	assert.Len(taskIDs, 2)
+	assert.Equal(taskIDs[0], taskIDs[1])
+}
+
+func TestTransport_RoundTripRange(t *testing.T) {
+	testData, err := os.ReadFile(test.File)
+	testifyassert.Nil(t, err, "load test file")
+	size := int64(len(testData))
+
+	var url = "http://x/y"
+	tests := []struct {
+		name         string
+		rg           string
+		completed    bool
+		code         int
+		contentRange string
+		expect       []byte
+		multipart    [][]byte
+	}{
+		{
+			name:         "single range from completed task",
+			rg:           "bytes=10-19",
+			completed:    true,
+			code:         http.StatusPartialContent,
+			contentRange: fmt.Sprintf("bytes 10-19/%d", size),
+			expect:       testData[10:20],
+		},
+		{
+			name:         "suffix range from completed task",
+			rg:           "bytes=-10",
+			completed:    true,
+			code:         http.StatusPartialContent,
+			contentRange: fmt.Sprintf("bytes %d-%d/%d", size-10, size-1, size),
+			expect:       testData[size-10:],
+		},
+		{
+			name:      "multiple ranges from completed task",
+			rg:        "bytes=0-4,100-109",
+			completed: true,
+			code:      http.StatusPartialContent,
+			multipart: [][]byte{testData[0:5], testData[100:110]},
+		},
+		{
+			name:         "range not satisfiable",
+			rg:           fmt.Sprintf("bytes=%d-", size),
+			completed:    true,
+			code:         http.StatusRequestedRangeNotSatisfiable,
+			contentRange: fmt.Sprintf("bytes */%d", size),
+			expect:       []byte{},
+		},
+		{
+			name:         "fallback to range task",
+			rg:           "bytes=10-19",
+			completed:    false,
+			code:         http.StatusPartialContent,
+			contentRange: "bytes 10-19/*",
+			expect:       testData[10:20],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+			if tc.completed {
+				meta := storage.PeerTaskMetadata{PeerID: "peer", TaskID: "task"}
+				peerTaskManager.EXPECT().FindCompletedTask(gomock.Any()).Return(&storage.ReusePeerTask{
+					PeerTaskMetadata: meta,
+					ContentLength:    size,
+				})
+				peerTaskManager.EXPECT().ReadCompletedTaskRange(gomock.Any(), meta, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, meta storage.PeerTaskMetadata, rg clientutil.Range) (io.ReadCloser, error) {
+						return io.NopCloser(bytes.NewBuffer(testData[rg.Start : rg.Start+rg.Length])), nil
+					},
+				).AnyTimes()
+			} else {
+				peerTaskManager.EXPECT().FindCompletedTask(gomock.Any()).Return(nil)
+				peerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+						assert.Equal(tc.rg, req.UrlMeta.Range)
+						return io.NopCloser(bytes.NewBuffer(testData[10:20])), map[string]string{
+							headers.ContentLength: "10",
+						}, nil
+					},
+				)
+			}
+			rt, _ := New(
+				WithPeerHost(&scheduler.PeerHost{}),
+				WithPeerTaskManager(peerTaskManager),
+				WithCondition(func(r *http.Request) bool {
+					return true
+				}))
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+			req.Header.Set(headers.Range, tc.rg)
+			resp, err := rt.RoundTrip(req)
+			assert.Nil(err)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(tc.code, resp.StatusCode)
+			assert.Equal(tc.contentRange, resp.Header.Get(headers.ContentRange))
+
+			if tc.multipart != nil {
+				_, params, err := mime.ParseMediaType(resp.Header.Get(headers.ContentType))
+				assert.Nil(err)
+				reader := multipart.NewReader(resp.Body, params["boundary"])
+				for _, expect := range tc.multipart {
+					part, err := reader.NextPart()
+					assert.Nil(err)
+					output, err := io.ReadAll(part)
+					assert.Nil(err)
+					assert.Equal(expect, output)
+				}
+				_, err = reader.NextPart()
+				assert.Equal(io.EOF, err)
+				return
+			}
+			output, err := io.ReadAll(resp.Body)
+			assert.Nil(err)
+			assert.Equal(tc.expect, output)
+			assert.Equal(int64(len(tc.expect)), resp.ContentLength)
+		})
+	}
+}
+
+func TestTransport_RoundTripRule(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	rule := &config.Proxy{
+		Filter:       "Expires&Signature",
+		Tag:          "d7y/blobs",
+		Pattern:      config.PatternCDN,
+		RateLimit:    clientutil.RateLimit{Limit: 1024},
+		StripHeaders: []string{"authorization"},
+	}
+	peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	peerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+			assert.Equal(rule.Filter, req.UrlMeta.Filter)
+			assert.Equal(rule.Tag, req.UrlMeta.Tag)
+			assert.Equal(rule.Pattern, req.Pattern)
+			assert.NotContains(req.UrlMeta.Header, "Authorization")
+			assert.Equal("bar", req.UrlMeta.Header["X-Foo"])
+			return io.NopCloser(bytes.NewBufferString("data")), nil, nil
+		},
+	)
+	rt, _ := New(
+		WithPeerHost(&scheduler.PeerHost{}),
+		WithPeerTaskManager(peerTaskManager),
+		WithRuleCondition(func(r *http.Request) (*config.Proxy, bool) {
+			return rule, true
+		}))
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://x/y?Expires=1&Signature=2", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Foo", "bar")
+	resp, err := rt.RoundTrip(req)
+	assert.Nil(err)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	assert.IsType(&limitedReadCloser{}, resp.Body)
+	output, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Equal("data", string(output))
+}
+
+func TestTransport_RoundTripMaxP2PSize(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+
+	data := strings.Repeat("a", 1024)
+	var heads, gets int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", len(data)))
+		if r.Method == http.MethodHead {
+			heads++
+			assert.Empty(r.Header.Get("Authorization"), "stripped headers are not sent")
+			assert.Equal("bar", r.Header.Get("X-Foo"))
+			return
+		}
+		gets++
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	rule := &config.Proxy{MaxP2PSize: 512, StripHeaders: []string{"authorization"}}
+	peerTaskManager := mock_peer.NewMockTaskManager(ctrl)
+	rt, _ := New(
+		WithPeerHost(&scheduler.PeerHost{}),
+		WithPeerTaskManager(peerTaskManager),
+		WithRuleCondition(func(r *http.Request) (*config.Proxy, bool) {
+			return rule, true
+		}))
+	roundTrip := func() {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+"/blob", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-Foo", "bar")
+		resp, err := rt.RoundTrip(req)
+		assert.Nil(err)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		output, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		assert.Equal(data, string(output))
+	}
+
+	// the content length is got by HEAD request, it exceeds max p2p size
+	peerTaskManager.EXPECT().FindCompletedTask(gomock.Any()).Return(nil)
+	roundTrip()
+	assert.Equal(1, heads)
+	assert.Equal(1, gets)
+
+	// the content length of the p2p download is used without HEAD request
+	rule.MaxP2PSize = 2048
+	peerTaskManager.EXPECT().FindCompletedTask(gomock.Any()).Return(nil).Times(2)
+	peerTaskManager.EXPECT().StartStreamPeerTask(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *peer.StreamPeerTaskRequest) (io.ReadCloser, map[string]string, error) {
+			return io.NopCloser(strings.NewReader(data)), map[string]string{
+				headers.ContentLength: fmt.Sprintf("%d", len(data)),
+			}, nil
+		},
+	).Times(2)
+	roundTrip()
+	assert.Equal(2, heads)
+	roundTrip()
+	assert.Equal(2, heads)
+	assert.Equal(1, gets)
+
+	// the content length of the completed task is used without HEAD request
+	rule.MaxP2PSize = 512
+	peerTaskManager.EXPECT().FindCompletedTask(gomock.Any()).Return(&storage.ReusePeerTask{ContentLength: int64(len(data))})
+	roundTrip()
+	assert.Equal(2, heads)
+	assert.Equal(2, gets)
+}
@@ -0,0 +1,147 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upload
+
+import (
+	"container/list"
+	"sync"
+
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+)
+
+const (
+	defaultMinAccessCount = 2
+	// maxTrackedPieces limits the access counters of the pieces not cached
+	maxTrackedPieces = 64 * 1024
+)
+
+type pieceCacheKey struct {
+	taskID string
+	peerID string
+	start  int64
+	length int64
+}
+
+type pieceCacheEntry struct {
+	key  pieceCacheKey
+	data []byte
+}
+
+type pieceAccess struct {
+	key   pieceCacheKey
+	count int
+}
+
+// pieceCache is a bounded in-memory lru cache for the uploading pieces,
+// a piece is admitted only after it is accessed at least minAccessCount times
+type pieceCache struct {
+	sync.Mutex
+	capacity       int64
+	size           int64
+	minAccessCount int
+
+	// entries and lru hold the cached pieces, the front of lru is the most recently used
+	entries map[pieceCacheKey]*list.Element
+	lru     *list.List
+
+	// accesses and accessLRU count the accesses of the pieces not cached
+	accesses  map[pieceCacheKey]*list.Element
+	accessLRU *list.List
+}
+
+func newPieceCache(capacity int64, minAccessCount int) *pieceCache {
+	if minAccessCount <= 0 {
+		minAccessCount = defaultMinAccessCount
+	}
+	return &pieceCache{
+		capacity:       capacity,
+		minAccessCount: minAccessCount,
+		entries:        map[pieceCacheKey]*list.Element{},
+		lru:            list.New(),
+		accesses:       map[pieceCacheKey]*list.Element{},
+		accessLRU:      list.New(),
+	}
+}
+
+// get returns the cached piece data, when not cached, admit indicates whether the piece
+// is accessed frequently enough to be cached
+func (c *pieceCache) get(key pieceCacheKey) (data []byte, admit bool) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok {
+		metrics.UploadPieceCacheHitCount.Add(1)
+		c.lru.MoveToFront(e)
+		return e.Value.(*pieceCacheEntry).data, false
+	}
+	metrics.UploadPieceCacheMissCount.Add(1)
+	if key.length > c.capacity {
+		return nil, false
+	}
+
+	var access *pieceAccess
+	if e, ok := c.accesses[key]; ok {
+		c.accessLRU.MoveToFront(e)
+		access = e.Value.(*pieceAccess)
+	} else {
+		access = &pieceAccess{key: key}
+		c.accesses[key] = c.accessLRU.PushFront(access)
+		if c.accessLRU.Len() > maxTrackedPieces {
+			c.removeAccess(c.accessLRU.Back())
+		}
+	}
+	access.count++
+	return nil, access.count >= c.minAccessCount
+}
+
+// put caches the piece data and evicts the least recently used pieces when exceeding capacity
+func (c *pieceCache) put(key pieceCacheKey, data []byte) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[key]; ok || int64(len(data)) > c.capacity {
+		return
+	}
+	if e, ok := c.accesses[key]; ok {
+		c.removeAccess(e)
+	}
+	c.entries[key] = c.lru.PushFront(&pieceCacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+	for c.size > c.capacity {
+		c.removeEntry(c.lru.Back())
+	}
+	metrics.UploadPieceCacheBytes.Set(float64(c.size))
+}
+
+// remove removes the piece from cache, eg: the task is deleted from storage
+func (c *pieceCache) remove(key pieceCacheKey) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.removeEntry(e)
+		metrics.UploadPieceCacheBytes.Set(float64(c.size))
+	}
+}
+
+func (c *pieceCache) removeEntry(e *list.Element) {
+	entry := c.lru.Remove(e).(*pieceCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
+
+func (c *pieceCache) removeAccess(e *list.Element) {
+	access := c.accessLRU.Remove(e).(*pieceAccess)
+	delete(c.accesses, access.key)
+}
@@ -0,0 +1,73 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upload
+
+import (
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestPieceCache(t *testing.T) {
+	assert := testifyassert.New(t)
+	cache := newPieceCache(16, 2)
+
+	newKey := func(start int64) pieceCacheKey {
+		return pieceCacheKey{
+			taskID: "task",
+			peerID: "peer",
+			start:  start,
+			length: 8,
+		}
+	}
+
+	// first access, not admitted
+	data, admit := cache.get(newKey(0))
+	assert.Nil(data)
+	assert.False(admit)
+
+	// second access, admitted
+	data, admit = cache.get(newKey(0))
+	assert.Nil(data)
+	assert.True(admit)
+	cache.put(newKey(0), []byte("01234567"))
+
+	data, _ = cache.get(newKey(0))
+	assert.Equal([]byte("01234567"), data)
+
+	cache.put(newKey(8), []byte("89abcdef"))
+	assert.Equal(int64(16), cache.size)
+
+	// touch the first piece, then the second piece is the least recently used one
+	cache.get(newKey(0))
+	cache.put(newKey(16), []byte("ghijklmn"))
+	assert.Equal(int64(16), cache.size)
+	data, _ = cache.get(newKey(8))
+	assert.Nil(data, "evicted")
+	data, _ = cache.get(newKey(0))
+	assert.NotNil(data)
+
+	// larger than capacity, never admitted
+	cache.get(pieceCacheKey{length: 32})
+	_, admit = cache.get(pieceCacheKey{length: 32})
+	assert.False(admit)
+
+	cache.remove(newKey(0))
+	data, _ = cache.get(newKey(0))
+	assert.Nil(data)
+	assert.Equal(int64(8), cache.size)
+}
@@ -17,6 +17,7 @@
 package upload
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -44,6 +45,9 @@ type uploadManager struct {
 	*http.Server
 	*rate.Limiter
 	StorageManager storage.Manager
+
+	// pieceCache caches the hot pieces in memory, it is nil when disabled
+	pieceCache *pieceCache
 }
 
 var _ Manager = (*uploadManager)(nil)
@@ -71,6 +75,17 @@ func WithLimiter(limiter *rate.Limiter) func(*uploadManager) {
 	}
 }
 
+// WithPieceCache enables the in-memory piece cache with capacity in bytes, zero capacity disables it,
+// a piece is cached after it is uploaded at least minAccessCount times
+func WithPieceCache(capacity int64, minAccessCount int) func(*uploadManager) {
+	return func(manager *uploadManager) {
+		if capacity <= 0 {
+			return
+		}
+		manager.pieceCache = newPieceCache(capacity, minAccessCount)
+	}
+}
+
 func (um *uploadManager) initRouter() {
 	r := mux.NewRouter()
 	r.HandleFunc(PeerDownloadHTTPPathPrefix+"{taskPrefix:.*}/"+"{task:.*}", um.handleUpload).Queries("peerId", "{.*}").Methods("GET")
@@ -107,25 +122,68 @@ func (um *uploadManager) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	meta := storage.PeerTaskMetadata{
+		TaskID: task,
+		PeerID: peer,
+	}
+	// the task with signature is not shared before it is verified
+	if verified, err := um.StorageManager.IsVerified(&meta); err == nil && !verified {
+		sLogger.Warnf("task signature is not verified, refuse to upload")
+		http.Error(w, storage.ErrTaskNotVerified.Error(), http.StatusForbidden)
+		return
+	}
+	key := pieceCacheKey{
+		taskID: task,
+		peerID: peer,
+		start:  rg[0].Start,
+		length: rg[0].Length,
+	}
 	// add header "Content-Length" to avoid chunked body in http client
 	w.Header().Add(headers.ContentLength, fmt.Sprintf("%d", rg[0].Length))
-	reader, closer, err := um.StorageManager.ReadPiece(r.Context(),
-		&storage.ReadPieceRequest{
-			PeerTaskMetadata: storage.PeerTaskMetadata{
-				TaskID: task,
-				PeerID: peer,
-			},
-			PieceMetadata: storage.PieceMetadata{
-				Num:   -1,
-				Range: rg[0],
-			},
-		})
-	if err != nil {
-		sLogger.Errorf("get task data failed: %s", err)
-		http.Error(w, fmt.Sprintf("get piece data error: %s", err), http.StatusInternalServerError)
-		return
+	var (
+		reader io.Reader
+		admit  bool
+	)
+	if um.pieceCache != nil {
+		var data []byte
+		data, admit = um.pieceCache.get(key)
+		if data != nil {
+			// the task may be deleted or invalid after the piece cached
+			if invalid, err := um.StorageManager.IsInvalid(&meta); err == nil && !invalid {
+				reader = bytes.NewReader(data)
+			} else {
+				um.pieceCache.remove(key)
+			}
+		}
+	}
+	if reader == nil {
+		pieceReader, closer, err := um.StorageManager.ReadPiece(r.Context(),
+			&storage.ReadPieceRequest{
+				PeerTaskMetadata: meta,
+				PieceMetadata: storage.PieceMetadata{
+					Num:   -1,
+					Range: rg[0],
+				},
+			})
+		if err != nil {
+			sLogger.Errorf("get task data failed: %s", err)
+			http.Error(w, fmt.Sprintf("get piece data error: %s", err), http.StatusInternalServerError)
+			return
+		}
+		defer closer.Close()
+		reader = pieceReader
+
+		if admit {
+			data := make([]byte, rg[0].Length)
+			if _, err = io.ReadFull(pieceReader, data); err != nil {
+				sLogger.Errorf("read piece data for cache failed: %s", err)
+				http.Error(w, fmt.Sprintf("read piece data error: %s", err), http.StatusInternalServerError)
+				return
+			}
+			um.pieceCache.put(key, data)
+			reader = bytes.NewReader(data)
+		}
 	}
-	defer closer.Close()
 	if um.Limiter != nil {
 		if err = um.Limiter.WaitN(r.Context(), int(rg[0].Length)); err != nil {
 			sLogger.Errorf("get limit failed: %s", err)
@@ -49,6 +49,10 @@ func TestUploadManager_Serve(t *testing.T) {
 	assert.Nil(err, "load test file")
 
 	mockStorageManager := mock_storage.NewMockManager(ctrl)
+	mockStorageManager.EXPECT().IsVerified(gomock.Any()).AnyTimes().
+		DoAndReturn(func(req *storage.PeerTaskMetadata) (bool, error) {
+			return req.TaskID != "task-unverified", nil
+		})
 	mockStorageManager.EXPECT().ReadPiece(gomock.Any(), gomock.Any()).AnyTimes().
 		DoAndReturn(func(ctx context.Context, req *storage.ReadPieceRequest) (io.Reader, io.Closer, error) {
 			return bytes.NewBuffer(testData[req.Range.Start : req.Range.Start+req.Range.Length]),
@@ -106,4 +110,13 @@ func TestUploadManager_Serve(t *testing.T) {
 		resp.Body.Close()
 		assert.Equal(tt.targetPieceData, data)
 	}
+
+	// the task with signature is not uploaded before it is verified
+	req, _ := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("http://%s%s%s/%s?peerId=%s", addr, PeerDownloadHTTPPathPrefix, "666", "task-unverified", "peer-3"), nil)
+	req.Header.Add("Range", "bytes=0-9")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(err, "get piece data")
+	resp.Body.Close()
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
 }
@@ -0,0 +1,112 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+	"d7y.io/dragonfly/v2/pkg/util/net/urlutils"
+)
+
+// CacheTaskID returns the task id of the argument, the argument is a task id or an url.
+// When it is an url, the url meta is generated from tag, filter, digest and headers in cfg,
+// just like downloading it.
+func CacheTaskID(arg string, cfg *config.DfgetConfig) string {
+	if !urlutils.IsValidURL(arg) {
+		return arg
+	}
+	c := *cfg
+	c.URL = arg
+	request := newDownRequest(&c, parseHeader(c.Header))
+	return idgen.TaskID(request.Url, request.UrlMeta)
+}
+
+// ListCache prints all tasks in the daemon storage.
+func ListCache(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr, w io.Writer) error {
+	result, err := client.ListTasks(ctx, target, &dfdaemon.ListTasksRequest{})
+	if err != nil {
+		return err
+	}
+	printTaskInfos(w, result.Tasks)
+	return nil
+}
+
+// StatCache prints the task stores of the task in the daemon storage.
+func StatCache(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr, taskID string, w io.Writer) error {
+	result, err := client.StatTask(ctx, target, &dfdaemon.StatTaskRequest{TaskId: taskID})
+	if err != nil {
+		return err
+	}
+	for _, task := range result.Tasks {
+		fmt.Fprintf(w, "task id: %s\n", task.TaskId)
+		fmt.Fprintf(w, "peer id: %s\n", task.PeerId)
+		fmt.Fprintf(w, "url: %s\n", task.Url)
+		if meta := task.UrlMeta; meta != nil {
+			fmt.Fprintf(w, "digest: %s\n", meta.Digest)
+			fmt.Fprintf(w, "tag: %s\n", meta.Tag)
+			fmt.Fprintf(w, "range: %s\n", meta.Range)
+			fmt.Fprintf(w, "filter: %s\n", meta.Filter)
+		}
+		fmt.Fprintf(w, "content length: %d\n", task.ContentLength)
+		fmt.Fprintf(w, "pieces: %d/%d\n", task.CompletedPiece, task.TotalPiece)
+		fmt.Fprintf(w, "done: %t\n", task.Done)
+		fmt.Fprintf(w, "pinned: %t\n", task.Pinned)
+		fmt.Fprintf(w, "priority: %d\n", task.Priority)
+		fmt.Fprintf(w, "last access: %s\n\n", time.Unix(0, task.LastAccessTime).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// DeleteCache evicts the task from the daemon storage.
+func DeleteCache(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr, taskID string) error {
+	return client.DeleteTask(ctx, target, &dfdaemon.DeleteTaskRequest{TaskId: taskID})
+}
+
+// PinCache pins or unpins the task in the daemon storage, pinned task is never reclaimed by gc.
+func PinCache(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr, taskID string, pinned bool, priority int32) error {
+	return client.PinTask(ctx, target, &dfdaemon.PinTaskRequest{
+		TaskId:   taskID,
+		Pinned:   pinned,
+		Priority: priority,
+	})
+}
+
+func printTaskInfos(w io.Writer, tasks []*dfdaemon.TaskInfo) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK ID\tPEER ID\tSIZE\tPIECES\tDONE\tPINNED\tPRIORITY\tLAST ACCESS\tURL")
+	for _, task := range tasks {
+		size := "unknown"
+		if task.ContentLength >= 0 {
+			size = units.BytesSize(float64(task.ContentLength))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%t\t%t\t%d\t%s\t%s\n",
+			task.TaskId, task.PeerId, size, task.CompletedPiece, task.TotalPiece, task.Done, task.Pinned, task.Priority,
+			time.Unix(0, task.LastAccessTime).Format(time.RFC3339), task.Url)
+	}
+	_ = tw.Flush()
+}
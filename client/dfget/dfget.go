@@ -26,12 +26,16 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/schollz/progressbar/v3"
+	"go.uber.org/atomic"
 
+	"d7y.io/dragonfly/v2/client/clientutil"
 	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dferrors"
 	"d7y.io/dragonfly/v2/internal/dfheaders"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/basic"
@@ -52,7 +56,7 @@ func Download(cfg *config.DfgetConfig, client daemonclient.DaemonClient) error {
 	)
 
 	wLog.Info("init success and start to download")
-	fmt.Println("init success and start to download")
+	fmt.Fprintln(MessageWriter(cfg), "init success and start to download")
 
 	if cfg.Timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
@@ -68,15 +72,40 @@ func Download(cfg *config.DfgetConfig, client daemonclient.DaemonClient) error {
 	<-ctx.Done()
 
 	if ctx.Err() == context.DeadlineExceeded {
-		return errors.Errorf("download timeout(%s)", cfg.Timeout)
+		return errors.Wrapf(ctx.Err(), "download timeout(%s)", cfg.Timeout)
 	}
 	return downError
 }
 
+// EmitResult emits the last event of dfget with the exit code of err, start is the time dfget started
+func EmitResult(cfg *config.DfgetConfig, start time.Time, err error) {
+	event := &Event{
+		Type:     EventTypeResult,
+		ExitCode: ExitCode(err),
+	}
+	if cfg.Manifest != "" {
+		event.URL = cfg.Manifest
+	}
+	if err != nil {
+		event.Code, event.Error = errorCode(err), err.Error()
+	}
+	event.setCost(start)
+	emitEvent(cfg, event)
+}
+
 func download(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig, wLog *logger.SugaredLoggerOnWith) error {
+	if cfg.Sync {
+		return syncDownload(ctx, client, cfg)
+	}
 	if cfg.Recursive {
 		return recursiveDownload(ctx, client, cfg)
 	}
+	if cfg.IsStdout() {
+		return streamDownload(ctx, client, cfg, os.Stdout, wLog)
+	}
+	if cfg.Extract != "" {
+		return extractDownload(ctx, client, cfg, wLog)
+	}
 	return singleDownload(ctx, client, cfg, wLog)
 }
 
@@ -84,7 +113,11 @@ func singleDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *
 	hdr := parseHeader(cfg.Header)
 
 	if client == nil {
-		return downloadFromSource(ctx, cfg, hdr)
+		err := downloadFromSource(ctx, cfg, hdr)
+		if err != nil {
+			emitEvent(cfg, &Event{Type: EventTypeFailed, Error: err.Error()})
+		}
+		return err
 	}
 
 	var (
@@ -101,6 +134,7 @@ func singleDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *
 			pb = newProgressBar(-1)
 		}
 
+		var lastEvent time.Time
 		for {
 			if result, downError = stream.Recv(); downError != nil {
 				break
@@ -118,24 +152,56 @@ func singleDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *
 				}
 
 				wLog.Infof("download from daemon success, length: %d bytes cost: %d ms", result.CompletedLength, time.Now().Sub(start).Milliseconds())
-				fmt.Printf("finish total length %d bytes\n", result.CompletedLength)
+				fmt.Fprintf(MessageWriter(cfg), "finish total length %d bytes\n", result.CompletedLength)
 
+				event := newResultEvent(result, start)
+				event.Type, event.Digest = EventTypeDone, outputDigest(cfg)
+				emitEvent(cfg, event)
 				break
 			}
+
+			// limit the progress events to one per second
+			if time.Since(lastEvent) >= time.Second {
+				lastEvent = time.Now()
+				emitEvent(cfg, newResultEvent(result, start))
+			}
 		}
 	}
 
 	if downError != nil {
 		wLog.Warnf("daemon downloads file error: %v", downError)
-		fmt.Printf("daemon downloads file error: %v\n", downError)
-		downError = downloadFromSource(ctx, cfg, hdr)
+		fmt.Fprintf(MessageWriter(cfg), "daemon downloads file error: %v\n", downError)
+		// keep the daemon error for the exit code
+		if backSourceDisabled(cfg) {
+			downError = errors.Wrap(downError, "back source is disabled")
+		} else {
+			downError = downloadFromSource(ctx, cfg, hdr)
+		}
 	}
 
+	if downError != nil {
+		emitEvent(cfg, &Event{Type: EventTypeFailed, Code: errorCode(downError), Error: downError.Error()})
+	}
 	return downError
 }
 
+// outputDigest returns the digest of the downloaded output for the done event, the digest is already
+// verified when it is set in cfg, otherwise the sha256 of the output is calculated
+func outputDigest(cfg *config.DfgetConfig) string {
+	if cfg.OutputFormat != config.OutputFormatJSON {
+		return ""
+	}
+	if !stringutils.IsBlank(cfg.Digest) {
+		return cfg.Digest
+	}
+	if digest := digestutils.HashFile(cfg.Output, digestutils.Sha256Hash); digest != "" {
+		return digestutils.Sha256Hash.String() + ":" + digest
+	}
+	return ""
+}
+
 func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[string]string) error {
-	if cfg.DisableBackSource {
+	if backSourceDisabled(cfg) {
 		return errors.New("try to download from source but back source is disabled")
 	}
 
@@ -149,7 +215,7 @@ func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[st
 	)
 
 	wLog.Info("try to download from source and ignore rate limit")
-	fmt.Println("try to download from source and ignore rate limit")
+	fmt.Fprintln(MessageWriter(cfg), "try to download from source and ignore rate limit")
 
 	if target, err = os.CreateTemp(filepath.Dir(cfg.Output), ".df_"); err != nil {
 		return err
@@ -174,8 +240,14 @@ func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[st
 		parsedHash := digestutils.Parse(cfg.Digest)
 		realHash := digestutils.HashFile(target.Name(), digestutils.Algorithms[parsedHash[0]])
 
-		if realHash != "" && realHash != parsedHash[1] {
-			return errors.Errorf("%s digest is not matched: real[%s] expected[%s]", parsedHash[0], realHash, parsedHash[1])
+		if realHash != "" && !strings.EqualFold(realHash, parsedHash[1]) {
+			return dferrors.Newf(base.Code_ClientDigestMismatch, "%s digest is not matched: real[%s] expected[%s]", parsedHash[0], realHash, parsedHash[1])
+		}
+	}
+
+	if !cfg.DisableFileAttributes {
+		if err = clientutil.ApplyFileAttributes(target.Name(), response.Attributes, false); err != nil {
+			wLog.Warnf("apply file attributes error: %s", err)
 		}
 	}
 
@@ -189,11 +261,38 @@ func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[st
 	}
 
 	wLog.Infof("download from source success, length: %d bytes cost: %d ms", written, time.Now().Sub(start).Milliseconds())
-	fmt.Printf("finish total length %d bytes\n", written)
+	fmt.Fprintf(MessageWriter(cfg), "finish total length %d bytes\n", written)
+
+	event := &Event{
+		Type:             EventTypeDone,
+		CompletedLength:  written,
+		ContentLength:    written,
+		Source:           SourceBackSource,
+		BackSourceLength: written,
+		Digest:           outputDigest(cfg),
+	}
+	event.setCost(start)
+	emitEvent(cfg, event)
 
 	return nil
 }
 
+// backSourceDisabled indicates whether dfget downloads from source when daemon fails, the signature
+// is verified by daemon only, so the content with signature is never downloaded from source by dfget
+func backSourceDisabled(cfg *config.DfgetConfig) bool {
+	return cfg.DisableBackSource || cfg.SignatureURL != ""
+}
+
+func newSignature(cfg *config.DfgetConfig) *base.Signature {
+	if cfg.SignatureURL == "" {
+		return nil
+	}
+	return &base.Signature{
+		Type: cfg.SignatureType,
+		Url:  cfg.SignatureURL,
+	}
+}
+
 func parseHeader(s []string) map[string]string {
 	hdr := make(map[string]string)
 	var key, value string
@@ -217,16 +316,20 @@ func newDownRequest(cfg *config.DfgetConfig, hdr map[string]string) *dfdaemon.Do
 		Limit:             float64(cfg.RateLimit),
 		DisableBackSource: cfg.DisableBackSource,
 		UrlMeta: &base.UrlMeta{
-			Digest: cfg.Digest,
-			Tag:    cfg.Tag,
-			Range:  hdr[dfheaders.Range],
-			Filter: cfg.Filter,
-			Header: hdr,
+			Digest:    cfg.Digest,
+			Tag:       cfg.Tag,
+			Range:     hdr[dfheaders.Range],
+			Filter:    cfg.Filter,
+			Header:    hdr,
+			Signature: newSignature(cfg),
 		},
-		Pattern:    cfg.Pattern,
-		Callsystem: cfg.CallSystem,
-		Uid:        int64(basic.UserID),
-		Gid:        int64(basic.UserGroup),
+		DisableFileAttributes: cfg.DisableFileAttributes,
+		Pin:                   cfg.Pin,
+		Priority:              cfg.Priority,
+		Pattern:               cfg.Pattern,
+		Callsystem:            cfg.CallSystem,
+		Uid:                   int64(basic.UserID),
+		Gid:                   int64(basic.UserGroup),
 	}
 }
 
@@ -288,44 +391,125 @@ func rejectRegex(u string, reject string) bool {
 }
 
 func recursiveDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig) error {
-	request, err := source.NewRequestWithContext(ctx, cfg.URL, parseHeader(cfg.Header))
+	_, configs, err := listRecursive(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	dirURL, err := url.Parse(cfg.URL)
+	if cfg.RecursiveList {
+		for _, c := range configs {
+			fmt.Printf("%s\n", c.URL)
+		}
+		return nil
+	}
+	for _, c := range configs {
+		// validate new dfget config
+		if err = c.Validate(); err != nil {
+			logger.Errorf("validate failed: %s", err)
+			return err
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	// the journal records the completed files, a re-run skips them
+	if err = config.MkdirAll(cfg.Output, 0777, basic.UserID, basic.UserGroup); err != nil {
+		return err
+	}
+	j, err := openJournal(cfg.Output)
 	if err != nil {
 		return err
 	}
+	defer j.Close()
+
+	var (
+		failed  atomic.Int32
+		skipped atomic.Int32
+	)
+	forEachConcurrently(cfg.Parallelism, len(configs), func(i int) {
+		c := configs[i]
+		if ctx.Err() != nil {
+			failed.Inc()
+			return
+		}
+		if j.completed(c.URL, c.Output) {
+			logger.Debugf("url %s is already downloaded to %s, skip", c.URL, c.Output)
+			skipped.Inc()
+			return
+		}
+
+		logger.Debugf("download %s to %s", c.URL, c.Output)
+		if err := download(ctx, client, c, logger.With("url", c.URL)); err != nil {
+			logger.Errorf("download %s to %s error: %s", c.URL, c.Output, err)
+			fmt.Fprintf(MessageWriter(cfg), "download %s error: %s\n", c.URL, err)
+			failed.Inc()
+			return
+		}
+		if err := j.record(c.URL, c.Output); err != nil {
+			logger.Warnf("record %s to journal error: %s", c.URL, err)
+		}
+	})
+
+	fmt.Fprintf(MessageWriter(cfg), "total: %d, skipped: %d, failed: %d\n", len(configs), skipped.Load(), failed.Load())
+	if failed.Load() > 0 {
+		return errors.Errorf("%d of %d files failed", failed.Load(), len(configs))
+	}
+	return nil
+}
+
+// listRecursive lists all resources in cfg.URL, and returns the dfget configs of the accepted resources,
+// the output of every resource is in cfg.Output with the same relative path
+func listRecursive(ctx context.Context, cfg *config.DfgetConfig) (*url.URL, []*config.DfgetConfig, error) {
+	request, err := source.NewRequestWithContext(ctx, cfg.URL, parseHeader(cfg.Header))
+	if err != nil {
+		return nil, nil, err
+	}
+	dirURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, nil, err
+	}
 	logger.Debugf("dirURL: %s", cfg.URL)
 
 	urls, err := source.List(request)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	var configs []*config.DfgetConfig
 	for _, u := range urls {
 		// reuse dfget config
 		c := *cfg
 		// update some attributes
-		c.Recursive, c.URL, c.Output = false, u.String(), path.Join(cfg.Output, strings.TrimPrefix(u.Path, dirURL.Path))
+		c.Recursive, c.Sync, c.URL, c.Output = false, false, u.String(), path.Join(cfg.Output, strings.TrimPrefix(u.Path, dirURL.Path))
 		if !accept(c.URL, dirURL.Path, u.Path, cfg.RecursiveLevel, cfg.RecursiveAcceptRegex, cfg.RecursiveRejectRegex) {
 			logger.Debugf("url %s is not accepted, skip", c.URL)
 			continue
 		}
-		if cfg.RecursiveList {
-			fmt.Printf("%s\n", u.String())
-			continue
-		}
-		// validate new dfget config
-		if err = c.Validate(); err != nil {
-			logger.Errorf("validate failed: %s", err)
-			return err
-		}
+		// the progress bars of concurrent downloads are messed up
+		c.ShowProgress = false
+		configs = append(configs, &c)
+	}
+	return dirURL, configs, nil
+}
 
-		logger.Debugf("download %s to %s", c.URL, c.Output)
-		err = download(ctx, client, &c, logger.With("url", c.URL))
-		if err != nil {
-			return err
-		}
+// forEachConcurrently calls fn with 0 to n-1, at most parallelism calls run at the same time
+func forEachConcurrently(parallelism, n int, fn func(i int)) {
+	if parallelism <= 0 {
+		parallelism = config.DefaultDfgetParallelism
 	}
-	return nil
+	var (
+		sem = make(chan struct{}, parallelism)
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
 }
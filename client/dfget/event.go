@@ -0,0 +1,244 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+// Exit codes of dfget, they are documented in docs/en/cli-reference/dfget.md
+const (
+	ExitCodeSuccess = 0
+	// ExitCodeUnknownError is the exit code of the errors without dferrors code
+	ExitCodeUnknownError = 1
+	// ExitCodeInvalidArgument is the exit code of invalid flags, config or bad request
+	ExitCodeInvalidArgument = 2
+	// ExitCodeTimeout is the exit code of download timeout
+	ExitCodeTimeout = 3
+	// ExitCodeClientError is the exit code of dferrors code 4000-4999
+	ExitCodeClientError = 4
+	// ExitCodeSchedulerError is the exit code of dferrors code 5000-5999
+	ExitCodeSchedulerError = 5
+	// ExitCodeCDNError is the exit code of dferrors code 6000-6999
+	ExitCodeCDNError = 6
+	// ExitCodeDigestMismatch is the exit code of the content not matched with --digest
+	ExitCodeDigestMismatch = 7
+	// ExitCodeSignatureVerifyFail is the exit code of the content not signed by the trusted keys of daemon
+	ExitCodeSignatureVerifyFail = 8
+)
+
+// ExitCode returns the exit code of the error returned by dfget
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+	if errors.Is(err, dferrors.ErrInvalidArgument) {
+		return ExitCodeInvalidArgument
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ExitCodeTimeout
+	}
+	if errors.Is(err, digestutils.ErrDigestNotMatch) {
+		return ExitCodeDigestMismatch
+	}
+
+	var dfError *dferrors.DfError
+	if !errors.As(err, &dfError) {
+		return ExitCodeUnknownError
+	}
+	switch code := dfError.Code; {
+	case code == base.Code_BadRequest:
+		return ExitCodeInvalidArgument
+	case code == base.Code_RequestTimeOut || code == base.Code_ClientScheduleTimeout:
+		return ExitCodeTimeout
+	case code == base.Code_ClientDigestMismatch:
+		return ExitCodeDigestMismatch
+	case code == base.Code_ClientSignatureVerifyFail:
+		return ExitCodeSignatureVerifyFail
+	case code >= base.Code_ClientError && code < base.Code_SchedError:
+		return ExitCodeClientError
+	case code >= base.Code_SchedError && code < base.Code_CDNError:
+		return ExitCodeSchedulerError
+	case code >= base.Code_CDNError && code < base.Code_CDNError+1000:
+		return ExitCodeCDNError
+	default:
+		return ExitCodeUnknownError
+	}
+}
+
+// errorCode returns the dferrors code of the error, 0 if the error has no code
+func errorCode(err error) base.Code {
+	var dfError *dferrors.DfError
+	if errors.As(err, &dfError) {
+		return dfError.Code
+	}
+	return 0
+}
+
+// event types
+const (
+	EventTypeProgress = "progress"
+	// EventTypeDone is emitted when one file is downloaded
+	EventTypeDone = "done"
+	// EventTypeFailed is emitted when one file is failed to download
+	EventTypeFailed = "failed"
+	// EventTypeResult is the last event of dfget
+	EventTypeResult = "result"
+)
+
+// download sources in events
+const (
+	SourceP2P        = "p2p"
+	SourceCDN        = "cdn"
+	SourceBackSource = "back-source"
+	// SourceMixed means the pieces are downloaded from more than one of p2p, cdn and source
+	SourceMixed = "mixed"
+)
+
+// Event is one line printed by dfget with --output-format json
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	URL  string    `json:"url,omitempty"`
+	// Output is "-" when streaming to stdout
+	Output string `json:"output,omitempty"`
+	TaskID string `json:"taskID,omitempty"`
+	PeerID string `json:"peerID,omitempty"`
+	// ContentLength is -1 when unknown
+	ContentLength   int64 `json:"contentLength,omitempty"`
+	CompletedLength int64 `json:"completedLength"`
+	// Speed is the average bytes per second since download started
+	Speed int64 `json:"speed"`
+	// Source is where the content is downloaded from, empty when the task is reused or the source is unknown
+	Source           string `json:"source,omitempty"`
+	Reuse            bool   `json:"reuse,omitempty"`
+	PeerLength       int64  `json:"peerLength,omitempty"`
+	CDNLength        int64  `json:"cdnLength,omitempty"`
+	BackSourceLength int64  `json:"backSourceLength,omitempty"`
+	// Digest is the digest of the downloaded content, in format of algorithm:xxx
+	Digest string `json:"digest,omitempty"`
+	// Cost is the milliseconds since download started
+	Cost int64 `json:"cost"`
+	// Code is the dferrors code of the error
+	Code     base.Code `json:"code,omitempty"`
+	ExitCode int       `json:"exitCode,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+var eventLock sync.Mutex
+
+// emitEvent prints the event in one json line when the output format is json,
+// the events of the concurrent downloads are not interleaved
+func emitEvent(cfg *config.DfgetConfig, event *Event) {
+	if cfg.OutputFormat != config.OutputFormatJSON {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.URL == "" {
+		event.URL = cfg.URL
+	}
+	if event.Output == "" {
+		event.Output = cfg.Output
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	w := os.Stdout
+	// stdout is reserved for the content when streaming
+	if cfg.IsStdout() {
+		w = os.Stderr
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// newResultEvent converts the down result from daemon to event
+func newResultEvent(result *dfdaemon.DownResult, start time.Time) *Event {
+	event := &Event{
+		Type:             EventTypeProgress,
+		TaskID:           result.TaskId,
+		PeerID:           result.PeerId,
+		ContentLength:    result.ContentLength,
+		CompletedLength:  int64(result.CompletedLength),
+		Reuse:            result.Reuse,
+		PeerLength:       int64(result.PeerLength),
+		CDNLength:        int64(result.CdnLength),
+		BackSourceLength: int64(result.BackSourceLength),
+	}
+	event.setCost(start)
+	event.Source = downloadSource(event.PeerLength, event.CDNLength, event.BackSourceLength)
+	return event
+}
+
+func (e *Event) setCost(start time.Time) {
+	cost := time.Since(start)
+	e.Cost = cost.Milliseconds()
+	if cost > 0 {
+		e.Speed = int64(float64(e.CompletedLength) / cost.Seconds())
+	}
+}
+
+func downloadSource(peerLength, cdnLength, backSourceLength int64) string {
+	var sources []string
+	if peerLength > 0 {
+		sources = append(sources, SourceP2P)
+	}
+	if cdnLength > 0 {
+		sources = append(sources, SourceCDN)
+	}
+	if backSourceLength > 0 {
+		sources = append(sources, SourceBackSource)
+	}
+	switch len(sources) {
+	case 0:
+		return ""
+	case 1:
+		return sources[0]
+	default:
+		return SourceMixed
+	}
+}
+
+// MessageWriter returns the writer for the messages to human, the messages are discarded
+// when the output format is json, and they are printed to stderr when streaming to stdout
+func MessageWriter(cfg *config.DfgetConfig) io.Writer {
+	if cfg.OutputFormat == config.OutputFormatJSON {
+		return io.Discard
+	}
+	if cfg.IsStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
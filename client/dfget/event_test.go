@@ -0,0 +1,101 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		exitCode int
+	}{
+		{
+			name:     "success",
+			exitCode: ExitCodeSuccess,
+		},
+		{
+			name:     "unknown error",
+			err:      errors.New("unknown"),
+			exitCode: ExitCodeUnknownError,
+		},
+		{
+			name:     "invalid argument",
+			err:      pkgerrors.Wrap(dferrors.ErrInvalidArgument, "url"),
+			exitCode: ExitCodeInvalidArgument,
+		},
+		{
+			name:     "timeout",
+			err:      pkgerrors.Wrapf(context.DeadlineExceeded, "download timeout(%s)", "1s"),
+			exitCode: ExitCodeTimeout,
+		},
+		{
+			name:     "client error",
+			err:      pkgerrors.Wrap(dferrors.New(base.Code_ClientPieceDownloadFail, "fail"), "download url"),
+			exitCode: ExitCodeClientError,
+		},
+		{
+			name:     "scheduler error",
+			err:      dferrors.New(base.Code_SchedTaskStatusError, "fail"),
+			exitCode: ExitCodeSchedulerError,
+		},
+		{
+			name:     "cdn error",
+			err:      dferrors.New(base.Code_CDNTaskNotFound, "fail"),
+			exitCode: ExitCodeCDNError,
+		},
+		{
+			name:     "digest mismatch",
+			err:      pkgerrors.Wrap(dferrors.New(base.Code_ClientDigestMismatch, "fail"), "download url"),
+			exitCode: ExitCodeDigestMismatch,
+		},
+		{
+			name:     "signature verify fail",
+			err:      dferrors.New(base.Code_ClientSignatureVerifyFail, "fail"),
+			exitCode: ExitCodeSignatureVerifyFail,
+		},
+		{
+			name:     "bad request",
+			err:      dferrors.New(base.Code_BadRequest, "fail"),
+			exitCode: ExitCodeInvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exitCode, ExitCode(tc.err))
+		})
+	}
+}
+
+func Test_downloadSource(t *testing.T) {
+	assert.Equal(t, "", downloadSource(0, 0, 0))
+	assert.Equal(t, SourceP2P, downloadSource(1, 0, 0))
+	assert.Equal(t, SourceCDN, downloadSource(0, 1, 0))
+	assert.Equal(t, SourceBackSource, downloadSource(0, 0, 1))
+	assert.Equal(t, SourceMixed, downloadSource(1, 1, 0))
+}
@@ -0,0 +1,65 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/basic"
+	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+	"d7y.io/dragonfly/v2/pkg/util/net/urlutils"
+)
+
+// Export materializes the completed task in the daemon storage to output.
+// The argument is a task id or an url, when it is an url, the url meta is generated from cfg
+// just like downloading it. mode is one of auto, hardlink, reflink and copy.
+// Export only reads the local storage, it never contacts scheduler or origin.
+func Export(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr,
+	cfg *config.DfgetConfig, arg string, output string, mode string) error {
+	exportMode, ok := dfdaemon.ExportMode_value[strings.ToUpper(mode)]
+	if !ok {
+		return errors.Errorf("export mode %q is invalid", mode)
+	}
+	path, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	request := &dfdaemon.ExportTaskRequest{
+		Output: path,
+		Mode:   dfdaemon.ExportMode(exportMode),
+		Uid:    int64(basic.UserID),
+		Gid:    int64(basic.UserGroup),
+	}
+	if urlutils.IsValidURL(arg) {
+		c := *cfg
+		c.URL = arg
+		downRequest := newDownRequest(&c, parseHeader(c.Header))
+		request.Url = downRequest.Url
+		request.UrlMeta = downRequest.UrlMeta
+	} else {
+		request.TaskId = arg
+	}
+	return client.ExportTask(ctx, target, request)
+}
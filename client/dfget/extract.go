@@ -0,0 +1,302 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+const (
+	// tarMagic is at offset 257 of ustar and gnu tar header
+	tarMagic       = "ustar"
+	tarMagicOffset = 257
+
+	extractBufferSize = 64 * 1024
+	// defaultExtractName is the file name of the decompressed content when it is not a tar archive
+	// and the name can not be got from url
+	defaultExtractName = "content"
+)
+
+// extractDownload streams the content through decompression and tar extraction into a temporary directory
+// beside the extract directory, then replaces the extract directory with it after all content is downloaded,
+// verified and extracted. The daemon caches the content compressed, so peers share the compressed bytes.
+func extractDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig, wLog *logger.SugaredLoggerOnWith) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(cfg.Extract), "."+filepath.Base(cfg.Extract)+".")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pr, pw := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := extractArchive(pr, tmpDir, archiveName(cfg.URL))
+		if err == nil {
+			// drain the padding after the archive, the download is never blocked by the pipe
+			_, err = io.Copy(io.Discard, pr)
+		}
+		pr.CloseWithError(err)
+		extracted <- err
+	}()
+
+	err = streamDownload(ctx, client, cfg, pw, wLog)
+	pw.CloseWithError(err)
+	if e := <-extracted; err == nil && e != nil {
+		err = errors.Wrap(e, "extract archive")
+		emitEvent(cfg, &Event{Type: EventTypeFailed, Error: err.Error()})
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmpDir, 0755); err != nil {
+		return err
+	}
+	if err = replaceDir(tmpDir, cfg.Extract); err != nil {
+		return err
+	}
+	wLog.Infof("extract archive to %s success", cfg.Extract)
+	fmt.Fprintf(MessageWriter(cfg), "extracted to %s\n", cfg.Extract)
+	return nil
+}
+
+// extractArchive decompresses the gzip or zstd content and extracts it to dir when it is a tar archive,
+// otherwise the decompressed content is written to the file with name in dir
+func extractArchive(r io.Reader, dir, name string) error {
+	var (
+		br     = bufio.NewReaderSize(r, extractBufferSize)
+		reader io.Reader
+	)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		reader, name = gr, trimArchiveExt(name, ".gz", ".tgz")
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		reader, name = zr, trimArchiveExt(name, ".zst", ".zstd", ".tzst")
+	default:
+		reader = br
+	}
+
+	tr := bufio.NewReaderSize(reader, extractBufferSize)
+	header, _ := tr.Peek(tarMagicOffset + len(tarMagic))
+	if len(header) == tarMagicOffset+len(tarMagic) && string(header[tarMagicOffset:]) == tarMagic {
+		if err := extractTar(tr, dir); err != nil {
+			return err
+		}
+		// read the rest of the decompressed content, the checksum of gzip is verified at the end
+		_, err := io.Copy(io.Discard, tr)
+		return err
+	}
+	return writeExtractedFile(tr, filepath.Join(dir, name), 0644)
+}
+
+// extractTar extracts the regular files, directories, symbolic links and hard links in the tar archive to dir,
+// the entries out of dir are refused
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			// the symbolic links are checked again after all are extracted, they may be chained in any order
+			return checkSymlinks(dir)
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := securePath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if target == dir {
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			if err = os.Chmod(target, hdr.FileInfo().Mode().Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // old tar writers use TypeRegA for regular files
+			if err = writeExtractedFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+			if err = os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) {
+				return errors.Errorf("illegal symbolic link %s to %s", hdr.Name, hdr.Linkname)
+			}
+			if _, err = securePath(dir, filepath.Join(filepath.Dir(hdr.Name), hdr.Linkname)); err != nil {
+				return errors.Wrapf(err, "symbolic link %s", hdr.Name)
+			}
+			if err = os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := securePath(dir, hdr.Linkname)
+			if err != nil {
+				return errors.Wrapf(err, "hard link %s", hdr.Name)
+			}
+			if err = os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			logger.Debugf("skip tar entry %s with type %c", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
+func writeExtractedFile(r io.Reader, path string, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// securePath joins name to dir, the path out of dir is refused. The path through the extracted symbolic links
+// is refused too, because the check is lexical, eg: a/b -> .. and a/b/c -> .. lead a/b/c/x out of dir
+func securePath(dir, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	if target != dir && !strings.HasPrefix(target, dir+string(os.PathSeparator)) {
+		return "", errors.Errorf("illegal path %s in archive", name)
+	}
+	for path := target; path != dir; path = filepath.Dir(path) {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", errors.Errorf("illegal path %s through symbolic link in archive", name)
+		}
+	}
+	return target, nil
+}
+
+// checkSymlinks refuses the symbolic links in dir which resolve out of dir, the dangling links are kept
+func checkSymlinks(dir string) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.Type()&os.ModeSymlink == 0 {
+			return err
+		}
+		target, err := filepath.EvalSymlinks(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "symbolic link %s", path)
+		}
+		if target != realDir && !strings.HasPrefix(target, realDir+string(os.PathSeparator)) {
+			return errors.Errorf("illegal symbolic link %s to %s out of archive", path, target)
+		}
+		return nil
+	})
+}
+
+// replaceDir renames src to dst, the exist dst is removed after src is renamed
+func replaceDir(src, dst string) error {
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		return os.Rename(src, dst)
+	}
+	backup := src + ".old"
+	if err := os.Rename(dst, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if e := os.Rename(backup, dst); e != nil {
+			logger.Errorf("restore %s error: %s", dst, e)
+		}
+		return err
+	}
+	return os.RemoveAll(backup)
+}
+
+// archiveName returns the file name in url
+func archiveName(u string) string {
+	name := defaultExtractName
+	if parsed, err := url.Parse(u); err == nil {
+		if base := path.Base(parsed.Path); base != "/" && base != "." {
+			name = base
+		}
+	}
+	return name
+}
+
+// trimArchiveExt trims the compression extension of name, the extensions of compressed tar are replaced with .tar
+func trimArchiveExt(name string, exts ...string) string {
+	for _, ext := range exts {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			if strings.HasPrefix(ext, ".t") {
+				return strings.TrimSuffix(name, ext) + ".tar"
+			}
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
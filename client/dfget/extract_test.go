@@ -0,0 +1,163 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTar(t *testing.T, entries []*tar.Header, contents map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, hdr := range entries {
+		hdr.Size = int64(len(contents[hdr.Name]))
+		require.Nil(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(contents[hdr.Name]))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tw.Close())
+	return buf.Bytes()
+}
+
+func Test_extractArchive(t *testing.T) {
+	contents := map[string]string{
+		"bin/app":    "app",
+		"README.md":  "readme",
+		"bin/link":   "",
+		"bin/hard":   "",
+		"bin/":       "",
+		"empty-dir/": "",
+	}
+	archive := newTestTar(t, []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "bin/app", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "bin/link", Typeflag: tar.TypeSymlink, Linkname: "app"},
+		{Name: "bin/hard", Typeflag: tar.TypeLink, Linkname: "bin/app"},
+		{Name: "empty-dir/", Typeflag: tar.TypeDir, Mode: 0700},
+	}, contents)
+
+	gz := &bytes.Buffer{}
+	gw := gzip.NewWriter(gz)
+	_, _ = gw.Write(archive)
+	require.Nil(t, gw.Close())
+
+	zs := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(zs)
+	require.Nil(t, err)
+	_, _ = zw.Write(archive)
+	require.Nil(t, zw.Close())
+
+	for name, data := range map[string][]byte{"tar": archive, "gzip": gz.Bytes(), "zstd": zs.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.Nil(t, extractArchive(bytes.NewReader(data), dir, "archive.tar"))
+
+			for _, file := range []string{"bin/app", "README.md", "bin/link", "bin/hard"} {
+				content, err := os.ReadFile(filepath.Join(dir, file))
+				assert.Nil(t, err)
+				expected := contents[file]
+				if expected == "" {
+					expected = contents["bin/app"]
+				}
+				assert.Equal(t, expected, string(content), file)
+			}
+			stat, err := os.Stat(filepath.Join(dir, "bin/app"))
+			require.Nil(t, err)
+			assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+			stat, err = os.Stat(filepath.Join(dir, "empty-dir"))
+			require.Nil(t, err)
+			assert.True(t, stat.IsDir())
+		})
+	}
+}
+
+func Test_extractArchive_notTar(t *testing.T) {
+	gz := &bytes.Buffer{}
+	gw := gzip.NewWriter(gz)
+	_, _ = gw.Write([]byte("plain content"))
+	require.Nil(t, gw.Close())
+
+	dir := t.TempDir()
+	require.Nil(t, extractArchive(gz, dir, "data.txt.gz"))
+	content, err := os.ReadFile(filepath.Join(dir, "data.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "plain content", string(content))
+}
+
+func Test_extractArchive_illegalPath(t *testing.T) {
+	for _, entries := range [][]*tar.Header{
+		{{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}},
+		{{Name: "abs-link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		{{Name: "hard", Typeflag: tar.TypeLink, Linkname: "../escape"}},
+		// chained symbolic links escape from the lexical check
+		{
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c/escape", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		{
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "hard", Typeflag: tar.TypeLink, Linkname: "a/b/a"},
+		},
+		// the link resolved through the symbolic link extracted later
+		{
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/s", Typeflag: tar.TypeSymlink, Linkname: "../c/.."},
+			{Name: "c", Typeflag: tar.TypeSymlink, Linkname: "."},
+		},
+	} {
+		archive := newTestTar(t, entries, map[string]string{})
+		base := t.TempDir()
+		dir := filepath.Join(base, "extract")
+		require.Nil(t, os.Mkdir(dir, 0755))
+		last := entries[len(entries)-1].Name
+		assert.NotNil(t, extractArchive(bytes.NewReader(archive), dir, "archive.tar"), last)
+		_, err := os.Lstat(filepath.Join(base, "escape"))
+		assert.True(t, os.IsNotExist(err), last)
+	}
+}
+
+func Test_replaceDir(t *testing.T) {
+	base := t.TempDir()
+	src, dst := filepath.Join(base, "src"), filepath.Join(base, "dst")
+	require.Nil(t, os.MkdirAll(src, 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(src, "new"), nil, 0644))
+	require.Nil(t, os.MkdirAll(dst, 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(dst, "old"), nil, 0644))
+
+	require.Nil(t, replaceDir(src, dst))
+	_, err := os.Stat(filepath.Join(dst, "new"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dst, "old"))
+	assert.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(base)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,63 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+	"d7y.io/dragonfly/v2/pkg/util/net/urlutils"
+)
+
+// Import imports the local file into the daemon storage under the task id of cfg.URL,
+// then other peers can download the url through P2P without downloading from origin.
+// It returns the task id of the imported file.
+func Import(ctx context.Context, client daemonclient.DaemonClient, target dfnet.NetAddr, cfg *config.DfgetConfig, file string) (string, error) {
+	if !urlutils.IsValidURL(cfg.URL) {
+		return "", errors.Errorf("url: %v is invalid", cfg.URL)
+	}
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !stat.Mode().IsRegular() {
+		return "", errors.Errorf("%s is not a regular file", path)
+	}
+
+	request := newDownRequest(cfg, parseHeader(cfg.Header))
+	err = client.ImportTask(ctx, target, &dfdaemon.ImportTaskRequest{
+		Url:     request.Url,
+		UrlMeta: request.UrlMeta,
+		Path:    path,
+	})
+	if err != nil {
+		return "", err
+	}
+	return idgen.TaskID(request.Url, request.UrlMeta), nil
+}
@@ -0,0 +1,136 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+// journalFileName is the resume journal of recursive download in the output directory
+const journalFileName = ".dfget.journal"
+
+// journalEntry records a completed file of recursive download
+type journalEntry struct {
+	URL    string `json:"url"`
+	Output string `json:"output"`
+	Length int64  `json:"length"`
+	// Digest is the sha256 of the output file, in format of sha256:xxx
+	Digest string `json:"digest"`
+}
+
+// journal records the completed files of recursive download line by line,
+// a re-run skips the files which are already completed and digest verified
+type journal struct {
+	sync.Mutex
+	file    *os.File
+	entries map[string]*journalEntry
+}
+
+// openJournal loads the completed entries in the journal of dir, and opens it for appending
+func openJournal(dir string) (*journal, error) {
+	journalPath := path.Join(dir, journalFileName)
+	j := &journal{entries: map[string]*journalEntry{}}
+	data, err := os.ReadFile(journalPath)
+	if err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var entry journalEntry
+			// the last line may be broken when dfget exits unexpectedly, just skip it
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.URL == "" {
+				logger.Warnf("skip invalid journal line: %q", scanner.Text())
+				continue
+			}
+			j.entries[entry.URL] = &entry
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(journalPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "open journal")
+	}
+	// terminate the broken line, avoid the next entry being appended to it
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err = file.Write([]byte{'\n'}); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	j.file = file
+	return j, nil
+}
+
+// completed indicates whether the url was downloaded to output, and the output is not changed
+func (j *journal) completed(url, output string) bool {
+	j.Lock()
+	entry, ok := j.entries[url]
+	j.Unlock()
+	if !ok || entry.Output != output {
+		return false
+	}
+	stat, err := os.Stat(output)
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() != entry.Length {
+		return false
+	}
+	digest := digestutils.HashFile(output, digestutils.Sha256Hash)
+	return digest != "" && digestutils.Sha256Hash.String()+":"+digest == entry.Digest
+}
+
+// record appends the completed output of url to the journal
+func (j *journal) record(url, output string) error {
+	stat, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	digest := digestutils.HashFile(output, digestutils.Sha256Hash)
+	if digest == "" {
+		return errors.Errorf("calculate digest of %s failed", output)
+	}
+	entry := &journalEntry{
+		URL:    url,
+		Output: output,
+		Length: stat.Size(),
+		Digest: digestutils.Sha256Hash.String() + ":" + digest,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.Lock()
+	defer j.Unlock()
+	if _, err = j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	j.entries[url] = entry
+	return nil
+}
+
+func (j *journal) Close() error {
+	return j.file.Close()
+}
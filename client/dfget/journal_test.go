@@ -0,0 +1,62 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_journal(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "a")
+	url := "http://a.b.c/a"
+	assert.Nil(t, os.WriteFile(output, []byte("hello"), 0644))
+
+	j, err := openJournal(dir)
+	assert.Nil(t, err)
+	assert.False(t, j.completed(url, output), "not recorded")
+	assert.Nil(t, j.record(url, output))
+	assert.True(t, j.completed(url, output))
+	assert.False(t, j.completed(url, filepath.Join(dir, "b")), "output changed")
+	assert.Nil(t, j.Close())
+
+	// simulate a broken line written when dfget exits unexpectedly
+	f, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"url": "http://a.b.c/b", "out`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	j, err = openJournal(dir)
+	assert.Nil(t, err)
+	assert.True(t, j.completed(url, output), "completed after reopen")
+	assert.Nil(t, j.record(url, output))
+	assert.Nil(t, j.Close())
+
+	j, err = openJournal(dir)
+	assert.Nil(t, err)
+	defer j.Close()
+	assert.True(t, j.completed(url, output), "recorded after the broken line")
+
+	// the content is changed with the same length
+	assert.Nil(t, os.WriteFile(output, []byte("world"), 0644))
+	assert.False(t, j.completed(url, output), "digest not match")
+}
@@ -0,0 +1,217 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+)
+
+// ManifestItem is one file to download in manifest
+type ManifestItem struct {
+	URL    string   `json:"url" yaml:"url"`
+	Output string   `json:"output,omitempty" yaml:"output,omitempty"`
+	Digest string   `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Tag    string   `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Filter string   `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Header []string `json:"header,omitempty" yaml:"header,omitempty"`
+}
+
+// manifestResult is the download result of one item in manifest
+type manifestResult struct {
+	item   *ManifestItem
+	output string
+	cost   time.Duration
+	err    error
+}
+
+// ParseManifest parses the manifest file by its extension:
+// .json and .yaml(.yml) files contain a list of ManifestItem,
+// other files are plain text, every line is "url [output] [digest]",
+// empty lines and lines starting with '#' are ignored.
+func ParseManifest(file string) ([]*ManifestItem, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*ManifestItem
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(data, &items)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &items)
+	default:
+		items, err = parseTextManifest(data)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse manifest %s", file)
+	}
+
+	for i, item := range items {
+		if item == nil || item.URL == "" {
+			return nil, errors.Errorf("parse manifest %s: item %d has no url", file, i+1)
+		}
+	}
+	return items, nil
+}
+
+func parseTextManifest(data []byte) ([]*ManifestItem, error) {
+	var (
+		items   []*ManifestItem
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) > 3 {
+			return nil, errors.Errorf("line %d: too many fields, want: url [output] [digest]", line)
+		}
+		item := &ManifestItem{URL: fields[0]}
+		if len(fields) > 1 {
+			item.Output = fields[1]
+		}
+		if len(fields) > 2 {
+			item.Digest = fields[2]
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// DownloadManifest downloads all items in cfg.Manifest concurrently with the same daemon client,
+// cfg.Parallelism limits the concurrent downloads. It prints the result of every item to w,
+// and returns an error when any item fails.
+func DownloadManifest(cfg *config.DfgetConfig, client daemonclient.DaemonClient, w io.Writer) error {
+	items, err := ParseManifest(cfg.Manifest)
+	if err != nil {
+		return err
+	}
+
+	results := make([]*manifestResult, len(items))
+	logger.Infof("start to download %d items in manifest %s, parallelism: %d", len(items), cfg.Manifest, cfg.Parallelism)
+	forEachConcurrently(cfg.Parallelism, len(items), func(i int) {
+		results[i] = downloadManifestItem(cfg, client, items[i])
+	})
+
+	return printManifestResults(w, results)
+}
+
+func downloadManifestItem(cfg *config.DfgetConfig, client daemonclient.DaemonClient, item *ManifestItem) *manifestResult {
+	var (
+		start  = time.Now()
+		c      = manifestItemConfig(cfg, item)
+		result = &manifestResult{item: item, output: c.Output}
+		ctx    = context.Background()
+		cancel context.CancelFunc
+	)
+	if err := c.Validate(); err != nil {
+		result.err = err
+		return result
+	}
+	result.output = c.Output
+
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	wLog := logger.With("url", c.URL)
+	wLog.Infof("download manifest item to %s", c.Output)
+	result.err = singleDownload(ctx, client, c, wLog)
+	if ctx.Err() == context.DeadlineExceeded {
+		result.err = errors.Errorf("download timeout(%s)", c.Timeout)
+	}
+	result.cost = time.Since(start)
+	return result
+}
+
+// manifestItemConfig returns the dfget config of the item, the options not set in item are inherited from cfg
+func manifestItemConfig(cfg *config.DfgetConfig, item *ManifestItem) *config.DfgetConfig {
+	c := *cfg
+	c.Manifest, c.Recursive, c.ShowProgress = "", false, false
+	c.URL = item.URL
+
+	// the relative output is based on cfg.Output, it is the working directory by default
+	switch {
+	case item.Output == "":
+		c.Output = path.Join(cfg.Output, path.Base(strings.TrimRight(item.URL, "/")))
+	case filepath.IsAbs(item.Output):
+		c.Output = item.Output
+	default:
+		c.Output = path.Join(cfg.Output, item.Output)
+	}
+
+	if item.Digest != "" {
+		c.Digest, c.Tag = item.Digest, ""
+	}
+	if item.Tag != "" {
+		c.Tag = item.Tag
+	}
+	if item.Filter != "" {
+		c.Filter = item.Filter
+	}
+	if len(item.Header) > 0 {
+		c.Header = append(append([]string{}, cfg.Header...), item.Header...)
+	}
+	return &c
+}
+
+func printManifestResults(w io.Writer, results []*manifestResult) error {
+	var failed int
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tOUTPUT\tRESULT\tCOST")
+	for _, result := range results {
+		status := "success"
+		if result.err != nil {
+			failed++
+			status = fmt.Sprintf("failed: %s", result.err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.item.URL, result.output, status, result.cost.Round(time.Millisecond))
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(w, "total: %d, success: %d, failed: %d\n", len(results), len(results)-failed, failed)
+
+	if failed > 0 {
+		return errors.Errorf("%d of %d items failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+func TestParseManifest(t *testing.T) {
+	dir := t.TempDir()
+	expected := []*ManifestItem{
+		{URL: "http://a.b.c/x", Output: "x"},
+		{URL: "http://a.b.c/y", Output: "/data/y", Digest: "sha256:abc"},
+		{URL: "http://a.b.c/z"},
+	}
+
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		items   []*ManifestItem
+		wantErr bool
+	}{
+		{
+			name:    "txt",
+			file:    "manifest.txt",
+			content: "# comment\nhttp://a.b.c/x x\n\n  http://a.b.c/y /data/y sha256:abc\nhttp://a.b.c/z\n",
+			items:   expected,
+		},
+		{
+			name: "json",
+			file: "manifest.json",
+			content: `[{"url": "http://a.b.c/x", "output": "x"},
+{"url": "http://a.b.c/y", "output": "/data/y", "digest": "sha256:abc"},
+{"url": "http://a.b.c/z"}]`,
+			items: expected,
+		},
+		{
+			name: "yaml",
+			file: "manifest.yaml",
+			content: `- url: http://a.b.c/x
+  output: x
+- url: http://a.b.c/y
+  output: /data/y
+  digest: sha256:abc
+- url: http://a.b.c/z
+`,
+			items: expected,
+		},
+		{
+			name:    "too many fields",
+			file:    "manifest.txt",
+			content: "http://a.b.c/x x sha256:abc extra\n",
+			wantErr: true,
+		},
+		{
+			name:    "no url",
+			file:    "manifest.yaml",
+			content: "- output: x\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(dir, tc.file)
+			assert.Nil(t, os.WriteFile(file, []byte(tc.content), 0644))
+			items, err := ParseManifest(file)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.items, items)
+		})
+	}
+}
+
+func Test_manifestItemConfig(t *testing.T) {
+	cfg := &config.DfgetConfig{
+		Output:   "/data",
+		Manifest: "manifest.txt",
+		Tag:      "tag",
+		Header:   []string{"a: b"},
+	}
+
+	c := manifestItemConfig(cfg, &ManifestItem{URL: "http://a.b.c/x/y"})
+	assert.Equal(t, "/data/y", c.Output)
+	assert.Equal(t, "tag", c.Tag)
+	assert.Equal(t, "", c.Manifest)
+
+	c = manifestItemConfig(cfg, &ManifestItem{URL: "http://a.b.c/x", Output: "sub/x", Digest: "sha256:abc", Header: []string{"c: d"}})
+	assert.Equal(t, "/data/sub/x", c.Output)
+	assert.Equal(t, "sha256:abc", c.Digest)
+	assert.Equal(t, "", c.Tag)
+	assert.Equal(t, []string{"a: b", "c: d"}, c.Header)
+	assert.Equal(t, []string{"a: b"}, cfg.Header)
+
+	c = manifestItemConfig(cfg, &ManifestItem{URL: "http://a.b.c/x", Output: "/tmp/x"})
+	assert.Equal(t, "/tmp/x", c.Output)
+}
@@ -0,0 +1,197 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc/base"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+	"d7y.io/dragonfly/v2/pkg/source"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+	"d7y.io/dragonfly/v2/pkg/util/stringutils"
+)
+
+// streamWriter writes the content in order and verifies the digest of it
+type streamWriter struct {
+	w       io.Writer
+	hash    hash.Hash
+	digest  []string
+	written int64
+}
+
+// newStreamWriter creates a stream writer which verifies the content with digest,
+// when digest is empty and hashContent is set, the sha256 of the content is calculated
+func newStreamWriter(w io.Writer, digest string, hashContent bool) *streamWriter {
+	sw := &streamWriter{w: w}
+	if !stringutils.IsBlank(digest) {
+		if sw.digest = digestutils.Parse(digest); len(sw.digest) == 2 {
+			sw.hash = digestutils.CreateHash(sw.digest[0])
+		}
+	} else if hashContent {
+		sw.digest = []string{digestutils.Sha256Hash.String(), ""}
+		sw.hash = digestutils.CreateHash(sw.digest[0])
+	}
+	return sw
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	if sw.hash != nil {
+		sw.hash.Write(p[:n])
+	}
+	sw.written += int64(n)
+	return n, err
+}
+
+// verify checks the digest after all content is written, the content is already consumed by the reader,
+// so the error only makes dfget exit with failure
+func (sw *streamWriter) verify() error {
+	if sw.hash == nil || sw.digest[1] == "" {
+		return nil
+	}
+	if realHash := digestutils.ToHashString(sw.hash); !strings.EqualFold(realHash, sw.digest[1]) {
+		return dferrors.Newf(base.Code_ClientDigestMismatch, "%s digest is not matched: real[%s] expected[%s]", sw.digest[0], realHash, sw.digest[1])
+	}
+	return nil
+}
+
+// contentDigest returns the digest of the written content in format of algorithm:xxx
+func (sw *streamWriter) contentDigest() string {
+	if sw.hash == nil {
+		return ""
+	}
+	return sw.digest[0] + ":" + digestutils.ToHashString(sw.hash)
+}
+
+// streamDownload downloads the content through the stream of daemon and writes it to w in order,
+// when the daemon fails before any content is written, it falls back to download from source.
+func streamDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig, w io.Writer, wLog *logger.SugaredLoggerOnWith) error {
+	var (
+		hdr   = parseHeader(cfg.Header)
+		sw    = newStreamWriter(w, cfg.Digest, cfg.OutputFormat == config.OutputFormatJSON)
+		start = time.Now()
+		event = &Event{Type: EventTypeDone}
+		err   error
+	)
+	if client != nil {
+		if err = streamFromDaemon(ctx, client, cfg, hdr, sw, event); err == nil {
+			wLog.Infof("stream from daemon success, length: %d bytes cost: %d ms", sw.written, time.Since(start).Milliseconds())
+			fmt.Fprintf(MessageWriter(cfg), "finish total length %d bytes\n", sw.written)
+		} else {
+			wLog.Warnf("daemon streams file error: %v", err)
+			fmt.Fprintf(MessageWriter(cfg), "daemon streams file error: %v\n", err)
+			// the written content can not be rolled back, keep the daemon error for the exit code
+			if sw.written > 0 {
+				err = errors.Wrapf(err, "stream interrupted after %d bytes", sw.written)
+			} else if backSourceDisabled(cfg) {
+				err = errors.Wrap(err, "back source is disabled")
+			}
+		}
+	}
+	if client == nil || (err != nil && sw.written == 0 && !backSourceDisabled(cfg)) {
+		event.Source = SourceBackSource
+		err = streamFromSource(ctx, cfg, hdr, sw)
+	}
+	if err == nil {
+		err = sw.verify()
+	}
+
+	if err != nil {
+		emitEvent(cfg, &Event{Type: EventTypeFailed, TaskID: event.TaskID, PeerID: event.PeerID,
+			CompletedLength: sw.written, Code: errorCode(err), Error: err.Error()})
+		return err
+	}
+	event.CompletedLength, event.Digest = sw.written, sw.contentDigest()
+	event.setCost(start)
+	emitEvent(cfg, event)
+	return nil
+}
+
+// streamFromDaemon writes the content from daemon to sw, the task info is set in event
+func streamFromDaemon(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig, hdr map[string]string, sw *streamWriter, event *Event) error {
+	stream, err := client.DownloadStream(ctx, newDownRequest(cfg, hdr))
+	if err != nil {
+		return err
+	}
+
+	var contentLength int64 = -1
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if result.TaskId != "" {
+			logger.With("url", cfg.URL).Infof("stream task %s, peer %s, content length: %d", result.TaskId, result.PeerId, result.ContentLength)
+			contentLength = result.ContentLength
+			event.TaskID, event.PeerID, event.ContentLength = result.TaskId, result.PeerId, result.ContentLength
+		}
+		if len(result.Data) == 0 {
+			continue
+		}
+		if _, err = sw.Write(result.Data); err != nil {
+			return errors.Wrap(err, "write content")
+		}
+	}
+
+	if contentLength >= 0 && sw.written != contentLength {
+		return errors.Errorf("content length not match, desired: %d, actual: %d", contentLength, sw.written)
+	}
+	return nil
+}
+
+func streamFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[string]string, sw *streamWriter) error {
+	if backSourceDisabled(cfg) {
+		return errors.New("try to download from source but back source is disabled")
+	}
+
+	wLog := logger.With("url", cfg.URL)
+	start := time.Now()
+	wLog.Info("try to stream from source and ignore rate limit")
+	fmt.Fprintln(MessageWriter(cfg), "try to stream from source and ignore rate limit")
+
+	request, err := source.NewRequestWithContext(ctx, cfg.URL, hdr)
+	if err != nil {
+		return err
+	}
+	response, err := source.Download(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if _, err = io.Copy(sw, response.Body); err != nil {
+		return err
+	}
+
+	wLog.Infof("stream from source success, length: %d bytes cost: %d ms", sw.written, time.Since(start).Milliseconds())
+	fmt.Fprintf(MessageWriter(cfg), "finish total length %d bytes\n", sw.written)
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/source"
+	sourcemock "d7y.io/dragonfly/v2/pkg/source/mock"
+	"d7y.io/dragonfly/v2/pkg/util/digestutils"
+)
+
+func Test_streamDownload(t *testing.T) {
+	content := idgen.UUIDString()
+
+	sourceClient := sourcemock.NewMockResourceClient(gomock.NewController(t))
+	require.Nil(t, source.Register("http", sourceClient, func(request *source.Request) *source.Request {
+		return request
+	}))
+	defer source.UnRegister("http")
+
+	testCases := []struct {
+		name    string
+		digest  string
+		wantErr bool
+	}{
+		{
+			name: "without digest",
+		},
+		{
+			name:   "digest matched",
+			digest: strings.Join([]string{digestutils.Sha256Hash.String(), digestutils.Sha256(content)}, ":"),
+		},
+		{
+			name:    "digest not matched",
+			digest:  strings.Join([]string{digestutils.Sha256Hash.String(), digestutils.Sha256("x")}, ":"),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.DfgetConfig{
+				URL:    "http://a.b.c/xx",
+				Output: config.StdoutOutput,
+				Digest: tc.digest,
+			}
+			sourceClient.EXPECT().Download(gomock.Any()).Return(source.NewResponse(io.NopCloser(strings.NewReader(content))), nil)
+
+			buf := &bytes.Buffer{}
+			err := streamDownload(context.Background(), nil, cfg, buf, logger.With("url", cfg.URL))
+			assert.Equal(t, content, buf.String())
+			if tc.wantErr {
+				assert.Equal(t, ExitCodeDigestMismatch, ExitCode(err))
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	daemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+type syncAction string
+
+const (
+	// syncActionAdd downloads the resource not in local
+	syncActionAdd syncAction = "add"
+	// syncActionUpdate downloads the resource changed in remote
+	syncActionUpdate syncAction = "update"
+	// syncActionDelete deletes the local file not in remote
+	syncActionDelete syncAction = "delete"
+	// syncActionKeep keeps the local file not changed
+	syncActionKeep syncAction = "keep"
+)
+
+// syncPlanItem is an action of syncing remote resources to local directory
+type syncPlanItem struct {
+	action syncAction
+	// config is nil when deleting local file
+	config *config.DfgetConfig
+	output string
+	// lastModified is the last modified timestamp milliseconds of remote resource, -1 is unknown
+	lastModified int64
+	reason       string
+}
+
+// syncDownload mirrors the resources in cfg.URL to cfg.Output, the remote resources are compared with
+// local files by content length and last modified time, only the new or changed resources are downloaded.
+// When cfg.SyncDelete is set, local files not in remote are deleted.
+func syncDownload(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig) error {
+	dirURL, configs, err := listRecursive(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	plan := make([]*syncPlanItem, len(configs))
+	forEachConcurrently(cfg.Parallelism, len(configs), func(i int) {
+		plan[i] = planSyncItem(ctx, configs[i])
+	})
+	if cfg.SyncDelete {
+		deletes, err := planSyncDelete(cfg, dirURL, configs)
+		if err != nil {
+			return err
+		}
+		plan = append(plan, deletes...)
+	}
+
+	if cfg.SyncDryRun {
+		printSyncPlan(MessageWriter(cfg), plan)
+		return nil
+	}
+	return executeSyncPlan(ctx, client, cfg, plan)
+}
+
+// planSyncItem compares the remote resource with the local file
+func planSyncItem(ctx context.Context, c *config.DfgetConfig) *syncPlanItem {
+	item := &syncPlanItem{
+		action:       syncActionKeep,
+		config:       c,
+		output:       c.Output,
+		lastModified: -1,
+	}
+	hdr := parseHeader(c.Header)
+
+	contentLength := int64(source.UnknownSourceFileLen)
+	if request, err := source.NewRequestWithContext(ctx, c.URL, hdr); err == nil {
+		if contentLength, err = source.GetContentLength(request); err != nil {
+			logger.Warnf("get content length of %s error: %s", c.URL, err)
+			contentLength = source.UnknownSourceFileLen
+		}
+	}
+	if request, err := source.NewRequestWithContext(ctx, c.URL, hdr); err == nil {
+		if lastModified, err := source.GetLastModified(request); err != nil {
+			logger.Warnf("get last modified of %s error: %s", c.URL, err)
+		} else if lastModified > 0 {
+			item.lastModified = lastModified
+		}
+	}
+
+	stat, err := os.Stat(c.Output)
+	switch {
+	case os.IsNotExist(err):
+		item.action, item.reason = syncActionAdd, "not exist"
+	case err != nil:
+		item.action, item.reason = syncActionUpdate, err.Error()
+	case !stat.Mode().IsRegular():
+		item.action, item.reason = syncActionUpdate, "not a regular file"
+	case contentLength >= 0 && stat.Size() != contentLength:
+		item.action, item.reason = syncActionUpdate, fmt.Sprintf("size changed, local: %d, remote: %d", stat.Size(), contentLength)
+	// compare in seconds, some file systems do not support higher precision
+	case item.lastModified > 0 && stat.ModTime().Unix() != item.lastModified/1000:
+		item.action, item.reason = syncActionUpdate, fmt.Sprintf("last modified changed, local: %s, remote: %s",
+			stat.ModTime().Format(time.RFC3339), time.Unix(0, item.lastModified*int64(time.Millisecond)).Format(time.RFC3339))
+	case contentLength < 0 && item.lastModified <= 0:
+		item.action, item.reason = syncActionUpdate, "unknown remote content length and last modified"
+	}
+
+	// different versions of the same url are different tasks, avoid reusing the stale task in daemon
+	if item.action != syncActionKeep && item.lastModified > 0 && c.Digest == "" {
+		c.Tag = syncTag(c.Tag, item.lastModified)
+	}
+	return item
+}
+
+func syncTag(tag string, lastModified int64) string {
+	if tag == "" {
+		return fmt.Sprintf("last-modified=%d", lastModified)
+	}
+	return fmt.Sprintf("%s;last-modified=%d", tag, lastModified)
+}
+
+// planSyncDelete finds the local files which are not in remote, the files not accepted by
+// the recursive filters are kept
+func planSyncDelete(cfg *config.DfgetConfig, dirURL *url.URL, configs []*config.DfgetConfig) ([]*syncPlanItem, error) {
+	outputs := map[string]bool{}
+	for _, c := range configs {
+		outputs[c.Output] = true
+	}
+
+	var items []*syncPlanItem
+	err := filepath.WalkDir(cfg.Output, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == cfg.Output {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() == journalFileName || outputs[file] {
+			return nil
+		}
+		rel, err := filepath.Rel(cfg.Output, file)
+		if err != nil {
+			return err
+		}
+		u := *dirURL
+		u.Path = path.Join(dirURL.Path, filepath.ToSlash(rel))
+		if !accept(u.String(), dirURL.Path, u.Path, cfg.RecursiveLevel, cfg.RecursiveAcceptRegex, cfg.RecursiveRejectRegex) {
+			return nil
+		}
+		items = append(items, &syncPlanItem{
+			action: syncActionDelete,
+			output: file,
+			reason: "not exist in remote",
+		})
+		return nil
+	})
+	return items, err
+}
+
+func printSyncPlan(w io.Writer, plan []*syncPlanItem) {
+	counts := map[syncAction]int{}
+	for _, item := range plan {
+		counts[item.action]++
+		switch item.action {
+		case syncActionAdd:
+			fmt.Fprintf(w, "%s: %s -> %s\n", item.action, item.config.URL, item.output)
+		case syncActionUpdate:
+			fmt.Fprintf(w, "%s: %s -> %s (%s)\n", item.action, item.config.URL, item.output, item.reason)
+		case syncActionDelete:
+			fmt.Fprintf(w, "%s: %s\n", item.action, item.output)
+		}
+	}
+	fmt.Fprintf(w, "dry run, add: %d, update: %d, delete: %d, keep: %d\n",
+		counts[syncActionAdd], counts[syncActionUpdate], counts[syncActionDelete], counts[syncActionKeep])
+}
+
+func executeSyncPlan(ctx context.Context, client daemonclient.DaemonClient, cfg *config.DfgetConfig, plan []*syncPlanItem) error {
+	var (
+		added, updated, deleted, kept, failed atomic.Int32
+		deletes                               []*syncPlanItem
+	)
+	for _, item := range plan {
+		switch item.action {
+		case syncActionKeep:
+			kept.Inc()
+		case syncActionDelete:
+			deletes = append(deletes, item)
+		}
+	}
+
+	forEachConcurrently(cfg.Parallelism, len(plan), func(i int) {
+		item := plan[i]
+		if item.action != syncActionAdd && item.action != syncActionUpdate {
+			return
+		}
+		if err := syncItem(ctx, client, item); err != nil {
+			logger.Errorf("sync %s to %s error: %s", item.config.URL, item.output, err)
+			fmt.Fprintf(MessageWriter(cfg), "sync %s error: %s\n", item.config.URL, err)
+			failed.Inc()
+			return
+		}
+		if item.action == syncActionAdd {
+			added.Inc()
+		} else {
+			updated.Inc()
+		}
+	})
+
+	for _, item := range deletes {
+		if err := os.Remove(item.output); err != nil && !os.IsNotExist(err) {
+			logger.Errorf("delete %s error: %s", item.output, err)
+			failed.Inc()
+			continue
+		}
+		logger.Infof("deleted %s, %s", item.output, item.reason)
+		deleted.Inc()
+		removeEmptyDirs(cfg.Output, path.Dir(item.output))
+	}
+
+	fmt.Fprintf(MessageWriter(cfg), "add: %d, update: %d, delete: %d, keep: %d, failed: %d\n",
+		added.Load(), updated.Load(), deleted.Load(), kept.Load(), failed.Load())
+	if failed.Load() > 0 {
+		return errors.Errorf("%d of %d files failed to sync", failed.Load(), len(plan))
+	}
+	return nil
+}
+
+func syncItem(ctx context.Context, client daemonclient.DaemonClient, item *syncPlanItem) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	c := item.config
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	logger.Infof("sync %s to %s, action: %s, reason: %s", c.URL, c.Output, item.action, item.reason)
+	if err := download(ctx, client, c, logger.With("url", c.URL)); err != nil {
+		return err
+	}
+	// keep the last modified time same as remote, then the file is treated as not changed in next sync
+	if item.lastModified > 0 {
+		mtime := time.Unix(0, item.lastModified*int64(time.Millisecond))
+		if err := os.Chtimes(c.Output, time.Now(), mtime); err != nil {
+			return errors.Wrap(err, "update last modified time")
+		}
+	}
+	return nil
+}
+
+// removeEmptyDirs removes the empty directories from dir to root, root is not removed
+func removeEmptyDirs(root, dir string) {
+	for dir != root && len(dir) > len(root) {
+		// os.Remove fails when the directory is not empty
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = path.Dir(dir)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/source"
+	sourcemock "d7y.io/dragonfly/v2/pkg/source/mock"
+)
+
+func Test_planSyncItem(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Unix(1600000000, 0)
+	lastModified := mtime.UnixNano() / int64(time.Millisecond)
+
+	sourceClient := sourcemock.NewMockResourceClient(gomock.NewController(t))
+	require.Nil(t, source.Register("http", sourceClient, func(request *source.Request) *source.Request {
+		return request
+	}))
+	defer source.UnRegister("http")
+
+	testCases := []struct {
+		name          string
+		content       string
+		mtime         time.Time
+		contentLength int64
+		action        syncAction
+	}{
+		{
+			name:          "not exist",
+			contentLength: 4,
+			action:        syncActionAdd,
+		},
+		{
+			name:          "not changed",
+			content:       "abcd",
+			mtime:         mtime,
+			contentLength: 4,
+			action:        syncActionKeep,
+		},
+		{
+			name:          "size changed",
+			content:       "abc",
+			mtime:         mtime,
+			contentLength: 4,
+			action:        syncActionUpdate,
+		},
+		{
+			name:          "last modified changed",
+			content:       "abcd",
+			mtime:         mtime.Add(-time.Hour),
+			contentLength: 4,
+			action:        syncActionUpdate,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(dir, tc.name)
+			if tc.content != "" {
+				require.Nil(t, os.WriteFile(output, []byte(tc.content), 0644))
+				require.Nil(t, os.Chtimes(output, tc.mtime, tc.mtime))
+			}
+			sourceClient.EXPECT().GetContentLength(gomock.Any()).Return(tc.contentLength, nil)
+			sourceClient.EXPECT().GetLastModified(gomock.Any()).Return(lastModified, nil)
+
+			item := planSyncItem(context.Background(), &config.DfgetConfig{URL: "http://a.b.c/" + tc.name, Output: output})
+			assert.Equal(t, tc.action, item.action)
+			assert.Equal(t, lastModified, item.lastModified)
+			if tc.action == syncActionKeep {
+				assert.Equal(t, "", item.config.Tag)
+			} else {
+				assert.Equal(t, syncTag("", lastModified), item.config.Tag)
+			}
+		})
+	}
+}
+
+func Test_planSyncDelete(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a", "b", "sub/c", "sub/d.log", journalFileName} {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, file), []byte("x"), 0644))
+	}
+
+	dirURL, err := url.Parse("http://a.b.c/data")
+	require.Nil(t, err)
+	cfg := &config.DfgetConfig{
+		Output:               dir,
+		RecursiveRejectRegex: `\.log$`,
+	}
+	configs := []*config.DfgetConfig{{Output: filepath.Join(dir, "a")}}
+
+	items, err := planSyncDelete(cfg, dirURL, configs)
+	assert.Nil(t, err)
+	var deletes []string
+	for _, item := range items {
+		assert.Equal(t, syncActionDelete, item.action)
+		deletes = append(deletes, item.output)
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "b"), filepath.Join(dir, "sub/c")}, deletes)
+
+	cfg.Output = filepath.Join(dir, "not-exist")
+	items, err = planSyncDelete(cfg, dirURL, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+}
@@ -70,7 +70,8 @@ func ListAvailablePlugins() {
 		}
 		typ, name := subs[1], subs[2]
 		switch typ {
-		case string(dfplugin.PluginTypeResource), string(dfplugin.PluginTypeScheduler), string(dfplugin.PluginTypeManager):
+		case string(dfplugin.PluginTypeResource), string(dfplugin.PluginTypeScheduler), string(dfplugin.PluginTypeManager),
+			string(dfplugin.PluginTypeStorage):
 			_, data, err := dfplugin.Load(d.PluginDir(), dfplugin.PluginType(typ), name, map[string]string{})
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "not valid plugin binary format %s: %q\n", fileName, err)
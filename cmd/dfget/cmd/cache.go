@@ -0,0 +1,196 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/dfget"
+	"d7y.io/dragonfly/v2/internal/dfnet"
+	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manage the tasks cached in the client daemon",
+	Long: `cache lists, inspects and evicts the tasks stored by the running client daemon.
+task can be specified by task id or by url, when using url, the task id is generated
+with --tag, --filter, --digest and --header just like downloading it.`,
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:               "ls",
+	Short:             "list all tasks in daemon storage",
+	Args:              cobra.NoArgs,
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		daemonClient, target, err := connectDaemon()
+		if err != nil {
+			return err
+		}
+		defer daemonClient.Close()
+
+		return dfget.ListCache(context.Background(), daemonClient, target, os.Stdout)
+	},
+}
+
+var cacheStatCmd = &cobra.Command{
+	Use:               "stat <task id|url>",
+	Short:             "show details of a task in daemon storage",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		daemonClient, target, err := connectDaemon()
+		if err != nil {
+			return err
+		}
+		defer daemonClient.Close()
+
+		taskID := dfget.CacheTaskID(args[0], urlMetaConfig(cmd))
+		return errors.Wrapf(dfget.StatCache(context.Background(), daemonClient, target, taskID, os.Stdout), "stat task %s", taskID)
+	},
+}
+
+var cacheDeleteCmd = &cobra.Command{
+	Use:               "rm <task id|url>",
+	Short:             "evict a task from daemon storage",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		daemonClient, target, err := connectDaemon()
+		if err != nil {
+			return err
+		}
+		defer daemonClient.Close()
+
+		taskID := dfget.CacheTaskID(args[0], urlMetaConfig(cmd))
+		if err := dfget.DeleteCache(context.Background(), daemonClient, target, taskID); err != nil {
+			return errors.Wrapf(err, "delete task %s", taskID)
+		}
+		fmt.Printf("task %s deleted\n", taskID)
+		return nil
+	},
+}
+
+var cachePinCmd = &cobra.Command{
+	Use:               "pin <task id|url>",
+	Short:             "pin a task in daemon storage, pinned task is never reclaimed by gc",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCachePin(cmd, args[0], true)
+	},
+}
+
+var cacheUnpinCmd = &cobra.Command{
+	Use:               "unpin <task id|url>",
+	Short:             "unpin a task in daemon storage, then it can be reclaimed by gc",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCachePin(cmd, args[0], false)
+	},
+}
+
+func init() {
+	// Add the command to parent
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd, cacheStatCmd, cacheDeleteCmd, cachePinCmd, cacheUnpinCmd)
+
+	addURLMetaFlags(cacheCmd.PersistentFlags())
+	for _, cmd := range []*cobra.Command{cachePinCmd, cacheUnpinCmd} {
+		cmd.Flags().Int32("priority", 0, "Priority of the task, task with lower priority is reclaimed first by gc")
+	}
+}
+
+func runCachePin(cmd *cobra.Command, arg string, pinned bool) error {
+	daemonClient, target, err := connectDaemon()
+	if err != nil {
+		return err
+	}
+	defer daemonClient.Close()
+
+	priority, _ := cmd.Flags().GetInt32("priority")
+	taskID := dfget.CacheTaskID(arg, urlMetaConfig(cmd))
+	if err := dfget.PinCache(context.Background(), daemonClient, target, taskID, pinned, priority); err != nil {
+		return errors.Wrapf(err, "pin task %s", taskID)
+	}
+	if pinned {
+		fmt.Printf("task %s pinned\n", taskID)
+	} else {
+		fmt.Printf("task %s unpinned\n", taskID)
+	}
+	return nil
+}
+
+// addURLMetaFlags adds the flags which are used for generating task id,
+// the flags are not bound to viper, avoid overriding the flags of root command
+func addURLMetaFlags(flags *pflag.FlagSet) {
+	flags.String("digest", "", "Digest of the url, in format of md5:xxx or sha256:yyy")
+	flags.String("tag", "", "Tag of the url")
+	flags.String("filter", "", "Filter the query parameters of the url, in format of key&sign")
+	flags.StringSliceP("header", "H", nil, "url header, eg: --header='Range: bytes=0-100'")
+}
+
+// urlMetaConfig returns the dfget config with the flags added by addURLMetaFlags
+func urlMetaConfig(cmd *cobra.Command) *config.DfgetConfig {
+	cfg := *dfgetConfig
+	flags := cmd.Flags()
+	cfg.Digest, _ = flags.GetString("digest")
+	cfg.Tag, _ = flags.GetString("tag")
+	cfg.Filter, _ = flags.GetString("filter")
+	cfg.Header, _ = flags.GetStringSlice("header")
+	if cfg.Digest != "" {
+		cfg.Tag = ""
+	}
+	return &cfg
+}
+
+// connectDaemon connects to the running daemon, it never spawns a new daemon
+func connectDaemon() (client.DaemonClient, dfnet.NetAddr, error) {
+	d, err := initDfgetDfpath(dfgetConfig)
+	if err != nil {
+		return nil, dfnet.NetAddr{}, err
+	}
+
+	target := dfnet.NetAddr{Type: dfnet.UNIX, Addr: d.DaemonSockPath()}
+	daemonClient, err := client.GetClientByAddr([]dfnet.NetAddr{target})
+	if err != nil {
+		return nil, target, err
+	}
+
+	if err := daemonClient.CheckHealth(context.Background(), target); err != nil {
+		daemonClient.Close()
+		return nil, target, errors.Wrap(err, "the daemon is unhealthy")
+	}
+	return daemonClient, target, nil
+}
@@ -0,0 +1,69 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"d7y.io/dragonfly/v2/client/dfget"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <task id|url> -O <path>",
+	Short: "export a cached task to a local file",
+	Long: `export writes a completed task stored in the running client daemon to a local file,
+it works offline and never contacts scheduler or origin. The task can be specified by task id
+or by url, when using url, the task id is generated with --tag, --filter, --digest and --header
+just like downloading it.`,
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		mode, _ := cmd.Flags().GetString("mode")
+		if output == "" {
+			return errors.New("--output is required")
+		}
+
+		daemonClient, target, err := connectDaemon()
+		if err != nil {
+			return err
+		}
+		defer daemonClient.Close()
+
+		if err := dfget.Export(context.Background(), daemonClient, target, urlMetaConfig(cmd), args[0], output, mode); err != nil {
+			return errors.Wrapf(err, "export %s", args[0])
+		}
+		fmt.Printf("%s exported to %s\n", args[0], output)
+		return nil
+	},
+}
+
+func init() {
+	// Add the command to parent
+	rootCmd.AddCommand(exportCmd)
+
+	flags := exportCmd.Flags()
+	flags.StringP("output", "O", "", "Destination path of the exported file")
+	flags.String("mode", "auto", "Export mode, one of auto, hardlink, reflink and copy, auto tries hardlink first and falls back to copy, reflink falls back to copy when it is not supported")
+	addURLMetaFlags(flags)
+}
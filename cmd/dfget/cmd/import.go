@@ -0,0 +1,72 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"d7y.io/dragonfly/v2/client/dfget"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import --url <logical url> --file <path>",
+	Short: "import a local file into the P2P cache",
+	Long: `import cuts a local file into pieces, stores them in the running client daemon and
+announces the task to scheduler, then other peers can download the url through P2P
+without downloading it from origin. The task id is generated from the url with --tag,
+--filter, --digest and --header just like downloading it.`,
+	Args:              cobra.NoArgs,
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, _ := cmd.Flags().GetString("url")
+		file, _ := cmd.Flags().GetString("file")
+		if url == "" || file == "" {
+			return errors.New("both --url and --file are required")
+		}
+
+		daemonClient, target, err := connectDaemon()
+		if err != nil {
+			return err
+		}
+		defer daemonClient.Close()
+
+		cfg := urlMetaConfig(cmd)
+		cfg.URL = url
+		taskID, err := dfget.Import(context.Background(), daemonClient, target, cfg, file)
+		if err != nil {
+			return errors.Wrapf(err, "import %s", file)
+		}
+		fmt.Printf("file %s imported, task id: %s\n", file, taskID)
+		return nil
+	},
+}
+
+func init() {
+	// Add the command to parent
+	rootCmd.AddCommand(importCmd)
+
+	flags := importCmd.Flags()
+	flags.String("url", "", "Logical url of the file, other peers download the file with it")
+	flags.String("file", "", "Path of the local file to import")
+	addURLMetaFlags(flags)
+}
@@ -68,49 +68,7 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:       true,
 	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		start := time.Now()
-
-		// Initialize daemon dfpath
-		d, err := initDfgetDfpath(dfgetConfig)
-		if err != nil {
-			return err
-		}
-
-		// Initialize logger
-		if err := logcore.InitDfget(dfgetConfig.Console, d.LogDir()); err != nil {
-			return errors.Wrap(err, "init client dfget logger")
-		}
-
-		// update plugin directory
-		source.UpdatePluginDir(d.PluginDir())
-
-		// Convert config
-		if err := dfgetConfig.Convert(args); err != nil {
-			return err
-		}
-
-		// Validate config
-		if err := dfgetConfig.Validate(); err != nil {
-			return err
-		}
-
-		fmt.Printf("--%s--  %s\n", start.Format("2006-01-02 15:04:05"), dfgetConfig.URL)
-		fmt.Printf("dfget version: %s\n", version.GitVersion)
-		fmt.Printf("current user: %s, default peer ip: %s\n", basic.Username, iputils.IPv4)
-		fmt.Printf("output path: %s\n", dfgetConfig.Output)
-
-		//  do get file
-		var errInfo string
-		err = runDfget(d.DfgetLockPath(), d.DaemonSockPath())
-		if err != nil {
-			errInfo = fmt.Sprintf("error: %v", err)
-		}
-
-		msg := fmt.Sprintf("download success: %t cost: %d ms %s", err == nil, time.Now().Sub(start).Milliseconds(), errInfo)
-		logger.With("url", dfgetConfig.URL).Info(msg)
-		fmt.Println(msg)
-
-		return errors.Wrapf(err, "download url: %s", dfgetConfig.URL)
+		return runDownload(args)
 	},
 }
 
@@ -119,7 +77,7 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		logger.Error(err)
-		os.Exit(1)
+		os.Exit(dfget.ExitCode(err))
 	}
 }
 
@@ -136,7 +94,7 @@ func init() {
 		"Download one file from the url, equivalent to the command's first position argument")
 
 	flagSet.StringP("output", "O", dfgetConfig.Output,
-		"Destination path which is used to store the downloaded file, it must be a full path")
+		"Destination path which is used to store the downloaded file, it must be a full path, '-' streams the content to stdout")
 
 	flagSet.Duration("timeout", dfgetConfig.Timeout, "Timeout for the downloading task, 0 is infinite")
 
@@ -144,7 +102,12 @@ func init() {
 		"The downloading network bandwidth limit per second in format of G(B)/g/M(B)/m/K(B)/k/B, pure number will be parsed as Byte, 0 is infinite")
 
 	flagSet.String("digest", dfgetConfig.Digest,
-		"Check the integrity of the downloaded file with digest, in format of md5:xxx or sha256:yyy")
+		"Check the integrity of the downloaded file with digest, in format of md5:xxx, sha256:xxx, sha512:xxx or blake3:xxx")
+
+	flagSet.String("signature-url", dfgetConfig.SignatureURL,
+		"Verify the downloaded file with the detached signature in the url by daemon, the file is not saved when it is not signed by the trusted keys of daemon")
+
+	flagSet.String("signature-type", dfgetConfig.SignatureType, "The type of the detached signature: minisign/gpg/cosign")
 
 	flagSet.String("tag", dfgetConfig.Tag,
 		"Different tags for the same url will be divided into different P2P overlay, it conflicts with --digest")
@@ -158,18 +121,36 @@ func init() {
 	flagSet.Bool("disable-back-source", dfgetConfig.DisableBackSource,
 		"Disable downloading directly from source when the daemon fails to download file")
 
+	flagSet.Bool("disable-file-attributes", dfgetConfig.DisableFileAttributes,
+		"Do not apply the mode, modification time and content type of the resource to the output, "+
+			"the output is copied instead of hard linked to the daemon storage when they are applied")
+
+	flagSet.Bool("pin", dfgetConfig.Pin, "Pin the task in the daemon storage, pinned task is never reclaimed by gc")
+
+	flagSet.Int32("priority", dfgetConfig.Priority,
+		"Priority of the task in the daemon storage, task with lower priority is reclaimed first by gc")
+
 	flagSet.StringP("pattern", "p", dfgetConfig.Pattern, "The downloading pattern: p2p/cdn/source")
 
 	flagSet.BoolP("show-progress", "b", dfgetConfig.ShowProgress, "Show progress bar, it conflicts with --console")
 
+	flagSet.String("output-format", dfgetConfig.OutputFormat,
+		"The format of messages: text/json, json prints the download events in json lines for machine, "+
+			"the exit codes are documented in dfget cli reference")
+
 	flagSet.String("callsystem", dfgetConfig.CallSystem, "The caller name which is mainly used for statistics and access control")
 
 	flagSet.String("workhome", dfgetConfig.WorkHome, "Dfget working directory")
 
 	flagSet.String("logdir", dfgetConfig.LogDir, "Dfget log directory")
 
+	flagSet.String("extract", dfgetConfig.Extract,
+		"Extract the downloaded tar, gzip or zstd archive to the directory, the directory is replaced after all files are extracted, "+
+			"the archive is cached compressed in daemon")
+
 	flagSet.BoolP("recursive", "r", dfgetConfig.Recursive,
-		"Recursively download all resources in target url, the target source client must support list action")
+		"Recursively download all resources in target url, the target source client must support list action, "+
+			"the completed files are recorded in .dfget.journal of output directory and skipped when re-running")
 
 	flagSet.Uint("level", dfgetConfig.RecursiveLevel,
 		"Recursively download only. Set the maximum number of subdirectories that dfget will recurse into. Set to 0 for no limit")
@@ -183,12 +164,80 @@ func init() {
 	flagSet.String("reject-regex", dfgetConfig.RecursiveRejectRegex,
 		`Recursively download only. Specify a regular expression to reject the complete URL. In this case, you have to enclose the pattern into quotes to prevent your shell from expanding it`)
 
+	flagSet.String("manifest", dfgetConfig.Manifest,
+		"Download all items in the manifest file concurrently, the file is in format of txt, json or yaml, "+
+			"every line of txt file is 'url [output] [digest]', the relative output is based on --output")
+
+	flagSet.Int("parallelism", dfgetConfig.Parallelism, "The max number of files downloaded concurrently in manifest and recursive download")
+
 	// Bind cmd flags
 	if err := viper.BindPFlags(flagSet); err != nil {
 		panic(errors.Wrap(err, "bind dfget flags to viper"))
 	}
 }
 
+// runDownload initializes the dfget environment and downloads the url in args or dfgetConfig
+func runDownload(args []string) error {
+	start := time.Now()
+	err := download(start, args)
+	// the result event is emitted for all errors, including the invalid flags
+	dfget.EmitResult(dfgetConfig, start, err)
+	return err
+}
+
+func download(start time.Time, args []string) error {
+
+	// Initialize daemon dfpath
+	d, err := initDfgetDfpath(dfgetConfig)
+	if err != nil {
+		return err
+	}
+
+	// Initialize logger
+	if err := logcore.InitDfget(dfgetConfig.Console, d.LogDir()); err != nil {
+		return errors.Wrap(err, "init client dfget logger")
+	}
+
+	// update plugin directory
+	source.UpdatePluginDir(d.PluginDir())
+
+	// Convert config
+	if err := dfgetConfig.Convert(args); err != nil {
+		return err
+	}
+
+	// Validate config
+	if err := dfgetConfig.Validate(); err != nil {
+		return err
+	}
+
+	target := dfgetConfig.URL
+	if dfgetConfig.Manifest != "" {
+		target = dfgetConfig.Manifest
+	}
+	out := dfget.MessageWriter(dfgetConfig)
+	fmt.Fprintf(out, "--%s--  %s\n", start.Format("2006-01-02 15:04:05"), target)
+	fmt.Fprintf(out, "dfget version: %s\n", version.GitVersion)
+	fmt.Fprintf(out, "current user: %s, default peer ip: %s\n", basic.Username, iputils.IPv4)
+	fmt.Fprintf(out, "output path: %s\n", dfgetConfig.Output)
+
+	//  do get file
+	var errInfo string
+	err = runDfget(d.DfgetLockPath(), d.DaemonSockPath())
+	if err != nil {
+		errInfo = fmt.Sprintf("error: %v", err)
+	}
+
+	msg := fmt.Sprintf("download success: %t cost: %d ms %s", err == nil, time.Now().Sub(start).Milliseconds(), errInfo)
+	logger.With("url", target).Info(msg)
+	fmt.Fprintln(out, msg)
+
+	if dfgetConfig.Manifest != "" {
+		return errors.Wrapf(err, "download manifest: %s", dfgetConfig.Manifest)
+	}
+	return errors.Wrapf(err, "download url: %s", dfgetConfig.URL)
+}
+
 func initDfgetDfpath(cfg *config.ClientOption) (dfpath.Dfpath, error) {
 	options := []dfpath.Option{}
 	if cfg.WorkHome != "" {
@@ -227,6 +276,9 @@ func runDfget(dfgetLockPath, daemonSockPath string) error {
 		}
 	}
 
+	if dfgetConfig.Manifest != "" {
+		return dfget.DownloadManifest(dfgetConfig, daemonClient, dfget.MessageWriter(dfgetConfig))
+	}
 	return dfget.Download(dfgetConfig, daemonClient)
 }
 
@@ -0,0 +1,57 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync url -O directory",
+	Short: "mirror all resources in target url to local directory",
+	Long: `sync lists all resources in target url like recursive download, and compares them with the local
+files in output directory by content length and last modified time, only the new or changed resources
+are downloaded through P2P. With --delete, the local files which are not in target url are deleted,
+the files not accepted by --level, --accept-regex and --reject-regex are always kept, --delete requires
+an explicit output directory which is not the root or home directory.
+With --dry-run, the plan is printed without downloading or deleting any files.`,
+	Args:               cobra.MaximumNArgs(1),
+	DisableAutoGenTag:  true,
+	SilenceUsage:       true,
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dfgetConfig.Sync, dfgetConfig.Recursive = true, true
+		return runDownload(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	flagSet := syncCmd.Flags()
+	flagSet.Bool("delete", dfgetConfig.SyncDelete, "Delete the local files which are not in target url, output directory is required")
+	flagSet.Bool("dry-run", dfgetConfig.SyncDryRun, "Print the sync plan without downloading or deleting any files")
+	if err := viper.BindPFlags(flagSet); err != nil {
+		panic(errors.Wrap(err, "bind dfget sync flags to viper"))
+	}
+
+	// share the download flags with root command, they are already bound to viper
+	flagSet.AddFlagSet(rootCmd.Flags())
+}
@@ -0,0 +1,48 @@
+#!/bin/bash
+
+set -o nounset
+set -o errexit
+set -o pipefail
+
+# address of the registry mirror of dfget daemon
+DFDAEMON_MIRROR=${DFDAEMON_MIRROR:-"http://127.0.0.1:65001"}
+# registries config path of containerd, the same as config_path in /etc/containerd/config.toml
+CONTAINERD_CONFIG_PATH=${CONTAINERD_CONFIG_PATH:-"/etc/containerd/certs.d"}
+
+server() {
+    case "${1}" in
+    docker.io)
+        echo "https://registry-1.docker.io"
+        ;;
+    *)
+        echo "https://${1}"
+        ;;
+    esac
+}
+
+gen-hosts() {
+    local registry=${1}
+    local dir="${CONTAINERD_CONFIG_PATH}/${registry}"
+    mkdir -p "${dir}"
+    cat <<EOT > "${dir}/hosts.toml"
+server = "$(server "${registry}")"
+
+[host."${DFDAEMON_MIRROR}"]
+  capabilities = ["pull", "resolve"]
+  [host."${DFDAEMON_MIRROR}".header]
+    X-Dragonfly-Registry = ["$(server "${registry}")"]
+EOT
+    echo "generated ${dir}/hosts.toml"
+}
+
+main() {
+    if [ $# -eq 0 ]; then
+        echo >&2 "usage: $0 registry [registry...], for example: $0 docker.io ghcr.io"
+        exit 1
+    fi
+    for registry in "$@"; do
+        gen-hosts "${registry}"
+    done
+}
+
+main "$@"
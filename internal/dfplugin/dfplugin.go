@@ -31,14 +31,16 @@ const (
 	// PluginInitFuncName indicates the function `DragonflyPluginInit` must be implemented in plugin
 	PluginInitFuncName = "DragonflyPluginInit"
 
-	// PluginMetaKeyType indicates the type of plugin, currently support: resource
+	// PluginMetaKeyType indicates the type of plugin, currently support: resource, manager, scheduler and storage
 	PluginMetaKeyType = "type"
 
 	// PluginMetaKeyName indicates the name of a plugin
 	PluginMetaKeyName = "name"
 )
 
-var PluginFormatExpr = regexp.MustCompile("d7y-(resource|manager|scheduler)-plugin-([a-z0-9]+).so")
+// PluginFormatExpr matches the file name of a plugin, the name of a plugin may contain '.', '-' and '_',
+// like the storage driver io.d7y.storage.v2.content_addressable
+var PluginFormatExpr = regexp.MustCompile(`^d7y-(resource|manager|scheduler|storage)-plugin-([a-z0-9._-]+)\.so$`)
 
 type PluginType string
 
@@ -46,12 +48,16 @@ const (
 	PluginTypeResource  = PluginType("resource")
 	PluginTypeManager   = PluginType("manager")
 	PluginTypeScheduler = PluginType("scheduler")
+	PluginTypeStorage   = PluginType("storage")
 )
 
 type PluginInitFunc func(option map[string]string) (plugin interface{}, meta map[string]string, err error)
 
 func Load(dir string, typ PluginType, name string, option map[string]string) (interface{}, map[string]string, error) {
 	soName := fmt.Sprintf(PluginFormat, string(typ), name)
+	if !PluginFormatExpr.MatchString(soName) {
+		return nil, nil, fmt.Errorf("invalid plugin name: %s", name)
+	}
 	p, err := plugin.Open(path.Join(dir, soName))
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,53 @@
+/*
+ *     Copyright 2020 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfplugin
+
+import (
+	"fmt"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestPluginFormatExpr(t *testing.T) {
+	assert := testifyassert.New(t)
+	for _, tc := range []struct {
+		typ  PluginType
+		name string
+		ok   bool
+	}{
+		{typ: PluginTypeResource, name: "oss", ok: true},
+		{typ: PluginTypeStorage, name: "io.d7y.storage.v2.content_addressable", ok: true},
+		{typ: PluginTypeStorage, name: "my-driver", ok: true},
+		{typ: PluginTypeStorage, name: "../driver", ok: false},
+		{typ: PluginTypeStorage, name: "Driver", ok: false},
+		{typ: PluginType("unknown"), name: "driver", ok: false},
+	} {
+		subs := PluginFormatExpr.FindStringSubmatch(fmt.Sprintf(PluginFormat, tc.typ, tc.name))
+		if !tc.ok {
+			assert.Nil(subs, tc.name)
+			continue
+		}
+		if assert.Len(subs, 3, tc.name) {
+			assert.Equal(string(tc.typ), subs[1])
+			assert.Equal(tc.name, subs[2])
+		}
+	}
+
+	_, _, err := Load(t.TempDir(), PluginTypeStorage, "../driver", nil)
+	assert.EqualError(err, "invalid plugin name: ../driver")
+}
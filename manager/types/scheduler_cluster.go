@@ -56,6 +56,8 @@ type SchedulerClusterConfig struct {
 
 type SchedulerClusterClientConfig struct {
 	LoadLimit uint32 `yaml:"loadLimit" mapstructure:"loadLimit" json:"load_limit" binding:"omitempty,gte=1,lte=5000"`
+	// RegistryMirrors replaces proxy.registryMirrors of dfdaemon, the items are same as dfdaemon config
+	RegistryMirrors []map[string]interface{} `yaml:"registryMirrors" mapstructure:"registryMirrors" json:"registry_mirrors" binding:"omitempty"`
 }
 
 type SchedulerClusterScopes struct {
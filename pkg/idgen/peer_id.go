@@ -19,12 +19,20 @@ package idgen
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
+const cdnPeerIDSuffix = "_CDN"
+
 func CDNPeerID(ip string) string {
-	return fmt.Sprintf("%s_%s", PeerID(ip), "CDN")
+	return PeerID(ip) + cdnPeerIDSuffix
+}
+
+// IsCDNPeerID indicates whether the peer id is generated by CDNPeerID
+func IsCDNPeerID(peerID string) bool {
+	return strings.HasSuffix(peerID, cdnPeerIDSuffix)
 }
 
 func PeerID(ip string) string {
@@ -101,3 +101,9 @@ func TestCDNPeerID(t *testing.T) {
 		})
 	}
 }
+
+func TestIsCDNPeerID(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(IsCDNPeerID(CDNPeerID("127.0.0.1")))
+	assert.False(IsCDNPeerID(PeerID("127.0.0.1")))
+}
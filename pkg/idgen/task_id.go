@@ -33,7 +33,10 @@ func TaskID(url string, meta *base.UrlMeta) string {
 	}
 
 	var data []string
-	data = append(data, urlutils.FilterURLParam(url, filters))
+	// content addressed task is identified by digest, so it is shared by different urls
+	if meta == nil || !meta.ContentAddressed || meta.Digest == "" || meta.Range != "" {
+		data = append(data, urlutils.FilterURLParam(url, filters))
+	}
 	if meta != nil {
 		if meta.Digest != "" {
 			data = append(data, meta.Digest)
@@ -76,6 +76,32 @@ func TestTaskID(t *testing.T) {
 				assert.Equal("2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b", d)
 			},
 		},
+		{
+			name: "generate taskID with content addressed digest",
+			url:  "https://example.com",
+			meta: &base.UrlMeta{
+				Digest:           "sha256:bar",
+				ContentAddressed: true,
+			},
+			expect: func(t *testing.T, d interface{}) {
+				assert := assert.New(t)
+				assert.Equal(TaskID("https://mirror.example.com", &base.UrlMeta{Digest: "sha256:bar", ContentAddressed: true}), d)
+				assert.NotEqual(TaskID("https://example.com", &base.UrlMeta{Digest: "sha256:bar"}), d)
+			},
+		},
+		{
+			name: "generate taskID with content addressed range",
+			url:  "https://example.com",
+			meta: &base.UrlMeta{
+				Digest:           "sha256:bar",
+				Range:            "0-9",
+				ContentAddressed: true,
+			},
+			expect: func(t *testing.T, d interface{}) {
+				assert := assert.New(t)
+				assert.NotEqual(TaskID("https://mirror.example.com", &base.UrlMeta{Digest: "sha256:bar", Range: "0-9", ContentAddressed: true}), d)
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -170,7 +170,7 @@ func (m *UrlMeta) validate(all bool) error {
 		if !_UrlMeta_Digest_Pattern.MatchString(m.GetDigest()) {
 			err := UrlMetaValidationError{
 				field:  "Digest",
-				reason: "value does not match regex pattern \"^(md5)|(sha256):[A-Fa-f0-9]+$\"",
+				reason: "value does not match regex pattern \"^[a-z0-9]+:[A-Fa-f0-9]+$\"",
 			}
 			if !all {
 				return err
@@ -201,6 +201,37 @@ func (m *UrlMeta) validate(all bool) error {
 
 	// no validation rules for Header
 
+	if all {
+		switch v := interface{}(m.GetSignature()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, UrlMetaValidationError{
+					field:  "Signature",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, UrlMetaValidationError{
+					field:  "Signature",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetSignature()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return UrlMetaValidationError{
+				field:  "Signature",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	// no validation rules for ContentAddressed
+
 	if len(errors) > 0 {
 		return UrlMetaMultiError(errors)
 	}
@@ -277,10 +308,146 @@ var _ interface {
 	ErrorName() string
 } = UrlMetaValidationError{}
 
-var _UrlMeta_Digest_Pattern = regexp.MustCompile("^(md5)|(sha256):[A-Fa-f0-9]+$")
+var _UrlMeta_Digest_Pattern = regexp.MustCompile("^[a-z0-9]+:[A-Fa-f0-9]+$")
 
 var _UrlMeta_Range_Pattern = regexp.MustCompile("^[0-9]+-[0-9]+$")
 
+// Validate checks the field values on Signature with the rules defined in the
+// proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *Signature) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on Signature with the rules defined in
+// the proto definition for this message. If any rules are violated, the
+// result is a list of violation errors wrapped in SignatureMultiError, or nil
+// if none found.
+func (m *Signature) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *Signature) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if _, ok := _Signature_Type_InLookup[m.GetType()]; !ok {
+		err := SignatureValidationError{
+			field:  "Type",
+			reason: "value must be in list [minisign gpg cosign]",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if uri, err := url.Parse(m.GetUrl()); err != nil {
+		err = SignatureValidationError{
+			field:  "Url",
+			reason: "value must be a valid URI",
+			cause:  err,
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	} else if !uri.IsAbs() {
+		err := SignatureValidationError{
+			field:  "Url",
+			reason: "value must be absolute",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if len(errors) > 0 {
+		return SignatureMultiError(errors)
+	}
+	return nil
+}
+
+// SignatureMultiError is an error wrapping multiple validation errors returned
+// by Signature.ValidateAll() if the designated constraints aren't met.
+type SignatureMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m SignatureMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m SignatureMultiError) AllErrors() []error { return m }
+
+// SignatureValidationError is the validation error returned by
+// Signature.Validate if the designated constraints aren't met.
+type SignatureValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e SignatureValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e SignatureValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e SignatureValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e SignatureValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e SignatureValidationError) ErrorName() string { return "SignatureValidationError" }
+
+// Error satisfies the builtin error interface
+func (e SignatureValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sSignature.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = SignatureValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = SignatureValidationError{}
+
+var _Signature_Type_InLookup = map[string]struct{}{
+	"minisign": {},
+	"gpg":      {},
+	"cosign":   {},
+}
+
 // Validate checks the field values on HostLoad with the rules defined in the
 // proto definition for this message. If any rules are violated, the first
 // error encountered is returned, or nil if there are no violations.
@@ -813,6 +980,35 @@ func (m *PiecePacket) validate(all bool) error {
 
 	// no validation rules for PieceMd5Sign
 
+	if all {
+		switch v := interface{}(m.GetFileAttributes()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, PiecePacketValidationError{
+					field:  "FileAttributes",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, PiecePacketValidationError{
+					field:  "FileAttributes",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetFileAttributes()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return PiecePacketValidationError{
+				field:  "FileAttributes",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
 	if len(errors) > 0 {
 		return PiecePacketMultiError(errors)
 	}
@@ -888,3 +1084,117 @@ var _ interface {
 	Cause() error
 	ErrorName() string
 } = PiecePacketValidationError{}
+
+// Validate checks the field values on FileAttributes with the rules defined in
+// the proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *FileAttributes) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on FileAttributes with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// result is a list of violation errors wrapped in FileAttributesMultiError,
+// or nil if none found.
+func (m *FileAttributes) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *FileAttributes) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if m.GetMode() > 511 {
+		err := FileAttributesValidationError{
+			field:  "Mode",
+			reason: "value must be less than or equal to 511",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	// no validation rules for LastModified
+
+	// no validation rules for ContentType
+
+	if len(errors) > 0 {
+		return FileAttributesMultiError(errors)
+	}
+	return nil
+}
+
+// FileAttributesMultiError is an error wrapping multiple validation errors
+// returned by FileAttributes.ValidateAll() if the designated constraints
+// aren't met.
+type FileAttributesMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m FileAttributesMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m FileAttributesMultiError) AllErrors() []error { return m }
+
+// FileAttributesValidationError is the validation error returned by
+// FileAttributes.Validate if the designated constraints aren't met.
+type FileAttributesValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e FileAttributesValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e FileAttributesValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e FileAttributesValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e FileAttributesValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e FileAttributesValidationError) ErrorName() string { return "FileAttributesValidationError" }
+
+// Error satisfies the builtin error interface
+func (e FileAttributesValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sFileAttributes.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = FileAttributesValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = FileAttributesValidationError{}
@@ -73,10 +73,25 @@ func GetElasticClientByAddrs(addrs []dfnet.NetAddr, opts ...grpc.DialOption) (Da
 type DaemonClient interface {
 	Download(ctx context.Context, req *dfdaemon.DownRequest, opts ...grpc.CallOption) (*DownResultStream, error)
 
+	// DownloadStream is not retried after the stream is created, the received content can not be rolled back
+	DownloadStream(ctx context.Context, req *dfdaemon.DownRequest, opts ...grpc.CallOption) (dfdaemon.Daemon_DownloadStreamClient, error)
+
 	GetPieceTasks(ctx context.Context, addr dfnet.NetAddr, ptr *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error)
 
 	CheckHealth(ctx context.Context, target dfnet.NetAddr, opts ...grpc.CallOption) error
 
+	ListTasks(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ListTasksRequest, opts ...grpc.CallOption) (*dfdaemon.ListTasksResult, error)
+
+	StatTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.StatTaskRequest, opts ...grpc.CallOption) (*dfdaemon.StatTaskResult, error)
+
+	DeleteTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.DeleteTaskRequest, opts ...grpc.CallOption) error
+
+	PinTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.PinTaskRequest, opts ...grpc.CallOption) error
+
+	ImportTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ImportTaskRequest, opts ...grpc.CallOption) error
+
+	ExportTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ExportTaskRequest, opts ...grpc.CallOption) error
+
 	Close() error
 }
 
@@ -107,6 +122,29 @@ func (dc *daemonClient) Download(ctx context.Context, req *dfdaemon.DownRequest,
 	return newDownResultStream(ctx, dc, taskID, req, opts)
 }
 
+func (dc *daemonClient) DownloadStream(ctx context.Context, req *dfdaemon.DownRequest, opts ...grpc.CallOption) (dfdaemon.Daemon_DownloadStreamClient,
+	error) {
+	req.Uuid = uuid.New().String()
+	taskID := idgen.TaskID(req.Url, req.UrlMeta)
+	var target string
+	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
+		var (
+			client dfdaemon.DaemonClient
+			err    error
+		)
+		client, target, err = dc.getDaemonClient(taskID, false)
+		if err != nil {
+			return nil, err
+		}
+		return client.DownloadStream(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.WithTaskID(taskID).Infof("DownloadStream: invoke daemon node %s DownloadStream failed: %v", target, err)
+		return nil, err
+	}
+	return res.(dfdaemon.Daemon_DownloadStreamClient), nil
+}
+
 func (dc *daemonClient) GetPieceTasks(ctx context.Context, target dfnet.NetAddr, ptr *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket,
 	error) {
 	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
@@ -137,3 +175,93 @@ func (dc *daemonClient) CheckHealth(ctx context.Context, target dfnet.NetAddr, o
 	}
 	return
 }
+
+func (dc *daemonClient) ListTasks(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ListTasksRequest, opts ...grpc.CallOption) (*dfdaemon.ListTasksResult,
+	error) {
+	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
+		client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+		}
+		return client.ListTasks(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.Infof("ListTasks: invoke daemon node %s ListTasks failed: %v", target, err)
+		return nil, err
+	}
+	return res.(*dfdaemon.ListTasksResult), nil
+}
+
+func (dc *daemonClient) StatTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.StatTaskRequest, opts ...grpc.CallOption) (*dfdaemon.StatTaskResult,
+	error) {
+	res, err := rpc.ExecuteWithRetry(func() (interface{}, error) {
+		client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+		}
+		return client.StatTask(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.WithTaskID(req.TaskId).Infof("StatTask: invoke daemon node %s StatTask failed: %v", target, err)
+		return nil, err
+	}
+	return res.(*dfdaemon.StatTaskResult), nil
+}
+
+func (dc *daemonClient) DeleteTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.DeleteTaskRequest, opts ...grpc.CallOption) (err error) {
+	_, err = rpc.ExecuteWithRetry(func() (interface{}, error) {
+		client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+		}
+		return client.DeleteTask(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.WithTaskID(req.TaskId).Infof("DeleteTask: invoke daemon node %s DeleteTask failed: %v", target, err)
+		return
+	}
+	return
+}
+
+func (dc *daemonClient) PinTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.PinTaskRequest, opts ...grpc.CallOption) (err error) {
+	_, err = rpc.ExecuteWithRetry(func() (interface{}, error) {
+		client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+		}
+		return client.PinTask(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.WithTaskID(req.TaskId).Infof("PinTask: invoke daemon node %s PinTask failed: %v", target, err)
+		return
+	}
+	return
+}
+
+// ImportTask is not retried, the daemon copies the whole file for every call
+func (dc *daemonClient) ImportTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ImportTaskRequest, opts ...grpc.CallOption) error {
+	client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+	if err != nil {
+		return fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+	}
+	if _, err = client.ImportTask(ctx, req, opts...); err != nil {
+		logger.Infof("ImportTask: invoke daemon node %s ImportTask failed: %v", target, err)
+		return err
+	}
+	return nil
+}
+
+func (dc *daemonClient) ExportTask(ctx context.Context, target dfnet.NetAddr, req *dfdaemon.ExportTaskRequest, opts ...grpc.CallOption) (err error) {
+	_, err = rpc.ExecuteWithRetry(func() (interface{}, error) {
+		client, err := dc.getDaemonClientWithTarget(target.GetEndpoint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect server %s: %v", target.GetEndpoint(), err)
+		}
+		return client.ExportTask(ctx, req, opts...)
+	}, 0.2, 2.0, 3, nil)
+	if err != nil {
+		logger.Infof("ExportTask: invoke daemon node %s ExportTask failed: %v", target, err)
+		return
+	}
+	return
+}
@@ -176,6 +176,57 @@ func (m *DownRequest) validate(all bool) error {
 
 	// no validation rules for Gid
 
+	if m.GetMode() > 511 {
+		err := DownRequestValidationError{
+			field:  "Mode",
+			reason: "value must be less than or equal to 511",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	for idx, item := range m.GetExtraOutputs() {
+		_, _ = idx, item
+
+		if all {
+			switch v := interface{}(item).(type) {
+			case interface{ ValidateAll() error }:
+				if err := v.ValidateAll(); err != nil {
+					errors = append(errors, DownRequestValidationError{
+						field:  fmt.Sprintf("ExtraOutputs[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			case interface{ Validate() error }:
+				if err := v.Validate(); err != nil {
+					errors = append(errors, DownRequestValidationError{
+						field:  fmt.Sprintf("ExtraOutputs[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			}
+		} else if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return DownRequestValidationError{
+					field:  fmt.Sprintf("ExtraOutputs[%v]", idx),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	// no validation rules for DisableFileAttributes
+
+	// no validation rules for Pin
+
+	// no validation rules for Priority
+
 	if len(errors) > 0 {
 		return DownRequestMultiError(errors)
 	}
@@ -266,6 +317,133 @@ var _DownRequest_Pattern_InLookup = map[string]struct{}{
 	"source": {},
 }
 
+// Validate checks the field values on Output with the rules defined in the
+// proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *Output) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on Output with the rules defined in the
+// proto definition for this message. If any rules are violated, the result is
+// a list of violation errors wrapped in OutputMultiError, or nil if none found.
+func (m *Output) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *Output) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetPath()) < 1 {
+		err := OutputValidationError{
+			field:  "Path",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if m.GetMode() > 511 {
+		err := OutputValidationError{
+			field:  "Mode",
+			reason: "value must be less than or equal to 511",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if m.Uid != nil {
+		// no validation rules for Uid
+	}
+
+	if m.Gid != nil {
+		// no validation rules for Gid
+	}
+
+	if len(errors) > 0 {
+		return OutputMultiError(errors)
+	}
+	return nil
+}
+
+// OutputMultiError is an error wrapping multiple validation errors returned by
+// Output.ValidateAll() if the designated constraints aren't met.
+type OutputMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m OutputMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m OutputMultiError) AllErrors() []error { return m }
+
+// OutputValidationError is the validation error returned by Output.Validate if
+// the designated constraints aren't met.
+type OutputValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e OutputValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e OutputValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e OutputValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e OutputValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e OutputValidationError) ErrorName() string { return "OutputValidationError" }
+
+// Error satisfies the builtin error interface
+func (e OutputValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sOutput.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = OutputValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = OutputValidationError{}
+
 // Validate checks the field values on DownResult with the rules defined in the
 // proto definition for this message. If any rules are violated, the first
 // error encountered is returned, or nil if there are no violations.
@@ -323,6 +501,16 @@ func (m *DownResult) validate(all bool) error {
 
 	// no validation rules for Done
 
+	// no validation rules for ContentLength
+
+	// no validation rules for Reuse
+
+	// no validation rules for PeerLength
+
+	// no validation rules for CdnLength
+
+	// no validation rules for BackSourceLength
+
 	if len(errors) > 0 {
 		return DownResultMultiError(errors)
 	}
@@ -398,3 +586,1288 @@ var _ interface {
 	Cause() error
 	ErrorName() string
 } = DownResultValidationError{}
+
+// Validate checks the field values on DownStreamResult with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *DownStreamResult) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on DownStreamResult with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// DownStreamResultMultiError, or nil if none found.
+func (m *DownStreamResult) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *DownStreamResult) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	// no validation rules for TaskId
+
+	// no validation rules for PeerId
+
+	// no validation rules for ContentLength
+
+	// no validation rules for Data
+
+	if len(errors) > 0 {
+		return DownStreamResultMultiError(errors)
+	}
+	return nil
+}
+
+// DownStreamResultMultiError is an error wrapping multiple validation errors
+// returned by DownStreamResult.ValidateAll() if the designated constraints
+// aren't met.
+type DownStreamResultMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m DownStreamResultMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m DownStreamResultMultiError) AllErrors() []error { return m }
+
+// DownStreamResultValidationError is the validation error returned by
+// DownStreamResult.Validate if the designated constraints aren't met.
+type DownStreamResultValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e DownStreamResultValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e DownStreamResultValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e DownStreamResultValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e DownStreamResultValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e DownStreamResultValidationError) ErrorName() string { return "DownStreamResultValidationError" }
+
+// Error satisfies the builtin error interface
+func (e DownStreamResultValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sDownStreamResult.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = DownStreamResultValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = DownStreamResultValidationError{}
+
+// Validate checks the field values on TaskInfo with the rules defined in the
+// proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *TaskInfo) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on TaskInfo with the rules defined in
+// the proto definition for this message. If any rules are violated, the
+// result is a list of violation errors wrapped in TaskInfoMultiError, or nil
+// if none found.
+func (m *TaskInfo) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *TaskInfo) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetTaskId()) < 1 {
+		err := TaskInfoValidationError{
+			field:  "TaskId",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if utf8.RuneCountInString(m.GetPeerId()) < 1 {
+		err := TaskInfoValidationError{
+			field:  "PeerId",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	// no validation rules for Url
+
+	if all {
+		switch v := interface{}(m.GetUrlMeta()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, TaskInfoValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, TaskInfoValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetUrlMeta()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return TaskInfoValidationError{
+				field:  "UrlMeta",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	// no validation rules for ContentLength
+
+	// no validation rules for TotalPiece
+
+	// no validation rules for CompletedPiece
+
+	// no validation rules for Done
+
+	// no validation rules for LastAccessTime
+
+	// no validation rules for Pinned
+
+	// no validation rules for Priority
+
+	if len(errors) > 0 {
+		return TaskInfoMultiError(errors)
+	}
+	return nil
+}
+
+// TaskInfoMultiError is an error wrapping multiple validation errors returned
+// by TaskInfo.ValidateAll() if the designated constraints aren't met.
+type TaskInfoMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m TaskInfoMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m TaskInfoMultiError) AllErrors() []error { return m }
+
+// TaskInfoValidationError is the validation error returned by
+// TaskInfo.Validate if the designated constraints aren't met.
+type TaskInfoValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e TaskInfoValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e TaskInfoValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e TaskInfoValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e TaskInfoValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e TaskInfoValidationError) ErrorName() string { return "TaskInfoValidationError" }
+
+// Error satisfies the builtin error interface
+func (e TaskInfoValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sTaskInfo.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = TaskInfoValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = TaskInfoValidationError{}
+
+// Validate checks the field values on ListTasksRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *ListTasksRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ListTasksRequest with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// ListTasksRequestMultiError, or nil if none found.
+func (m *ListTasksRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ListTasksRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if len(errors) > 0 {
+		return ListTasksRequestMultiError(errors)
+	}
+	return nil
+}
+
+// ListTasksRequestMultiError is an error wrapping multiple validation errors
+// returned by ListTasksRequest.ValidateAll() if the designated constraints
+// aren't met.
+type ListTasksRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ListTasksRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ListTasksRequestMultiError) AllErrors() []error { return m }
+
+// ListTasksRequestValidationError is the validation error returned by
+// ListTasksRequest.Validate if the designated constraints aren't met.
+type ListTasksRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ListTasksRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ListTasksRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ListTasksRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ListTasksRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ListTasksRequestValidationError) ErrorName() string { return "ListTasksRequestValidationError" }
+
+// Error satisfies the builtin error interface
+func (e ListTasksRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sListTasksRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ListTasksRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ListTasksRequestValidationError{}
+
+// Validate checks the field values on ListTasksResult with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *ListTasksResult) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ListTasksResult with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// ListTasksResultMultiError, or nil if none found.
+func (m *ListTasksResult) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ListTasksResult) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	for idx, item := range m.GetTasks() {
+		_, _ = idx, item
+
+		if all {
+			switch v := interface{}(item).(type) {
+			case interface{ ValidateAll() error }:
+				if err := v.ValidateAll(); err != nil {
+					errors = append(errors, ListTasksResultValidationError{
+						field:  fmt.Sprintf("Tasks[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			case interface{ Validate() error }:
+				if err := v.Validate(); err != nil {
+					errors = append(errors, ListTasksResultValidationError{
+						field:  fmt.Sprintf("Tasks[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			}
+		} else if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return ListTasksResultValidationError{
+					field:  fmt.Sprintf("Tasks[%v]", idx),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	if len(errors) > 0 {
+		return ListTasksResultMultiError(errors)
+	}
+	return nil
+}
+
+// ListTasksResultMultiError is an error wrapping multiple validation errors
+// returned by ListTasksResult.ValidateAll() if the designated constraints
+// aren't met.
+type ListTasksResultMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ListTasksResultMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ListTasksResultMultiError) AllErrors() []error { return m }
+
+// ListTasksResultValidationError is the validation error returned by
+// ListTasksResult.Validate if the designated constraints aren't met.
+type ListTasksResultValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ListTasksResultValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ListTasksResultValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ListTasksResultValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ListTasksResultValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ListTasksResultValidationError) ErrorName() string { return "ListTasksResultValidationError" }
+
+// Error satisfies the builtin error interface
+func (e ListTasksResultValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sListTasksResult.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ListTasksResultValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ListTasksResultValidationError{}
+
+// Validate checks the field values on StatTaskRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *StatTaskRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on StatTaskRequest with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// StatTaskRequestMultiError, or nil if none found.
+func (m *StatTaskRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *StatTaskRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetTaskId()) < 1 {
+		err := StatTaskRequestValidationError{
+			field:  "TaskId",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if len(errors) > 0 {
+		return StatTaskRequestMultiError(errors)
+	}
+	return nil
+}
+
+// StatTaskRequestMultiError is an error wrapping multiple validation errors
+// returned by StatTaskRequest.ValidateAll() if the designated constraints
+// aren't met.
+type StatTaskRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m StatTaskRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m StatTaskRequestMultiError) AllErrors() []error { return m }
+
+// StatTaskRequestValidationError is the validation error returned by
+// StatTaskRequest.Validate if the designated constraints aren't met.
+type StatTaskRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e StatTaskRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e StatTaskRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e StatTaskRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e StatTaskRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e StatTaskRequestValidationError) ErrorName() string { return "StatTaskRequestValidationError" }
+
+// Error satisfies the builtin error interface
+func (e StatTaskRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sStatTaskRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = StatTaskRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = StatTaskRequestValidationError{}
+
+// Validate checks the field values on StatTaskResult with the rules defined in
+// the proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *StatTaskResult) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on StatTaskResult with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// result is a list of violation errors wrapped in StatTaskResultMultiError,
+// or nil if none found.
+func (m *StatTaskResult) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *StatTaskResult) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	for idx, item := range m.GetTasks() {
+		_, _ = idx, item
+
+		if all {
+			switch v := interface{}(item).(type) {
+			case interface{ ValidateAll() error }:
+				if err := v.ValidateAll(); err != nil {
+					errors = append(errors, StatTaskResultValidationError{
+						field:  fmt.Sprintf("Tasks[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			case interface{ Validate() error }:
+				if err := v.Validate(); err != nil {
+					errors = append(errors, StatTaskResultValidationError{
+						field:  fmt.Sprintf("Tasks[%v]", idx),
+						reason: "embedded message failed validation",
+						cause:  err,
+					})
+				}
+			}
+		} else if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return StatTaskResultValidationError{
+					field:  fmt.Sprintf("Tasks[%v]", idx),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	if len(errors) > 0 {
+		return StatTaskResultMultiError(errors)
+	}
+	return nil
+}
+
+// StatTaskResultMultiError is an error wrapping multiple validation errors
+// returned by StatTaskResult.ValidateAll() if the designated constraints
+// aren't met.
+type StatTaskResultMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m StatTaskResultMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m StatTaskResultMultiError) AllErrors() []error { return m }
+
+// StatTaskResultValidationError is the validation error returned by
+// StatTaskResult.Validate if the designated constraints aren't met.
+type StatTaskResultValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e StatTaskResultValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e StatTaskResultValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e StatTaskResultValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e StatTaskResultValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e StatTaskResultValidationError) ErrorName() string { return "StatTaskResultValidationError" }
+
+// Error satisfies the builtin error interface
+func (e StatTaskResultValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sStatTaskResult.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = StatTaskResultValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = StatTaskResultValidationError{}
+
+// Validate checks the field values on DeleteTaskRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *DeleteTaskRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on DeleteTaskRequest with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// DeleteTaskRequestMultiError, or nil if none found.
+func (m *DeleteTaskRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *DeleteTaskRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetTaskId()) < 1 {
+		err := DeleteTaskRequestValidationError{
+			field:  "TaskId",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if len(errors) > 0 {
+		return DeleteTaskRequestMultiError(errors)
+	}
+	return nil
+}
+
+// DeleteTaskRequestMultiError is an error wrapping multiple validation errors
+// returned by DeleteTaskRequest.ValidateAll() if the designated constraints
+// aren't met.
+type DeleteTaskRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m DeleteTaskRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m DeleteTaskRequestMultiError) AllErrors() []error { return m }
+
+// DeleteTaskRequestValidationError is the validation error returned by
+// DeleteTaskRequest.Validate if the designated constraints aren't met.
+type DeleteTaskRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e DeleteTaskRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e DeleteTaskRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e DeleteTaskRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e DeleteTaskRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e DeleteTaskRequestValidationError) ErrorName() string {
+	return "DeleteTaskRequestValidationError"
+}
+
+// Error satisfies the builtin error interface
+func (e DeleteTaskRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sDeleteTaskRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = DeleteTaskRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = DeleteTaskRequestValidationError{}
+
+// Validate checks the field values on PinTaskRequest with the rules defined in
+// the proto definition for this message. If any rules are violated, the first
+// error encountered is returned, or nil if there are no violations.
+func (m *PinTaskRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on PinTaskRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// result is a list of violation errors wrapped in PinTaskRequestMultiError,
+// or nil if none found.
+func (m *PinTaskRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *PinTaskRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetTaskId()) < 1 {
+		err := PinTaskRequestValidationError{
+			field:  "TaskId",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	// no validation rules for Pinned
+
+	// no validation rules for Priority
+
+	if len(errors) > 0 {
+		return PinTaskRequestMultiError(errors)
+	}
+	return nil
+}
+
+// PinTaskRequestMultiError is an error wrapping multiple validation errors
+// returned by PinTaskRequest.ValidateAll() if the designated constraints
+// aren't met.
+type PinTaskRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m PinTaskRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m PinTaskRequestMultiError) AllErrors() []error { return m }
+
+// PinTaskRequestValidationError is the validation error returned by
+// PinTaskRequest.Validate if the designated constraints aren't met.
+type PinTaskRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e PinTaskRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e PinTaskRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e PinTaskRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e PinTaskRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e PinTaskRequestValidationError) ErrorName() string { return "PinTaskRequestValidationError" }
+
+// Error satisfies the builtin error interface
+func (e PinTaskRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sPinTaskRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = PinTaskRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = PinTaskRequestValidationError{}
+
+// Validate checks the field values on ImportTaskRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *ImportTaskRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ImportTaskRequest with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// ImportTaskRequestMultiError, or nil if none found.
+func (m *ImportTaskRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ImportTaskRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	if utf8.RuneCountInString(m.GetUrl()) < 1 {
+		err := ImportTaskRequestValidationError{
+			field:  "Url",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if all {
+		switch v := interface{}(m.GetUrlMeta()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, ImportTaskRequestValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, ImportTaskRequestValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetUrlMeta()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return ImportTaskRequestValidationError{
+				field:  "UrlMeta",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(m.GetPath()) < 1 {
+		err := ImportTaskRequestValidationError{
+			field:  "Path",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if len(errors) > 0 {
+		return ImportTaskRequestMultiError(errors)
+	}
+	return nil
+}
+
+// ImportTaskRequestMultiError is an error wrapping multiple validation errors
+// returned by ImportTaskRequest.ValidateAll() if the designated constraints
+// aren't met.
+type ImportTaskRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ImportTaskRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ImportTaskRequestMultiError) AllErrors() []error { return m }
+
+// ImportTaskRequestValidationError is the validation error returned by
+// ImportTaskRequest.Validate if the designated constraints aren't met.
+type ImportTaskRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ImportTaskRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ImportTaskRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ImportTaskRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ImportTaskRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ImportTaskRequestValidationError) ErrorName() string {
+	return "ImportTaskRequestValidationError"
+}
+
+// Error satisfies the builtin error interface
+func (e ImportTaskRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sImportTaskRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ImportTaskRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ImportTaskRequestValidationError{}
+
+// Validate checks the field values on ExportTaskRequest with the rules defined
+// in the proto definition for this message. If any rules are violated, the
+// first error encountered is returned, or nil if there are no violations.
+func (m *ExportTaskRequest) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ExportTaskRequest with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// ExportTaskRequestMultiError, or nil if none found.
+func (m *ExportTaskRequest) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ExportTaskRequest) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	// no validation rules for TaskId
+
+	// no validation rules for Url
+
+	if all {
+		switch v := interface{}(m.GetUrlMeta()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, ExportTaskRequestValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, ExportTaskRequestValidationError{
+					field:  "UrlMeta",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetUrlMeta()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return ExportTaskRequestValidationError{
+				field:  "UrlMeta",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(m.GetOutput()) < 1 {
+		err := ExportTaskRequestValidationError{
+			field:  "Output",
+			reason: "value length must be at least 1 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	if _, ok := ExportMode_name[int32(m.GetMode())]; !ok {
+		err := ExportTaskRequestValidationError{
+			field:  "Mode",
+			reason: "value must be one of the defined enum values",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
+	// no validation rules for Uid
+
+	// no validation rules for Gid
+
+	if len(errors) > 0 {
+		return ExportTaskRequestMultiError(errors)
+	}
+	return nil
+}
+
+// ExportTaskRequestMultiError is an error wrapping multiple validation errors
+// returned by ExportTaskRequest.ValidateAll() if the designated constraints
+// aren't met.
+type ExportTaskRequestMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ExportTaskRequestMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ExportTaskRequestMultiError) AllErrors() []error { return m }
+
+// ExportTaskRequestValidationError is the validation error returned by
+// ExportTaskRequest.Validate if the designated constraints aren't met.
+type ExportTaskRequestValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ExportTaskRequestValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ExportTaskRequestValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ExportTaskRequestValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ExportTaskRequestValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ExportTaskRequestValidationError) ErrorName() string {
+	return "ExportTaskRequestValidationError"
+}
+
+// Error satisfies the builtin error interface
+func (e ExportTaskRequestValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sExportTaskRequest.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ExportTaskRequestValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ExportTaskRequestValidationError{}
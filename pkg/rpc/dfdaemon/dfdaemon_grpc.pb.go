@@ -21,10 +21,24 @@ const _ = grpc.SupportPackageIsVersion7
 type DaemonClient interface {
 	// Trigger client to download file
 	Download(ctx context.Context, in *DownRequest, opts ...grpc.CallOption) (Daemon_DownloadClient, error)
+	// Trigger client to download file and stream the content back instead of writing to output
+	DownloadStream(ctx context.Context, in *DownRequest, opts ...grpc.CallOption) (Daemon_DownloadStreamClient, error)
 	// Get piece tasks from other peers
 	GetPieceTasks(ctx context.Context, in *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error)
 	// Check daemon health
 	CheckHealth(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// List all tasks in daemon storage
+	ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResult, error)
+	// Stat task in daemon storage
+	StatTask(ctx context.Context, in *StatTaskRequest, opts ...grpc.CallOption) (*StatTaskResult, error)
+	// Delete task from daemon storage
+	DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Pin task in daemon storage or update its priority for gc
+	PinTask(ctx context.Context, in *PinTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Import local file into daemon storage and announce it to scheduler
+	ImportTask(ctx context.Context, in *ImportTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Export completed task in daemon storage to output without contacting scheduler or source
+	ExportTask(ctx context.Context, in *ExportTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type daemonClient struct {
@@ -67,6 +81,38 @@ func (x *daemonDownloadClient) Recv() (*DownResult, error) {
 	return m, nil
 }
 
+func (c *daemonClient) DownloadStream(ctx context.Context, in *DownRequest, opts ...grpc.CallOption) (Daemon_DownloadStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Daemon_serviceDesc.Streams[1], "/dfdaemon.Daemon/DownloadStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &daemonDownloadStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Daemon_DownloadStreamClient interface {
+	Recv() (*DownStreamResult, error)
+	grpc.ClientStream
+}
+
+type daemonDownloadStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *daemonDownloadStreamClient) Recv() (*DownStreamResult, error) {
+	m := new(DownStreamResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *daemonClient) GetPieceTasks(ctx context.Context, in *base.PieceTaskRequest, opts ...grpc.CallOption) (*base.PiecePacket, error) {
 	out := new(base.PiecePacket)
 	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/GetPieceTasks", in, out, opts...)
@@ -85,16 +131,84 @@ func (c *daemonClient) CheckHealth(ctx context.Context, in *emptypb.Empty, opts
 	return out, nil
 }
 
+func (c *daemonClient) ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResult, error) {
+	out := new(ListTasksResult)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/ListTasks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) StatTask(ctx context.Context, in *StatTaskRequest, opts ...grpc.CallOption) (*StatTaskResult, error) {
+	out := new(StatTaskResult)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/StatTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/DeleteTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) PinTask(ctx context.Context, in *PinTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/PinTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) ImportTask(ctx context.Context, in *ImportTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/ImportTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) ExportTask(ctx context.Context, in *ExportTaskRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/dfdaemon.Daemon/ExportTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DaemonServer is the server API for Daemon service.
 // All implementations must embed UnimplementedDaemonServer
 // for forward compatibility
 type DaemonServer interface {
 	// Trigger client to download file
 	Download(*DownRequest, Daemon_DownloadServer) error
+	// Trigger client to download file and stream the content back instead of writing to output
+	DownloadStream(*DownRequest, Daemon_DownloadStreamServer) error
 	// Get piece tasks from other peers
 	GetPieceTasks(context.Context, *base.PieceTaskRequest) (*base.PiecePacket, error)
 	// Check daemon health
 	CheckHealth(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// List all tasks in daemon storage
+	ListTasks(context.Context, *ListTasksRequest) (*ListTasksResult, error)
+	// Stat task in daemon storage
+	StatTask(context.Context, *StatTaskRequest) (*StatTaskResult, error)
+	// Delete task from daemon storage
+	DeleteTask(context.Context, *DeleteTaskRequest) (*emptypb.Empty, error)
+	// Pin task in daemon storage or update its priority for gc
+	PinTask(context.Context, *PinTaskRequest) (*emptypb.Empty, error)
+	// Import local file into daemon storage and announce it to scheduler
+	ImportTask(context.Context, *ImportTaskRequest) (*emptypb.Empty, error)
+	// Export completed task in daemon storage to output without contacting scheduler or source
+	ExportTask(context.Context, *ExportTaskRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedDaemonServer()
 }
 
@@ -105,12 +219,33 @@ type UnimplementedDaemonServer struct {
 func (UnimplementedDaemonServer) Download(*DownRequest, Daemon_DownloadServer) error {
 	return status.Errorf(codes.Unimplemented, "method Download not implemented")
 }
+func (UnimplementedDaemonServer) DownloadStream(*DownRequest, Daemon_DownloadStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadStream not implemented")
+}
 func (UnimplementedDaemonServer) GetPieceTasks(context.Context, *base.PieceTaskRequest) (*base.PiecePacket, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPieceTasks not implemented")
 }
 func (UnimplementedDaemonServer) CheckHealth(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckHealth not implemented")
 }
+func (UnimplementedDaemonServer) ListTasks(context.Context, *ListTasksRequest) (*ListTasksResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTasks not implemented")
+}
+func (UnimplementedDaemonServer) StatTask(context.Context, *StatTaskRequest) (*StatTaskResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatTask not implemented")
+}
+func (UnimplementedDaemonServer) DeleteTask(context.Context, *DeleteTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTask not implemented")
+}
+func (UnimplementedDaemonServer) PinTask(context.Context, *PinTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PinTask not implemented")
+}
+func (UnimplementedDaemonServer) ImportTask(context.Context, *ImportTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportTask not implemented")
+}
+func (UnimplementedDaemonServer) ExportTask(context.Context, *ExportTaskRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportTask not implemented")
+}
 func (UnimplementedDaemonServer) mustEmbedUnimplementedDaemonServer() {}
 
 // UnsafeDaemonServer may be embedded to opt out of forward compatibility for this service.
@@ -145,6 +280,27 @@ func (x *daemonDownloadServer) Send(m *DownResult) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Daemon_DownloadStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServer).DownloadStream(m, &daemonDownloadStreamServer{stream})
+}
+
+type Daemon_DownloadStreamServer interface {
+	Send(*DownStreamResult) error
+	grpc.ServerStream
+}
+
+type daemonDownloadStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *daemonDownloadStreamServer) Send(m *DownStreamResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Daemon_GetPieceTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(base.PieceTaskRequest)
 	if err := dec(in); err != nil {
@@ -181,6 +337,114 @@ func _Daemon_CheckHealth_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Daemon_ListTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).ListTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/ListTasks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).ListTasks(ctx, req.(*ListTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_StatTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).StatTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/StatTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).StatTask(ctx, req.(*StatTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_DeleteTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).DeleteTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/DeleteTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).DeleteTask(ctx, req.(*DeleteTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_PinTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).PinTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/PinTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).PinTask(ctx, req.(*PinTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_ImportTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).ImportTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/ImportTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).ImportTask(ctx, req.(*ImportTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_ExportTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).ExportTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfdaemon.Daemon/ExportTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).ExportTask(ctx, req.(*ExportTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Daemon_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "dfdaemon.Daemon",
 	HandlerType: (*DaemonServer)(nil),
@@ -193,6 +457,30 @@ var _Daemon_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CheckHealth",
 			Handler:    _Daemon_CheckHealth_Handler,
 		},
+		{
+			MethodName: "ListTasks",
+			Handler:    _Daemon_ListTasks_Handler,
+		},
+		{
+			MethodName: "StatTask",
+			Handler:    _Daemon_StatTask_Handler,
+		},
+		{
+			MethodName: "DeleteTask",
+			Handler:    _Daemon_DeleteTask_Handler,
+		},
+		{
+			MethodName: "PinTask",
+			Handler:    _Daemon_PinTask_Handler,
+		},
+		{
+			MethodName: "ImportTask",
+			Handler:    _Daemon_ImportTask_Handler,
+		},
+		{
+			MethodName: "ExportTask",
+			Handler:    _Daemon_ExportTask_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -200,6 +488,11 @@ var _Daemon_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Daemon_Download_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "DownloadStream",
+			Handler:       _Daemon_DownloadStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "pkg/rpc/dfdaemon/dfdaemon.proto",
 }
@@ -36,10 +36,24 @@ import (
 type DaemonServer interface {
 	// Trigger client to download file
 	Download(context.Context, *dfdaemon.DownRequest, chan<- *dfdaemon.DownResult) error
+	// Trigger client to download file and stream the content back
+	DownloadStream(*dfdaemon.DownRequest, dfdaemon.Daemon_DownloadStreamServer) error
 	// Get piece tasks from other peers
 	GetPieceTasks(context.Context, *base.PieceTaskRequest) (*base.PiecePacket, error)
 	// Check daemon health
 	CheckHealth(context.Context) error
+	// List all tasks in daemon storage
+	ListTasks(context.Context, *dfdaemon.ListTasksRequest) (*dfdaemon.ListTasksResult, error)
+	// Stat task in daemon storage
+	StatTask(context.Context, *dfdaemon.StatTaskRequest) (*dfdaemon.StatTaskResult, error)
+	// Delete task from daemon storage
+	DeleteTask(context.Context, *dfdaemon.DeleteTaskRequest) error
+	// Pin task in daemon storage or update its priority for gc
+	PinTask(context.Context, *dfdaemon.PinTaskRequest) error
+	// Import local file into daemon storage and announce it to scheduler
+	ImportTask(context.Context, *dfdaemon.ImportTaskRequest) error
+	// Export completed task in daemon storage to output
+	ExportTask(context.Context, *dfdaemon.ExportTaskRequest) error
 }
 
 type proxy struct {
@@ -85,6 +99,16 @@ func (p *proxy) Download(req *dfdaemon.DownRequest, stream dfdaemon.Daemon_Downl
 	return
 }
 
+func (p *proxy) DownloadStream(req *dfdaemon.DownRequest, stream dfdaemon.Daemon_DownloadStreamServer) error {
+	peerAddr := "unknown"
+	if pe, ok := peer.FromContext(stream.Context()); ok {
+		peerAddr = pe.Addr.String()
+	}
+	logger.Infof("trigger stream download for url: %s, from: %s, uuid: %s", req.Url, peerAddr, req.Uuid)
+
+	return p.server.DownloadStream(req, stream)
+}
+
 func (p *proxy) GetPieceTasks(ctx context.Context, ptr *base.PieceTaskRequest) (*base.PiecePacket, error) {
 	return p.server.GetPieceTasks(ctx, ptr)
 }
@@ -93,6 +117,30 @@ func (p *proxy) CheckHealth(ctx context.Context, req *empty.Empty) (*empty.Empty
 	return new(empty.Empty), p.server.CheckHealth(ctx)
 }
 
+func (p *proxy) ListTasks(ctx context.Context, req *dfdaemon.ListTasksRequest) (*dfdaemon.ListTasksResult, error) {
+	return p.server.ListTasks(ctx, req)
+}
+
+func (p *proxy) StatTask(ctx context.Context, req *dfdaemon.StatTaskRequest) (*dfdaemon.StatTaskResult, error) {
+	return p.server.StatTask(ctx, req)
+}
+
+func (p *proxy) DeleteTask(ctx context.Context, req *dfdaemon.DeleteTaskRequest) (*empty.Empty, error) {
+	return new(empty.Empty), p.server.DeleteTask(ctx, req)
+}
+
+func (p *proxy) PinTask(ctx context.Context, req *dfdaemon.PinTaskRequest) (*empty.Empty, error) {
+	return new(empty.Empty), p.server.PinTask(ctx, req)
+}
+
+func (p *proxy) ImportTask(ctx context.Context, req *dfdaemon.ImportTaskRequest) (*empty.Empty, error) {
+	return new(empty.Empty), p.server.ImportTask(ctx, req)
+}
+
+func (p *proxy) ExportTask(ctx context.Context, req *dfdaemon.ExportTaskRequest) (*empty.Empty, error) {
+	return new(empty.Empty), p.server.ExportTask(ctx, req)
+}
+
 func send(drc chan *dfdaemon.DownResult, closeDrc func(), stream dfdaemon.Daemon_DownloadServer, errChan chan error) {
 	err := safe.Call(func() {
 		defer closeDrc()
@@ -189,6 +189,17 @@ func (m *PeerTaskRequest) validate(all bool) error {
 
 	// no validation rules for IsMigrating
 
+	if _, ok := _PeerTaskRequest_Pattern_InLookup[m.GetPattern()]; !ok {
+		err := PeerTaskRequestValidationError{
+			field:  "Pattern",
+			reason: "value must be in list [ p2p cdn source]",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if len(errors) > 0 {
 		return PeerTaskRequestMultiError(errors)
 	}
@@ -266,6 +277,13 @@ var _ interface {
 	ErrorName() string
 } = PeerTaskRequestValidationError{}
 
+var _PeerTaskRequest_Pattern_InLookup = map[string]struct{}{
+	"":       {},
+	"p2p":    {},
+	"cdn":    {},
+	"source": {},
+}
+
 // Validate checks the field values on RegisterResult with the rules defined in
 // the proto definition for this message. If any rules are violated, the first
 // error encountered is returned, or nil if there are no violations.
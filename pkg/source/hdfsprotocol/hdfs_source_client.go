@@ -147,6 +147,10 @@ func (h *hdfsSourceClient) Download(request *source.Request) (*source.Response,
 		newHdfsFileReaderClose(hdfsFile, limitReadN, hdfsFile),
 		source.WithExpireInfo(source.ExpireInfo{
 			LastModified: timeutils.Format(fileInfo.ModTime()),
+		}),
+		source.WithFileAttributes(source.FileAttributes{
+			Mode:         fileInfo.Mode().Perm(),
+			LastModified: fileInfo.ModTime().UnixNano() / time.Millisecond.Nanoseconds(),
 		}))
 	return response, nil
 }
@@ -186,7 +186,11 @@ func (client *httpSourceClient) Download(request *source.Request) (*source.Respo
 				LastModified: resp.Header.Get(headers.LastModified),
 				ETag:         resp.Header.Get(headers.ETag),
 			},
-		))
+		),
+		source.WithFileAttributes(source.FileAttributes{
+			LastModified: timeutils.UnixMillis(resp.Header.Get(headers.LastModified)),
+			ContentType:  resp.Header.Get(headers.ContentType),
+		}))
 	return response, nil
 }
 
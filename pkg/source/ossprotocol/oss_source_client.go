@@ -171,7 +171,11 @@ func (osc *ossSourceClient) Download(request *source.Request) (*source.Response,
 				LastModified: objectResult.Response.Headers.Get(headers.LastModified),
 				ETag:         objectResult.Response.Headers.Get(headers.ETag),
 			},
-		))
+		),
+		source.WithFileAttributes(source.FileAttributes{
+			LastModified: timeutils.UnixMillis(objectResult.Response.Headers.Get(headers.LastModified)),
+			ContentType:  objectResult.Response.Headers.Get(headers.ContentType),
+		}))
 	return response, nil
 }
 
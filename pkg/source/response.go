@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 )
 
 type Response struct {
@@ -28,6 +29,18 @@ type Response struct {
 	Header        Header
 	Body          io.ReadCloser
 	ContentLength int64
+	// Attributes are the optional file attributes of the resource, nil when the source client does not support
+	Attributes *FileAttributes
+}
+
+// FileAttributes are the attributes of the resource which can be applied to the downloaded file
+type FileAttributes struct {
+	// Mode is the permission bits of the resource, 0 stands for unknown
+	Mode os.FileMode `json:"mode,omitempty"`
+	// LastModified is the last modified timestamp milliseconds of the resource, 0 stands for unknown
+	LastModified int64 `json:"lastModified,omitempty"`
+	// ContentType is the mime type of the resource
+	ContentType string `json:"contentType,omitempty"`
 }
 
 func NewResponse(rc io.ReadCloser, opts ...func(*Response)) *Response {
@@ -78,6 +91,12 @@ func WithExpireInfo(info ExpireInfo) func(*Response) {
 	}
 }
 
+func WithFileAttributes(attr FileAttributes) func(*Response) {
+	return func(resp *Response) {
+		resp.Attributes = &attr
+	}
+}
+
 func (resp *Response) ExpireInfo() ExpireInfo {
 	return ExpireInfo{
 		LastModified: resp.Header.Get(LastModified),
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"crypto/md5"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"hash"
 	"io"
@@ -27,6 +28,8 @@ import (
 	"strings"
 
 	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/zeebo/blake3"
 
 	"d7y.io/dragonfly/v2/pkg/unit"
 	"d7y.io/dragonfly/v2/pkg/util/fileutils"
@@ -34,19 +37,37 @@ import (
 
 const (
 	Sha256Hash digest.Algorithm = "sha256"
+	Sha512Hash digest.Algorithm = "sha512"
 	Md5Hash    digest.Algorithm = "md5"
+	Blake3Hash digest.Algorithm = "blake3"
 )
 
 var (
 	// Algorithms is used to check if an algorithm is supported.
 	// If algo is not supported, Algorithms[algo] will return empty string.
 	// Please don't use digest.Algorithm() to convert a string to digest.Algorithm.
-	Algorithms = map[string]digest.Algorithm{
-		Sha256Hash.String(): Sha256Hash,
-		Md5Hash.String():    Md5Hash,
-	}
+	Algorithms = map[string]digest.Algorithm{}
+
+	// hashFuncs are the hash constructors of the registered algorithms
+	hashFuncs = map[digest.Algorithm]func() hash.Hash{}
 )
 
+func init() {
+	Register(Md5Hash, md5.New)
+	Register(Sha256Hash, sha256.New)
+	Register(Sha512Hash, sha512.New)
+	Register(Blake3Hash, func() hash.Hash {
+		return blake3.New()
+	})
+}
+
+// Register registers a digest algorithm, digest in format of algorithm:xxx is supported after registered.
+// It is not safe for concurrent use, call it in init of the package which provides the algorithm.
+func Register(algo digest.Algorithm, newHash func() hash.Hash) {
+	Algorithms[algo.String()] = algo
+	hashFuncs[algo] = newHash
+}
+
 func Sha256(values ...string) string {
 	if len(values) == 0 {
 		return ""
@@ -78,7 +99,7 @@ func Md5Bytes(bytes []byte) string {
 }
 
 // HashFile computes hash value corresponding to hashType,
-// hashType is from digestutils.Md5Hash and digestutils.Sha256Hash.
+// hashType is one of the registered algorithms.
 func HashFile(file string, hashType digest.Algorithm) string {
 	if !fileutils.IsRegular(file) {
 		return ""
@@ -91,12 +112,8 @@ func HashFile(file string, hashType digest.Algorithm) string {
 
 	defer f.Close()
 
-	var h hash.Hash
-	if hashType == Md5Hash {
-		h = md5.New()
-	} else if hashType == Sha256Hash {
-		h = sha256.New()
-	} else {
+	h := CreateHash(hashType.String())
+	if h == nil {
 		return ""
 	}
 
@@ -119,14 +136,28 @@ func Parse(digest string) []string {
 	return strings.Split(digest, ":")
 }
 
+// CreateHash creates the hash of the registered algorithm, nil is returned when the algorithm is not supported.
 func CreateHash(hashType string) hash.Hash {
-	algo := Algorithms[hashType]
-	switch algo {
-	case Sha256Hash:
-		return sha256.New()
-	case Md5Hash:
-		return md5.New()
-	default:
+	newHash, ok := hashFuncs[Algorithms[hashType]]
+	if !ok {
 		return nil
 	}
+	return newHash()
+}
+
+// Validate checks whether the digest is in format of algorithm:xxx,
+// the algorithm must be registered and xxx must be the hex encoded hash of it.
+func Validate(d string) error {
+	parsed := Parse(d)
+	if len(parsed) != 2 {
+		return errors.Errorf("digest %q is not in format of algorithm:hex", d)
+	}
+	h := CreateHash(parsed[0])
+	if h == nil {
+		return errors.Errorf("digest algorithm %q is not supported", parsed[0])
+	}
+	if decoded, err := hex.DecodeString(parsed[1]); err != nil || len(decoded) != h.Size() {
+		return errors.Errorf("digest %q is not a valid %s hash", parsed[1], parsed[0])
+	}
+	return nil
 }
@@ -18,7 +18,6 @@ package digestutils
 
 import (
 	"crypto/md5"
-	"encoding/hex"
 	"hash"
 	"io"
 
@@ -46,17 +45,29 @@ type DigestReader interface {
 
 // TODO add AF_ALG digest https://github.com/golang/sys/commit/e24f485414aeafb646f6fca458b0bf869c0880a1
 
+// NewDigestReader creates a reader which verifies the content with digest when EOF,
+// the digest is a md5 hex string or in format of algorithm:xxx with a registered algorithm.
 func NewDigestReader(log *logger.SugaredLoggerOnWith, reader io.Reader, digest ...string) io.Reader {
-	var d string
+	var (
+		d string
+		h hash.Hash
+	)
 	if len(digest) > 0 {
 		d = digest[0]
+		if parsed := Parse(d); len(parsed) == 2 {
+			if h = CreateHash(parsed[0]); h != nil {
+				d = parsed[1]
+			}
+		}
+	}
+	if h == nil {
+		h = md5.New()
 	}
 	return &digestReader{
 		SugaredLoggerOnWith: log,
 		digest:              d,
-		// TODO support more digest method like sha1, sha256
-		hash: md5.New(),
-		r:    reader,
+		hash:                h,
+		r:                   reader,
 	}
 }
 
@@ -72,7 +83,7 @@ func (dr *digestReader) Read(p []byte) (int, error) {
 		digest := dr.Digest()
 		if digest != dr.digest {
 			dr.Warnf("digest not match, desired: %s, actual: %s", dr.digest, digest)
-			return n, ErrDigestNotMatch
+			return n, errors.Wrapf(ErrDigestNotMatch, "desired: %s, actual: %s", dr.digest, digest)
 		}
 		dr.Debugf("digest match: %s", digest)
 	}
@@ -81,5 +92,5 @@ func (dr *digestReader) Read(p []byte) (int, error) {
 
 // Digest returns the digest of contents.
 func (dr *digestReader) Digest() string {
-	return hex.EncodeToString(dr.hash.Sum(nil)[:16])
+	return ToHashString(dr.hash)
 }
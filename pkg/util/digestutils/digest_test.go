@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
 
 	"d7y.io/dragonfly/v2/pkg/basic"
@@ -66,3 +67,30 @@ func TestHashFile(t *testing.T) {
 
 	assert.Equal(t, expected, HashFile(path, Md5Hash))
 }
+
+func TestCreateHash(t *testing.T) {
+	testCases := []struct {
+		algo     digest.Algorithm
+		expected string
+	}{
+		{Md5Hash, "5d41402abc4b2a76b9719d911017c592"},
+		{Sha256Hash, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{Sha512Hash, "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
+		{Blake3Hash, "ea8f163db38682925e4491c5e58d4bb3506ef8c14eb78a86e908c5624a67200f"},
+	}
+	for _, tc := range testCases {
+		h := CreateHash(tc.algo.String())
+		assert.NotNil(t, h)
+		h.Write([]byte("hello"))
+		assert.Equal(t, tc.expected, ToHashString(h))
+		assert.Nil(t, Validate(tc.algo.String()+":"+tc.expected))
+	}
+	assert.Nil(t, CreateHash("sha1"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NotNil(t, Validate("5d41402abc4b2a76b9719d911017c592"))
+	assert.NotNil(t, Validate("sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"))
+	assert.NotNil(t, Validate("sha256:5d41402abc4b2a76b9719d911017c592"))
+	assert.NotNil(t, Validate("md5:xyz"))
+}
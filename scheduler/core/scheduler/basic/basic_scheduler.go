@@ -187,6 +187,11 @@ func (s *Scheduler) selectCandidateChildren(peer *supervisor.Peer, limit int, bl
 			return false
 		}
 
+		if candidateNode.Pattern == supervisor.PeerPatternCDN && (peer.Host == nil || !peer.Host.IsCDN) {
+			peer.Log().Debugf("******candidate child peer %s is not selected because it downloads from cdn only******", candidateNode.ID)
+			return false
+		}
+
 		if !candidateNode.IsConnected() {
 			peer.Log().Debugf("******candidate child peer %s is not selected because it is not connected******", candidateNode.ID)
 			return false
@@ -247,6 +252,11 @@ func (s *Scheduler) selectCandidateParents(peer *supervisor.Peer, limit int, bla
 				candidateNode.ID)
 			return false
 		}
+		if peer.Pattern == supervisor.PeerPatternCDN && (candidateNode.Host == nil || !candidateNode.Host.IsCDN) {
+			peer.Log().Debugf("++++++candidate parent peer %s is not selected because peer downloads from cdn only++++++",
+				candidateNode.ID)
+			return false
+		}
 		if candidateNode.Host.GetFreeUploadLoad() <= 0 {
 			peer.Log().Debugf("++++++candidate parent peer %s is not selected because it's free upload load equal to less than zero++++++",
 				candidateNode.ID)
@@ -245,6 +245,7 @@ func (s *SchedulerService) RegisterTask(req *schedulerRPC.PeerTaskRequest, task
 		return peer
 	}
 	peer = supervisor.NewPeer(req.PeerId, task, host)
+	peer.Pattern = req.Pattern
 	s.peerManager.Add(peer)
 	return peer
 }
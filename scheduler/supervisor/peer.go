@@ -186,6 +186,9 @@ func (status PeerStatus) String() string {
 	}
 }
 
+// PeerPatternCDN is the download pattern of the peers whose parents are cdn peers only
+const PeerPatternCDN = "cdn"
+
 const (
 	PeerStatusWaiting PeerStatus = iota
 	PeerStatusRunning
@@ -202,6 +205,8 @@ type Peer struct {
 	Task *Task
 	// Host is peer host
 	Host *Host
+	// Pattern is the download pattern of peer, p2p, cdn or source
+	Pattern string
 	// TotalPieceCount is downloaded finished piece count
 	TotalPieceCount atomic.Int32
 	// CreateAt is peer create time